@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/signal"
 
-	"github.com/common-nighthawk/go-figure"
-	"github.com/gofrontier-com/go-utils/output"
 	"github.com/gofrontier-com/sheriff/pkg/cmd/sheriff"
+	"github.com/gofrontier-com/sheriff/pkg/core"
 )
 
 var (
@@ -15,11 +17,23 @@ var (
 )
 
 func main() {
-	myFigure := figure.NewFigure("Sheriff for Azure", "doom", true)
-	myFigure.Print()
-	output.PrintlnInfo()
+	// The first interrupt cancels the context so that Sheriff stops cleanly after the request that is in
+	// flight. Once it has been cancelled, a second interrupt terminates Sheriff immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	command := sheriff.NewRootCmd(version, commit, date)
-	if err := command.Execute(); err != nil {
+	err := command.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		var changesPresentError *core.ChangesPresentError
+		if errors.As(err, &changesPresentError) {
+			os.Exit(2)
+		}
+
 		os.Exit(1)
 	}
 }
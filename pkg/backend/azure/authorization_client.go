@@ -0,0 +1,181 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+)
+
+// AuthorizationClient is a backend.AuthorizationClient that calls the Azure Resource Manager authorization API.
+type AuthorizationClient struct {
+	clientFactory *armauthorization.ClientFactory
+}
+
+// NewAuthorizationClient creates an AuthorizationClient that authenticates with the given credential.
+func NewAuthorizationClient(credential azcore.TokenCredential) (*AuthorizationClient, error) {
+	// Every client used by Sheriff is scope-based, so a single client factory that is not bound to
+	// a subscription is shared by all scopes.
+	clientFactory, err := armauthorization.NewClientFactory("", credential, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthorizationClient{clientFactory: clientFactory}, nil
+}
+
+func (c *AuthorizationClient) GetRoleDefinitionById(ctx context.Context, roleDefinitionId string) (*armauthorization.RoleDefinition, error) {
+	response, err := c.clientFactory.NewRoleDefinitionsClient().GetByID(ctx, roleDefinitionId, nil)
+	if err != nil {
+		var responseError *azcore.ResponseError
+		if errors.As(err, &responseError) && responseError.ErrorCode == "RoleDefinitionDoesNotExist" {
+			return nil, backend.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return &response.RoleDefinition, nil
+}
+
+func (c *AuthorizationClient) ListRoleDefinitionsByName(ctx context.Context, scope string, roleName string) ([]*armauthorization.RoleDefinition, error) {
+	return c.listRoleDefinitions(ctx, scope, fmt.Sprintf("roleName eq '%s'", roleName))
+}
+
+func (c *AuthorizationClient) ListCustomRoleDefinitions(ctx context.Context, scope string) ([]*armauthorization.RoleDefinition, error) {
+	return c.listRoleDefinitions(ctx, scope, "type eq 'CustomRole'")
+}
+
+func (c *AuthorizationClient) listRoleDefinitions(ctx context.Context, scope string, filter string) ([]*armauthorization.RoleDefinition, error) {
+	var roleDefinitions []*armauthorization.RoleDefinition
+
+	options := &armauthorization.RoleDefinitionsClientListOptions{
+		Filter: to.Ptr(filter),
+	}
+	pager := c.clientFactory.NewRoleDefinitionsClient().NewListPager(scope, options)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		roleDefinitions = append(roleDefinitions, page.Value...)
+	}
+
+	return roleDefinitions, nil
+}
+
+func (c *AuthorizationClient) CreateOrUpdateRoleDefinition(ctx context.Context, scope string, roleDefinitionName string, roleDefinition armauthorization.RoleDefinition) error {
+	_, err := c.clientFactory.NewRoleDefinitionsClient().CreateOrUpdate(ctx, scope, roleDefinitionName, roleDefinition, nil)
+	return err
+}
+
+func (c *AuthorizationClient) ListRoleAssignmentsForPrincipal(ctx context.Context, scope string, principalId string) ([]*armauthorization.RoleAssignment, error) {
+	var roleAssignments []*armauthorization.RoleAssignment
+
+	options := &armauthorization.RoleAssignmentsClientListForScopeOptions{
+		Filter: to.Ptr(fmt.Sprintf("assignedTo('%s')", principalId)),
+	}
+	pager := c.clientFactory.NewRoleAssignmentsClient().NewListForScopePager(scope, options)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		roleAssignments = append(roleAssignments, page.Value...)
+	}
+
+	return roleAssignments, nil
+}
+
+func (c *AuthorizationClient) ListRoleAssignmentSchedules(ctx context.Context, scope string) ([]*armauthorization.RoleAssignmentSchedule, error) {
+	var roleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule
+
+	pager := c.clientFactory.NewRoleAssignmentSchedulesClient().NewListForScopePager(scope, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		roleAssignmentSchedules = append(roleAssignmentSchedules, page.Value...)
+	}
+
+	return roleAssignmentSchedules, nil
+}
+
+func (c *AuthorizationClient) ListRoleEligibilitySchedules(ctx context.Context, scope string) ([]*armauthorization.RoleEligibilitySchedule, error) {
+	var roleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule
+
+	pager := c.clientFactory.NewRoleEligibilitySchedulesClient().NewListForScopePager(scope, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		roleEligibilitySchedules = append(roleEligibilitySchedules, page.Value...)
+	}
+
+	return roleEligibilitySchedules, nil
+}
+
+func (c *AuthorizationClient) CreateRoleAssignmentScheduleRequest(ctx context.Context, scope string, requestName string, request armauthorization.RoleAssignmentScheduleRequest) error {
+	_, err := c.clientFactory.NewRoleAssignmentScheduleRequestsClient().Create(ctx, scope, requestName, request, nil)
+	return err
+}
+
+func (c *AuthorizationClient) CancelRoleAssignmentScheduleRequest(ctx context.Context, scope string, requestName string) error {
+	_, err := c.clientFactory.NewRoleAssignmentScheduleRequestsClient().Cancel(ctx, scope, requestName, nil)
+	return err
+}
+
+func (c *AuthorizationClient) CreateRoleEligibilityScheduleRequest(ctx context.Context, scope string, requestName string, request armauthorization.RoleEligibilityScheduleRequest) error {
+	_, err := c.clientFactory.NewRoleEligibilityScheduleRequestsClient().Create(ctx, scope, requestName, request, nil)
+	return err
+}
+
+func (c *AuthorizationClient) CancelRoleEligibilityScheduleRequest(ctx context.Context, scope string, requestName string) error {
+	_, err := c.clientFactory.NewRoleEligibilityScheduleRequestsClient().Cancel(ctx, scope, requestName, nil)
+	return err
+}
+
+func (c *AuthorizationClient) ListRoleManagementPolicyAssignments(ctx context.Context, scope string) ([]*armauthorization.RoleManagementPolicyAssignment, error) {
+	var roleManagementPolicyAssignments []*armauthorization.RoleManagementPolicyAssignment
+
+	pager := c.clientFactory.NewRoleManagementPolicyAssignmentsClient().NewListForScopePager(scope, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		roleManagementPolicyAssignments = append(roleManagementPolicyAssignments, page.Value...)
+	}
+
+	return roleManagementPolicyAssignments, nil
+}
+
+func (c *AuthorizationClient) GetRoleManagementPolicy(ctx context.Context, scope string, roleManagementPolicyName string) (*armauthorization.RoleManagementPolicy, error) {
+	response, err := c.clientFactory.NewRoleManagementPoliciesClient().Get(ctx, scope, roleManagementPolicyName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// There's a bug in the SDK where the response is not nil even if you pass a bad Id.
+	if response.RoleManagementPolicy.ID == nil {
+		return nil, backend.ErrNotFound
+	}
+
+	return &response.RoleManagementPolicy, nil
+}
+
+func (c *AuthorizationClient) UpdateRoleManagementPolicy(ctx context.Context, scope string, roleManagementPolicyName string, roleManagementPolicy armauthorization.RoleManagementPolicy) error {
+	_, err := c.clientFactory.NewRoleManagementPoliciesClient().Update(ctx, scope, roleManagementPolicyName, roleManagementPolicy, nil)
+	return err
+}
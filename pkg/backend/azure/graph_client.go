@@ -0,0 +1,301 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/google/uuid"
+	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/groups"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+)
+
+// maxInFilterValues is the maximum number of values that Graph accepts in an `in` filter.
+const maxInFilterValues = 15
+
+// GraphClient is a backend.GraphClient that calls the Microsoft Graph API.
+type GraphClient struct {
+	graphServiceClient *msgraphsdkgo.GraphServiceClient
+}
+
+// NewGraphClient creates a GraphClient that authenticates with the given credential.
+func NewGraphClient(credential azcore.TokenCredential) (*GraphClient, error) {
+	graphServiceClient, err := msgraphsdkgo.NewGraphServiceClientWithCredentials(credential, []string{"https://graph.microsoft.com/.default"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &GraphClient{graphServiceClient: graphServiceClient}, nil
+}
+
+func (c *GraphClient) GetUserById(ctx context.Context, userId string) (models.Userable, error) {
+	user, err := c.graphServiceClient.Users().ByUserId(userId).Get(ctx, nil)
+	if err != nil {
+		return nil, notFoundOr(err, userId)
+	}
+
+	return user, nil
+}
+
+func (c *GraphClient) ListUsersByUpn(ctx context.Context, upn string) ([]models.Userable, error) {
+	filterValue := eqFilter("userPrincipalName", upn)
+	query := users.UsersRequestBuilderGetQueryParameters{
+		Filter: &filterValue,
+	}
+	options := users.UsersRequestBuilderGetRequestConfiguration{
+		QueryParameters: &query,
+	}
+	result, err := c.graphServiceClient.Users().Get(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.GetValue(), nil
+}
+
+func (c *GraphClient) ListUsersByIds(ctx context.Context, userIds []string) ([]models.Userable, error) {
+	return c.listUsers(ctx, inFilters("id", userIds))
+}
+
+func (c *GraphClient) ListUsersByUpns(ctx context.Context, upns []string) ([]models.Userable, error) {
+	return c.listUsers(ctx, inFilters("userPrincipalName", upns))
+}
+
+func (c *GraphClient) listUsers(ctx context.Context, filters []string) ([]models.Userable, error) {
+	var result []models.Userable
+
+	for _, filter := range filters {
+		filterValue := filter
+		query := users.UsersRequestBuilderGetQueryParameters{
+			Filter: &filterValue,
+		}
+		options := users.UsersRequestBuilderGetRequestConfiguration{
+			QueryParameters: &query,
+		}
+		response, err := c.graphServiceClient.Users().Get(ctx, &options)
+		for {
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, response.GetValue()...)
+
+			nextLink := response.GetOdataNextLink()
+			if nextLink == nil {
+				break
+			}
+
+			response, err = c.graphServiceClient.Users().WithUrl(*nextLink).Get(ctx, nil)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *GraphClient) GetGroupById(ctx context.Context, groupId string) (models.Groupable, error) {
+	group, err := c.graphServiceClient.Groups().ByGroupId(groupId).Get(ctx, nil)
+	if err != nil {
+		return nil, notFoundOr(err, groupId)
+	}
+
+	return group, nil
+}
+
+func (c *GraphClient) ListGroupsByName(ctx context.Context, groupName string) ([]models.Groupable, error) {
+	filterValue := eqFilter("displayName", groupName)
+	query := groups.GroupsRequestBuilderGetQueryParameters{
+		Filter: &filterValue,
+	}
+	options := groups.GroupsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &query,
+	}
+	result, err := c.graphServiceClient.Groups().Get(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.GetValue(), nil
+}
+
+func (c *GraphClient) ListGroupsByIds(ctx context.Context, groupIds []string) ([]models.Groupable, error) {
+	return c.listGroups(ctx, inFilters("id", groupIds))
+}
+
+func (c *GraphClient) ListGroupsByNames(ctx context.Context, groupNames []string) ([]models.Groupable, error) {
+	return c.listGroups(ctx, inFilters("displayName", groupNames))
+}
+
+func (c *GraphClient) listGroups(ctx context.Context, filters []string) ([]models.Groupable, error) {
+	var result []models.Groupable
+
+	for _, filter := range filters {
+		filterValue := filter
+		query := groups.GroupsRequestBuilderGetQueryParameters{
+			Filter: &filterValue,
+		}
+		options := groups.GroupsRequestBuilderGetRequestConfiguration{
+			QueryParameters: &query,
+		}
+		response, err := c.graphServiceClient.Groups().Get(ctx, &options)
+		for {
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, response.GetValue()...)
+
+			nextLink := response.GetOdataNextLink()
+			if nextLink == nil {
+				break
+			}
+
+			response, err = c.graphServiceClient.Groups().WithUrl(*nextLink).Get(ctx, nil)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *GraphClient) GetServicePrincipalById(ctx context.Context, servicePrincipalId string) (models.ServicePrincipalable, error) {
+	servicePrincipal, err := c.graphServiceClient.ServicePrincipals().ByServicePrincipalId(servicePrincipalId).Get(ctx, nil)
+	if err != nil {
+		return nil, notFoundOr(err, servicePrincipalId)
+	}
+
+	return servicePrincipal, nil
+}
+
+func (c *GraphClient) ListServicePrincipalsByName(ctx context.Context, servicePrincipalName string) ([]models.ServicePrincipalable, error) {
+	filterValue := servicePrincipalNameFilter(servicePrincipalName)
+	query := serviceprincipals.ServicePrincipalsRequestBuilderGetQueryParameters{
+		Filter: &filterValue,
+	}
+	options := serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &query,
+	}
+	result, err := c.graphServiceClient.ServicePrincipals().Get(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.GetValue(), nil
+}
+
+func (c *GraphClient) ListServicePrincipalsByIds(ctx context.Context, servicePrincipalIds []string) ([]models.ServicePrincipalable, error) {
+	return c.listServicePrincipals(ctx, inFilters("id", servicePrincipalIds))
+}
+
+func (c *GraphClient) ListServicePrincipalsByNames(ctx context.Context, servicePrincipalNames []string) ([]models.ServicePrincipalable, error) {
+	var ids, displayNames []string
+	for _, n := range servicePrincipalNames {
+		if _, err := uuid.Parse(n); err == nil {
+			ids = append(ids, n)
+		} else {
+			displayNames = append(displayNames, n)
+		}
+	}
+
+	var filters []string
+	for _, chunk := range chunk(ids) {
+		filters = append(filters, fmt.Sprintf("%s or %s", inFilter("id", chunk), inFilter("appId", chunk)))
+	}
+	filters = append(filters, inFilters("displayName", displayNames)...)
+
+	return c.listServicePrincipals(ctx, filters)
+}
+
+func (c *GraphClient) listServicePrincipals(ctx context.Context, filters []string) ([]models.ServicePrincipalable, error) {
+	var result []models.ServicePrincipalable
+
+	for _, filter := range filters {
+		filterValue := filter
+		query := serviceprincipals.ServicePrincipalsRequestBuilderGetQueryParameters{
+			Filter: &filterValue,
+		}
+		options := serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration{
+			QueryParameters: &query,
+		}
+		response, err := c.graphServiceClient.ServicePrincipals().Get(ctx, &options)
+		for {
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, response.GetValue()...)
+
+			nextLink := response.GetOdataNextLink()
+			if nextLink == nil {
+				break
+			}
+
+			response, err = c.graphServiceClient.ServicePrincipals().WithUrl(*nextLink).Get(ctx, nil)
+		}
+	}
+
+	return result, nil
+}
+
+// notFoundOr translates the error Graph returns for a missing object into backend.ErrNotFound.
+func notFoundOr(err error, id string) error {
+	if strings.HasPrefix(err.Error(), fmt.Sprintf("Resource '%s' does not exist", id)) {
+		return backend.ErrNotFound
+	}
+
+	return err
+}
+
+// servicePrincipalNameFilter builds a filter that matches service principals by object Id or application
+// Id if the given name is a UUID, otherwise by display name.
+func servicePrincipalNameFilter(servicePrincipalName string) string {
+	if _, err := uuid.Parse(servicePrincipalName); err == nil {
+		return fmt.Sprintf("%s or %s", eqFilter("id", servicePrincipalName), eqFilter("appId", servicePrincipalName))
+	}
+
+	return eqFilter("displayName", servicePrincipalName)
+}
+
+// eqFilter builds an `eq` filter on the given property that matches the given value.
+func eqFilter(property string, value string) string {
+	return fmt.Sprintf("%s eq %s", property, quote(value))
+}
+
+// inFilters builds `in` filters on the given property that between them match any of the given values,
+// with no more than maxInFilterValues values in each.
+func inFilters(property string, values []string) []string {
+	var filters []string
+	for _, chunk := range chunk(values) {
+		filters = append(filters, inFilter(property, chunk))
+	}
+
+	return filters
+}
+
+// inFilter builds an `in` filter on the given property that matches any of the given values.
+func inFilter(property string, values []string) string {
+	quotedValues := make([]string, len(values))
+	for i, v := range values {
+		quotedValues[i] = quote(v)
+	}
+
+	return fmt.Sprintf("%s in (%s)", property, strings.Join(quotedValues, ", "))
+}
+
+// quote quotes a value for use in a filter, escaping any single quotes within it.
+func quote(value string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(value, "'", "''"))
+}
+
+// chunk splits values into chunks of no more than maxInFilterValues values.
+func chunk(values []string) [][]string {
+	var chunks [][]string
+	for start := 0; start < len(values); start += maxInFilterValues {
+		chunks = append(chunks, values[start:min(start+maxInFilterValues, len(values))])
+	}
+
+	return chunks
+}
@@ -0,0 +1,68 @@
+package azure
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInFiltersChunksValues(t *testing.T) {
+	var values []string
+	for i := 0; i < 2*maxInFilterValues+1; i++ {
+		values = append(values, fmt.Sprintf("%d", i))
+	}
+
+	filters := inFilters("id", values)
+	if len(filters) != 3 {
+		t.Fatalf("expected 3 filters, got %d", len(filters))
+	}
+
+	if expected := fmt.Sprintf("id in ('%d')", 2*maxInFilterValues); filters[2] != expected {
+		t.Errorf("expected last filter to be %q, got %q", expected, filters[2])
+	}
+}
+
+func TestInFilterEscapesQuotes(t *testing.T) {
+	filter := inFilter("displayName", []string{"O'Brien", "Engineers"})
+	if expected := "displayName in ('O''Brien', 'Engineers')"; filter != expected {
+		t.Errorf("expected %q, got %q", expected, filter)
+	}
+}
+
+func TestEqFilterEscapesQuotes(t *testing.T) {
+	filter := eqFilter("userPrincipalName", "o'brien@example.com")
+	if expected := "userPrincipalName eq 'o''brien@example.com'"; filter != expected {
+		t.Errorf("expected %q, got %q", expected, filter)
+	}
+}
+
+func TestServicePrincipalNameFilter(t *testing.T) {
+	tests := []struct {
+		name                 string
+		servicePrincipalName string
+		expected             string
+	}{
+		{
+			name:                 "display name",
+			servicePrincipalName: "sp-deploy",
+			expected:             "displayName eq 'sp-deploy'",
+		},
+		{
+			name:                 "display name with quote",
+			servicePrincipalName: "O'Brien's app",
+			expected:             "displayName eq 'O''Brien''s app'",
+		},
+		{
+			name:                 "Id",
+			servicePrincipalName: "00000000-0000-0000-0000-000000000001",
+			expected:             "id eq '00000000-0000-0000-0000-000000000001' or appId eq '00000000-0000-0000-0000-000000000001'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if filter := servicePrincipalNameFilter(tt.servicePrincipalName); filter != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// ErrNotFound is returned when a resource that is requested by Id does not exist.
+var ErrNotFound = errors.New("resource not found")
+
+// AuthorizationClient is the subset of the Azure Resource Manager authorization API that is used by Sheriff.
+type AuthorizationClient interface {
+	// GetRoleDefinitionById gets a role definition by its fully qualified Id.
+	GetRoleDefinitionById(ctx context.Context, roleDefinitionId string) (*armauthorization.RoleDefinition, error)
+
+	// ListRoleDefinitionsByName lists the role definitions with the given role name that are visible at the given scope.
+	ListRoleDefinitionsByName(ctx context.Context, scope string, roleName string) ([]*armauthorization.RoleDefinition, error)
+
+	// ListCustomRoleDefinitions lists the custom role definitions that are visible at the given scope.
+	ListCustomRoleDefinitions(ctx context.Context, scope string) ([]*armauthorization.RoleDefinition, error)
+
+	// CreateOrUpdateRoleDefinition creates or updates the role definition with the given name at the given scope.
+	CreateOrUpdateRoleDefinition(ctx context.Context, scope string, roleDefinitionName string, roleDefinition armauthorization.RoleDefinition) error
+
+	// ListRoleAssignmentsForPrincipal lists the role assignments at the given scope that apply to the given principal.
+	ListRoleAssignmentsForPrincipal(ctx context.Context, scope string, principalId string) ([]*armauthorization.RoleAssignment, error)
+
+	// ListRoleAssignmentSchedules lists the role assignment schedules at and above the given scope.
+	ListRoleAssignmentSchedules(ctx context.Context, scope string) ([]*armauthorization.RoleAssignmentSchedule, error)
+
+	// ListRoleEligibilitySchedules lists the role eligibility schedules at and above the given scope.
+	ListRoleEligibilitySchedules(ctx context.Context, scope string) ([]*armauthorization.RoleEligibilitySchedule, error)
+
+	// CreateRoleAssignmentScheduleRequest creates a role assignment schedule request at the given scope.
+	CreateRoleAssignmentScheduleRequest(ctx context.Context, scope string, requestName string, request armauthorization.RoleAssignmentScheduleRequest) error
+
+	// CancelRoleAssignmentScheduleRequest cancels a pending role assignment schedule request at the given scope.
+	CancelRoleAssignmentScheduleRequest(ctx context.Context, scope string, requestName string) error
+
+	// CreateRoleEligibilityScheduleRequest creates a role eligibility schedule request at the given scope.
+	CreateRoleEligibilityScheduleRequest(ctx context.Context, scope string, requestName string, request armauthorization.RoleEligibilityScheduleRequest) error
+
+	// CancelRoleEligibilityScheduleRequest cancels a pending role eligibility schedule request at the given scope.
+	CancelRoleEligibilityScheduleRequest(ctx context.Context, scope string, requestName string) error
+
+	// ListRoleManagementPolicyAssignments lists the role management policy assignments at the given scope.
+	ListRoleManagementPolicyAssignments(ctx context.Context, scope string) ([]*armauthorization.RoleManagementPolicyAssignment, error)
+
+	// GetRoleManagementPolicy gets a role management policy by name at the given scope.
+	GetRoleManagementPolicy(ctx context.Context, scope string, roleManagementPolicyName string) (*armauthorization.RoleManagementPolicy, error)
+
+	// UpdateRoleManagementPolicy updates a role management policy by name at the given scope.
+	UpdateRoleManagementPolicy(ctx context.Context, scope string, roleManagementPolicyName string, roleManagementPolicy armauthorization.RoleManagementPolicy) error
+}
+
+// GraphClient is the subset of the Microsoft Graph API that is used by Sheriff.
+type GraphClient interface {
+	// GetUserById gets a user by object Id.
+	GetUserById(ctx context.Context, userId string) (models.Userable, error)
+
+	// ListUsersByUpn lists the users with the given user principal name.
+	ListUsersByUpn(ctx context.Context, upn string) ([]models.Userable, error)
+
+	// ListUsersByIds lists the users with any of the given object Ids.
+	ListUsersByIds(ctx context.Context, userIds []string) ([]models.Userable, error)
+
+	// ListUsersByUpns lists the users with any of the given user principal names.
+	ListUsersByUpns(ctx context.Context, upns []string) ([]models.Userable, error)
+
+	// GetGroupById gets a group by object Id.
+	GetGroupById(ctx context.Context, groupId string) (models.Groupable, error)
+
+	// ListGroupsByName lists the groups with the given display name.
+	ListGroupsByName(ctx context.Context, groupName string) ([]models.Groupable, error)
+
+	// ListGroupsByIds lists the groups with any of the given object Ids.
+	ListGroupsByIds(ctx context.Context, groupIds []string) ([]models.Groupable, error)
+
+	// ListGroupsByNames lists the groups with any of the given display names.
+	ListGroupsByNames(ctx context.Context, groupNames []string) ([]models.Groupable, error)
+
+	// GetServicePrincipalById gets a service principal by object Id.
+	GetServicePrincipalById(ctx context.Context, servicePrincipalId string) (models.ServicePrincipalable, error)
+
+	// ListServicePrincipalsByName lists the service principals with the given display name, application
+	// (client) Id or object Id.
+	ListServicePrincipalsByName(ctx context.Context, servicePrincipalName string) ([]models.ServicePrincipalable, error)
+
+	// ListServicePrincipalsByIds lists the service principals with any of the given object Ids.
+	ListServicePrincipalsByIds(ctx context.Context, servicePrincipalIds []string) ([]models.ServicePrincipalable, error)
+
+	// ListServicePrincipalsByNames lists the service principals with any of the given display names,
+	// application (client) Ids or object Ids.
+	ListServicePrincipalsByNames(ctx context.Context, servicePrincipalNames []string) ([]models.ServicePrincipalable, error)
+}
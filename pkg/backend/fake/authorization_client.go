@@ -0,0 +1,445 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/google/uuid"
+)
+
+func (b *Backend) GetRoleDefinitionById(ctx context.Context, roleDefinitionId string) (*armauthorization.RoleDefinition, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Role definitions can be referenced by Id at any scope that they are visible at, so match on name.
+	idParts := strings.Split(roleDefinitionId, "/")
+	for _, d := range b.roleDefinitions {
+		if *d.Name == idParts[len(idParts)-1] {
+			return clone(d), nil
+		}
+	}
+
+	return nil, backend.ErrNotFound
+}
+
+func (b *Backend) ListRoleDefinitionsByName(ctx context.Context, scope string, roleName string) ([]*armauthorization.RoleDefinition, error) {
+	return b.listRoleDefinitions(scope, func(d *armauthorization.RoleDefinition) bool {
+		return *d.Properties.RoleName == roleName
+	}), nil
+}
+
+func (b *Backend) ListCustomRoleDefinitions(ctx context.Context, scope string) ([]*armauthorization.RoleDefinition, error) {
+	return b.listRoleDefinitions(scope, func(d *armauthorization.RoleDefinition) bool {
+		return d.Properties.RoleType != nil && *d.Properties.RoleType == "CustomRole"
+	}), nil
+}
+
+func (b *Backend) listRoleDefinitions(scope string, filter func(*armauthorization.RoleDefinition) bool) []*armauthorization.RoleDefinition {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var roleDefinitions []*armauthorization.RoleDefinition
+	for _, d := range b.roleDefinitions {
+		if isAtOrAbove(getRoleDefinitionScope(d), scope) && filter(d) {
+			roleDefinitions = append(roleDefinitions, clone(d))
+		}
+	}
+
+	return roleDefinitions
+}
+
+func (b *Backend) CreateOrUpdateRoleDefinition(ctx context.Context, scope string, roleDefinitionName string, roleDefinition armauthorization.RoleDefinition) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.record("CreateOrUpdateRoleDefinition", scope, roleDefinitionName)
+
+	roleDefinition.ID = to.Ptr(fmt.Sprintf("%s/providers/Microsoft.Authorization/roleDefinitions/%s", scope, roleDefinitionName))
+	roleDefinition.Name = to.Ptr(roleDefinitionName)
+
+	idx := slices.IndexFunc(b.roleDefinitions, func(d *armauthorization.RoleDefinition) bool {
+		return *d.Name == roleDefinitionName
+	})
+	if idx == -1 {
+		b.roleDefinitions = append(b.roleDefinitions, clone(&roleDefinition))
+	} else {
+		b.roleDefinitions[idx] = clone(&roleDefinition)
+	}
+
+	return nil
+}
+
+func (b *Backend) ListRoleAssignmentsForPrincipal(ctx context.Context, scope string, principalId string) ([]*armauthorization.RoleAssignment, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var roleAssignments []*armauthorization.RoleAssignment
+	for _, a := range b.roleAssignments {
+		if *a.Properties.PrincipalID == principalId && isAtOrAbove(*a.Properties.Scope, scope) {
+			roleAssignments = append(roleAssignments, clone(a))
+		}
+	}
+
+	return roleAssignments, nil
+}
+
+func (b *Backend) ListRoleAssignmentSchedules(ctx context.Context, scope string) ([]*armauthorization.RoleAssignmentSchedule, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var roleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule
+	for _, s := range b.roleAssignmentSchedules {
+		if isAtOrAbove(*s.Properties.Scope, scope) || isAtOrAbove(scope, *s.Properties.Scope) {
+			roleAssignmentSchedules = append(roleAssignmentSchedules, clone(s))
+		}
+	}
+
+	return roleAssignmentSchedules, nil
+}
+
+func (b *Backend) ListRoleEligibilitySchedules(ctx context.Context, scope string) ([]*armauthorization.RoleEligibilitySchedule, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var roleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule
+	for _, s := range b.roleEligibilitySchedules {
+		if isAtOrAbove(*s.Properties.Scope, scope) || isAtOrAbove(scope, *s.Properties.Scope) {
+			roleEligibilitySchedules = append(roleEligibilitySchedules, clone(s))
+		}
+	}
+
+	return roleEligibilitySchedules, nil
+}
+
+func (b *Backend) CreateRoleAssignmentScheduleRequest(ctx context.Context, scope string, requestName string, request armauthorization.RoleAssignmentScheduleRequest) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.record("CreateRoleAssignmentScheduleRequest", scope, requestName)
+
+	properties := request.Properties
+
+	idx := slices.IndexFunc(b.roleAssignmentSchedules, func(s *armauthorization.RoleAssignmentSchedule) bool {
+		return *s.Properties.Scope == scope &&
+			*s.Properties.RoleDefinitionID == *properties.RoleDefinitionID &&
+			*s.Properties.PrincipalID == *properties.PrincipalID
+	})
+
+	switch *properties.RequestType {
+	case armauthorization.RequestTypeAdminAssign, armauthorization.RequestTypeAdminUpdate:
+		if *properties.RequestType == armauthorization.RequestTypeAdminAssign && idx != -1 {
+			return fmt.Errorf("role assignment already exists")
+		}
+		if *properties.RequestType == armauthorization.RequestTypeAdminUpdate && idx == -1 {
+			return backend.ErrNotFound
+		}
+
+		principalType, err := b.getPrincipalType(*properties.PrincipalID)
+		if err != nil {
+			return err
+		}
+
+		var startDateTime, endDateTime *time.Time
+		var duration *string
+		if properties.ScheduleInfo != nil {
+			startDateTime = properties.ScheduleInfo.StartDateTime
+			if properties.ScheduleInfo.Expiration != nil {
+				endDateTime = properties.ScheduleInfo.Expiration.EndDateTime
+				duration = properties.ScheduleInfo.Expiration.Duration
+			}
+		}
+		startDateTime, endDateTime, err = getScheduleWindow(startDateTime, endDateTime, duration)
+		if err != nil {
+			return err
+		}
+
+		name := uuid.New().String()
+		roleAssignmentSchedule := &armauthorization.RoleAssignmentSchedule{
+			ID:   to.Ptr(fmt.Sprintf("%s/providers/Microsoft.Authorization/roleAssignmentSchedules/%s", scope, name)),
+			Name: to.Ptr(name),
+			Properties: &armauthorization.RoleAssignmentScheduleProperties{
+				AssignmentType:   to.Ptr(armauthorization.AssignmentTypeAssigned),
+				Condition:        properties.Condition,
+				ConditionVersion: properties.ConditionVersion,
+				EndDateTime:      endDateTime,
+				MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+				PrincipalID:      properties.PrincipalID,
+				PrincipalType:    principalType,
+				RoleDefinitionID: properties.RoleDefinitionID,
+				Scope:            to.Ptr(scope),
+				StartDateTime:    startDateTime,
+				Status:           getScheduleStatus(startDateTime),
+			},
+		}
+
+		if idx == -1 {
+			b.roleAssignmentSchedules = append(b.roleAssignmentSchedules, roleAssignmentSchedule)
+		} else {
+			b.roleAssignmentSchedules[idx] = roleAssignmentSchedule
+		}
+	case armauthorization.RequestTypeAdminRemove:
+		if idx == -1 {
+			return backend.ErrNotFound
+		}
+
+		b.roleAssignmentSchedules = slices.Delete(b.roleAssignmentSchedules, idx, idx+1)
+	default:
+		return fmt.Errorf("unsupported request type \"%s\"", *properties.RequestType)
+	}
+
+	return nil
+}
+
+func (b *Backend) CancelRoleAssignmentScheduleRequest(ctx context.Context, scope string, requestName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.record("CancelRoleAssignmentScheduleRequest", scope, requestName)
+
+	idx := slices.IndexFunc(b.roleAssignmentSchedules, func(s *armauthorization.RoleAssignmentSchedule) bool {
+		return *s.Properties.Scope == scope && *s.Name == requestName
+	})
+	if idx == -1 {
+		return backend.ErrNotFound
+	}
+
+	b.roleAssignmentSchedules = slices.Delete(b.roleAssignmentSchedules, idx, idx+1)
+
+	return nil
+}
+
+func (b *Backend) CreateRoleEligibilityScheduleRequest(ctx context.Context, scope string, requestName string, request armauthorization.RoleEligibilityScheduleRequest) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.record("CreateRoleEligibilityScheduleRequest", scope, requestName)
+
+	properties := request.Properties
+
+	idx := slices.IndexFunc(b.roleEligibilitySchedules, func(s *armauthorization.RoleEligibilitySchedule) bool {
+		return *s.Properties.Scope == scope &&
+			*s.Properties.RoleDefinitionID == *properties.RoleDefinitionID &&
+			*s.Properties.PrincipalID == *properties.PrincipalID
+	})
+
+	switch *properties.RequestType {
+	case armauthorization.RequestTypeAdminAssign, armauthorization.RequestTypeAdminUpdate:
+		if *properties.RequestType == armauthorization.RequestTypeAdminAssign && idx != -1 {
+			return fmt.Errorf("role eligibility already exists")
+		}
+		if *properties.RequestType == armauthorization.RequestTypeAdminUpdate && idx == -1 {
+			return backend.ErrNotFound
+		}
+
+		principalType, err := b.getPrincipalType(*properties.PrincipalID)
+		if err != nil {
+			return err
+		}
+
+		var startDateTime, endDateTime *time.Time
+		var duration *string
+		if properties.ScheduleInfo != nil {
+			startDateTime = properties.ScheduleInfo.StartDateTime
+			if properties.ScheduleInfo.Expiration != nil {
+				endDateTime = properties.ScheduleInfo.Expiration.EndDateTime
+				duration = properties.ScheduleInfo.Expiration.Duration
+			}
+		}
+		startDateTime, endDateTime, err = getScheduleWindow(startDateTime, endDateTime, duration)
+		if err != nil {
+			return err
+		}
+
+		name := uuid.New().String()
+		roleEligibilitySchedule := &armauthorization.RoleEligibilitySchedule{
+			ID:   to.Ptr(fmt.Sprintf("%s/providers/Microsoft.Authorization/roleEligibilitySchedules/%s", scope, name)),
+			Name: to.Ptr(name),
+			Properties: &armauthorization.RoleEligibilityScheduleProperties{
+				Condition:        properties.Condition,
+				ConditionVersion: properties.ConditionVersion,
+				EndDateTime:      endDateTime,
+				MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+				PrincipalID:      properties.PrincipalID,
+				PrincipalType:    principalType,
+				RoleDefinitionID: properties.RoleDefinitionID,
+				Scope:            to.Ptr(scope),
+				StartDateTime:    startDateTime,
+				Status:           getScheduleStatus(startDateTime),
+			},
+		}
+
+		if idx == -1 {
+			b.roleEligibilitySchedules = append(b.roleEligibilitySchedules, roleEligibilitySchedule)
+		} else {
+			b.roleEligibilitySchedules[idx] = roleEligibilitySchedule
+		}
+	case armauthorization.RequestTypeAdminRemove:
+		if idx == -1 {
+			return backend.ErrNotFound
+		}
+
+		b.roleEligibilitySchedules = slices.Delete(b.roleEligibilitySchedules, idx, idx+1)
+	default:
+		return fmt.Errorf("unsupported request type \"%s\"", *properties.RequestType)
+	}
+
+	return nil
+}
+
+func (b *Backend) CancelRoleEligibilityScheduleRequest(ctx context.Context, scope string, requestName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.record("CancelRoleEligibilityScheduleRequest", scope, requestName)
+
+	idx := slices.IndexFunc(b.roleEligibilitySchedules, func(s *armauthorization.RoleEligibilitySchedule) bool {
+		return *s.Properties.Scope == scope && *s.Name == requestName
+	})
+	if idx == -1 {
+		return backend.ErrNotFound
+	}
+
+	b.roleEligibilitySchedules = slices.Delete(b.roleEligibilitySchedules, idx, idx+1)
+
+	return nil
+}
+
+func (b *Backend) ListRoleManagementPolicyAssignments(ctx context.Context, scope string) ([]*armauthorization.RoleManagementPolicyAssignment, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Azure has a role management policy for every role definition at every scope, so create them on first use.
+	for _, d := range b.roleDefinitions {
+		if !isAtOrAbove(getRoleDefinitionScope(d), scope) {
+			continue
+		}
+
+		exists := slices.ContainsFunc(b.roleManagementPolicyAssignments, func(a *armauthorization.RoleManagementPolicyAssignment) bool {
+			return *a.Properties.Scope == scope && *a.Properties.RoleDefinitionID == *d.ID
+		})
+		if !exists {
+			b.addRoleManagementPolicy(scope, d)
+		}
+	}
+
+	var roleManagementPolicyAssignments []*armauthorization.RoleManagementPolicyAssignment
+	for _, a := range b.roleManagementPolicyAssignments {
+		if *a.Properties.Scope == scope {
+			roleManagementPolicyAssignments = append(roleManagementPolicyAssignments, clone(a))
+		}
+	}
+
+	return roleManagementPolicyAssignments, nil
+}
+
+func (b *Backend) addRoleManagementPolicy(scope string, roleDefinition *armauthorization.RoleDefinition) {
+	policyName := uuid.New().String()
+	policyId := fmt.Sprintf("%s/providers/Microsoft.Authorization/roleManagementPolicies/%s", scope, policyName)
+	b.roleManagementPolicies = append(b.roleManagementPolicies, &armauthorization.RoleManagementPolicy{
+		ID:   to.Ptr(policyId),
+		Name: to.Ptr(policyName),
+		Properties: &armauthorization.RoleManagementPolicyProperties{
+			Scope: to.Ptr(scope),
+		},
+	})
+
+	assignmentName := fmt.Sprintf("%s_%s", policyName, *roleDefinition.Name)
+	b.roleManagementPolicyAssignments = append(b.roleManagementPolicyAssignments, &armauthorization.RoleManagementPolicyAssignment{
+		ID:   to.Ptr(fmt.Sprintf("%s/providers/Microsoft.Authorization/roleManagementPolicyAssignments/%s", scope, assignmentName)),
+		Name: to.Ptr(assignmentName),
+		Properties: &armauthorization.RoleManagementPolicyAssignmentProperties{
+			PolicyAssignmentProperties: &armauthorization.PolicyAssignmentProperties{
+				Policy: &armauthorization.PolicyAssignmentPropertiesPolicy{
+					ID: to.Ptr(policyId),
+				},
+				RoleDefinition: &armauthorization.PolicyAssignmentPropertiesRoleDefinition{
+					DisplayName: roleDefinition.Properties.RoleName,
+					ID:          roleDefinition.ID,
+				},
+				Scope: &armauthorization.PolicyAssignmentPropertiesScope{
+					ID: to.Ptr(scope),
+				},
+			},
+			PolicyID:         to.Ptr(policyId),
+			RoleDefinitionID: roleDefinition.ID,
+			Scope:            to.Ptr(scope),
+		},
+	})
+}
+
+func (b *Backend) GetRoleManagementPolicy(ctx context.Context, scope string, roleManagementPolicyName string) (*armauthorization.RoleManagementPolicy, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, p := range b.roleManagementPolicies {
+		if *p.Properties.Scope == scope && *p.Name == roleManagementPolicyName {
+			return clone(p), nil
+		}
+	}
+
+	return nil, backend.ErrNotFound
+}
+
+func (b *Backend) UpdateRoleManagementPolicy(ctx context.Context, scope string, roleManagementPolicyName string, roleManagementPolicy armauthorization.RoleManagementPolicy) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.record("UpdateRoleManagementPolicy", scope, roleManagementPolicyName)
+
+	idx := slices.IndexFunc(b.roleManagementPolicies, func(p *armauthorization.RoleManagementPolicy) bool {
+		return *p.Properties.Scope == scope && *p.Name == roleManagementPolicyName
+	})
+	if idx == -1 {
+		return backend.ErrNotFound
+	}
+
+	policy := b.roleManagementPolicies[idx]
+	policy.Properties.Rules = clone(&roleManagementPolicy).Properties.Rules
+
+	for _, a := range b.roleManagementPolicyAssignments {
+		if *a.Properties.PolicyID == *policy.ID {
+			a.Properties.EffectiveRules = clone(policy).Properties.Rules
+		}
+	}
+
+	return nil
+}
+
+// getRoleDefinitionScope gets the scope that a role definition was created at.
+func getRoleDefinitionScope(roleDefinition *armauthorization.RoleDefinition) string {
+	return strings.Split(*roleDefinition.ID, "/providers/Microsoft.Authorization/roleDefinitions/")[0]
+}
+
+// getScheduleWindow gets the start and end of a schedule from the schedule info of a request.
+func getScheduleWindow(startDateTime *time.Time, endDateTime *time.Time, duration *string) (*time.Time, *time.Time, error) {
+	if startDateTime == nil {
+		startDateTime = to.Ptr(time.Now().UTC())
+	}
+
+	if duration != nil {
+		d, err := core.ParseDuration(*duration)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		endDateTime = to.Ptr(d.AddTo(*startDateTime))
+	}
+
+	return startDateTime, endDateTime, nil
+}
+
+// getScheduleStatus gets the status of a schedule that starts at the given time.
+func getScheduleStatus(startDateTime *time.Time) *armauthorization.Status {
+	if startDateTime.After(time.Now()) {
+		return to.Ptr(armauthorization.StatusScheduleCreated)
+	}
+
+	return to.Ptr(armauthorization.StatusProvisioned)
+}
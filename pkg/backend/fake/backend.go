@@ -0,0 +1,245 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/google/uuid"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+var (
+	_ backend.AuthorizationClient = (*Backend)(nil)
+	_ backend.GraphClient         = (*Backend)(nil)
+)
+
+// Request is a write that was made to a Backend.
+type Request struct {
+	// Operation is the name of the backend method that was called, e.g. "CreateRoleAssignmentScheduleRequest".
+	Operation string
+
+	// Scope is the scope that the write was made at.
+	Scope string
+
+	// Name is the name of the resource that was written.
+	Name string
+}
+
+// Backend is an in-memory implementation of backend.AuthorizationClient and backend.GraphClient, holding
+// users, groups, service principals, role definitions, role assignments, schedules and role management policies.
+// Schedule requests are applied to the schedules it holds, so a plan that has been applied to a Backend can be
+// planned again to check that it converges.
+type Backend struct {
+	mu sync.Mutex
+
+	users             []models.Userable
+	groups            []models.Groupable
+	servicePrincipals []models.ServicePrincipalable
+
+	roleDefinitions                 []*armauthorization.RoleDefinition
+	roleAssignments                 []*armauthorization.RoleAssignment
+	roleAssignmentSchedules         []*armauthorization.RoleAssignmentSchedule
+	roleEligibilitySchedules        []*armauthorization.RoleEligibilitySchedule
+	roleManagementPolicies          []*armauthorization.RoleManagementPolicy
+	roleManagementPolicyAssignments []*armauthorization.RoleManagementPolicyAssignment
+
+	requests []*Request
+}
+
+// NewBackend creates an empty Backend.
+func NewBackend() *Backend {
+	return &Backend{}
+}
+
+// AddUser adds a user with the given object Id and user principal name.
+func (b *Backend) AddUser(userId string, upn string) models.Userable {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	user := models.NewUser()
+	user.SetId(to.Ptr(userId))
+	user.SetUserPrincipalName(to.Ptr(upn))
+	user.SetDisplayName(to.Ptr(upn))
+	b.users = append(b.users, user)
+
+	return user
+}
+
+// AddGroup adds a group with the given object Id and display name.
+func (b *Backend) AddGroup(groupId string, displayName string) models.Groupable {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group := models.NewGroup()
+	group.SetId(to.Ptr(groupId))
+	group.SetDisplayName(to.Ptr(displayName))
+	b.groups = append(b.groups, group)
+
+	return group
+}
+
+// AddServicePrincipal adds a service principal with the given object Id, application (client) Id and display name.
+func (b *Backend) AddServicePrincipal(servicePrincipalId string, appId string, displayName string) models.ServicePrincipalable {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	servicePrincipal := models.NewServicePrincipal()
+	servicePrincipal.SetId(to.Ptr(servicePrincipalId))
+	servicePrincipal.SetAppId(to.Ptr(appId))
+	servicePrincipal.SetDisplayName(to.Ptr(displayName))
+	b.servicePrincipals = append(b.servicePrincipals, servicePrincipal)
+
+	return servicePrincipal
+}
+
+// AddRoleDefinition adds a role definition at the given scope, which is visible at that scope and all scopes
+// below it. Built-in role definitions are added at the root scope, "/".
+func (b *Backend) AddRoleDefinition(scope string, roleDefinition *armauthorization.RoleDefinition) *armauthorization.RoleDefinition {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	roleDefinition = clone(roleDefinition)
+	if roleDefinition.Name == nil {
+		roleDefinition.Name = to.Ptr(uuid.New().String())
+	}
+	roleDefinition.ID = to.Ptr(fmt.Sprintf("%s/providers/Microsoft.Authorization/roleDefinitions/%s", strings.TrimSuffix(scope, "/"), *roleDefinition.Name))
+	b.roleDefinitions = append(b.roleDefinitions, roleDefinition)
+
+	return clone(roleDefinition)
+}
+
+// AddRoleAssignment adds a role assignment of the given role definition to the given principal at the given scope.
+func (b *Backend) AddRoleAssignment(scope string, principalId string, roleDefinitionId string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	name := uuid.New().String()
+	b.roleAssignments = append(b.roleAssignments, &armauthorization.RoleAssignment{
+		ID:   to.Ptr(fmt.Sprintf("%s/providers/Microsoft.Authorization/roleAssignments/%s", scope, name)),
+		Name: to.Ptr(name),
+		Properties: &armauthorization.RoleAssignmentProperties{
+			PrincipalID:      to.Ptr(principalId),
+			RoleDefinitionID: to.Ptr(roleDefinitionId),
+			Scope:            to.Ptr(scope),
+		},
+	})
+}
+
+// AddRoleAssignmentSchedule adds an existing role assignment schedule.
+func (b *Backend) AddRoleAssignmentSchedule(roleAssignmentSchedule *armauthorization.RoleAssignmentSchedule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.roleAssignmentSchedules = append(b.roleAssignmentSchedules, clone(roleAssignmentSchedule))
+}
+
+// AddRoleEligibilitySchedule adds an existing role eligibility schedule.
+func (b *Backend) AddRoleEligibilitySchedule(roleEligibilitySchedule *armauthorization.RoleEligibilitySchedule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.roleEligibilitySchedules = append(b.roleEligibilitySchedules, clone(roleEligibilitySchedule))
+}
+
+// RoleAssignmentSchedules gets the role assignment schedules that the Backend holds.
+func (b *Backend) RoleAssignmentSchedules() []*armauthorization.RoleAssignmentSchedule {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return cloneAll(b.roleAssignmentSchedules)
+}
+
+// RoleEligibilitySchedules gets the role eligibility schedules that the Backend holds.
+func (b *Backend) RoleEligibilitySchedules() []*armauthorization.RoleEligibilitySchedule {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return cloneAll(b.roleEligibilitySchedules)
+}
+
+// RoleDefinitions gets the role definitions that the Backend holds.
+func (b *Backend) RoleDefinitions() []*armauthorization.RoleDefinition {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return cloneAll(b.roleDefinitions)
+}
+
+// Requests gets the writes that have been made to the Backend, in the order that they were made.
+func (b *Backend) Requests() []*Request {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	requests := make([]*Request, len(b.requests))
+	copy(requests, b.requests)
+
+	return requests
+}
+
+func (b *Backend) record(operation string, scope string, name string) {
+	b.requests = append(b.requests, &Request{
+		Operation: operation,
+		Scope:     scope,
+		Name:      name,
+	})
+}
+
+// getPrincipalType gets the type of the principal with the given object Id.
+func (b *Backend) getPrincipalType(principalId string) (*armauthorization.PrincipalType, error) {
+	for _, u := range b.users {
+		if *u.GetId() == principalId {
+			return to.Ptr(armauthorization.PrincipalTypeUser), nil
+		}
+	}
+
+	for _, g := range b.groups {
+		if *g.GetId() == principalId {
+			return to.Ptr(armauthorization.PrincipalTypeGroup), nil
+		}
+	}
+
+	for _, s := range b.servicePrincipals {
+		if *s.GetId() == principalId {
+			return to.Ptr(armauthorization.PrincipalTypeServicePrincipal), nil
+		}
+	}
+
+	return nil, fmt.Errorf("principal with Id \"%s\" not found", principalId)
+}
+
+// isAtOrAbove determines whether scope is the same as, or an ancestor of, otherScope.
+func isAtOrAbove(scope string, otherScope string) bool {
+	scope = strings.TrimSuffix(scope, "/")
+
+	return scope == "" || scope == otherScope || strings.HasPrefix(otherScope, scope+"/")
+}
+
+// clone deep copies an Azure model, so that callers cannot modify the state of the Backend.
+func clone[T any](value *T) *T {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	var cloned T
+	err = json.Unmarshal(data, &cloned)
+	if err != nil {
+		panic(err)
+	}
+
+	return &cloned
+}
+
+func cloneAll[T any](values []*T) []*T {
+	var cloned []*T
+	for _, v := range values {
+		cloned = append(cloned, clone(v))
+	}
+
+	return cloned
+}
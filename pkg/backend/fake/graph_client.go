@@ -0,0 +1,192 @@
+package fake
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/google/uuid"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func (b *Backend) GetUserById(ctx context.Context, userId string) (models.Userable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, u := range b.users {
+		if *u.GetId() == userId {
+			return u, nil
+		}
+	}
+
+	return nil, backend.ErrNotFound
+}
+
+func (b *Backend) ListUsersByUpn(ctx context.Context, upn string) ([]models.Userable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var users []models.Userable
+	for _, u := range b.users {
+		if strings.EqualFold(*u.GetUserPrincipalName(), upn) {
+			users = append(users, u)
+		}
+	}
+
+	return users, nil
+}
+
+func (b *Backend) ListUsersByIds(ctx context.Context, userIds []string) ([]models.Userable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var users []models.Userable
+	for _, u := range b.users {
+		if containsFold(userIds, *u.GetId()) {
+			users = append(users, u)
+		}
+	}
+
+	return users, nil
+}
+
+func (b *Backend) ListUsersByUpns(ctx context.Context, upns []string) ([]models.Userable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var users []models.Userable
+	for _, u := range b.users {
+		if containsFold(upns, *u.GetUserPrincipalName()) {
+			users = append(users, u)
+		}
+	}
+
+	return users, nil
+}
+
+func (b *Backend) GetGroupById(ctx context.Context, groupId string) (models.Groupable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, g := range b.groups {
+		if *g.GetId() == groupId {
+			return g, nil
+		}
+	}
+
+	return nil, backend.ErrNotFound
+}
+
+func (b *Backend) ListGroupsByName(ctx context.Context, groupName string) ([]models.Groupable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var groups []models.Groupable
+	for _, g := range b.groups {
+		if strings.EqualFold(*g.GetDisplayName(), groupName) {
+			groups = append(groups, g)
+		}
+	}
+
+	return groups, nil
+}
+
+func (b *Backend) ListGroupsByIds(ctx context.Context, groupIds []string) ([]models.Groupable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var groups []models.Groupable
+	for _, g := range b.groups {
+		if containsFold(groupIds, *g.GetId()) {
+			groups = append(groups, g)
+		}
+	}
+
+	return groups, nil
+}
+
+func (b *Backend) ListGroupsByNames(ctx context.Context, groupNames []string) ([]models.Groupable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var groups []models.Groupable
+	for _, g := range b.groups {
+		if containsFold(groupNames, *g.GetDisplayName()) {
+			groups = append(groups, g)
+		}
+	}
+
+	return groups, nil
+}
+
+func (b *Backend) GetServicePrincipalById(ctx context.Context, servicePrincipalId string) (models.ServicePrincipalable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.servicePrincipals {
+		if *s.GetId() == servicePrincipalId {
+			return s, nil
+		}
+	}
+
+	return nil, backend.ErrNotFound
+}
+
+func (b *Backend) ListServicePrincipalsByName(ctx context.Context, servicePrincipalName string) ([]models.ServicePrincipalable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, err := uuid.Parse(servicePrincipalName)
+	isId := err == nil
+
+	var servicePrincipals []models.ServicePrincipalable
+	for _, s := range b.servicePrincipals {
+		if isId && (*s.GetId() == servicePrincipalName || *s.GetAppId() == servicePrincipalName) ||
+			!isId && strings.EqualFold(*s.GetDisplayName(), servicePrincipalName) {
+			servicePrincipals = append(servicePrincipals, s)
+		}
+	}
+
+	return servicePrincipals, nil
+}
+
+func (b *Backend) ListServicePrincipalsByIds(ctx context.Context, servicePrincipalIds []string) ([]models.ServicePrincipalable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var servicePrincipals []models.ServicePrincipalable
+	for _, s := range b.servicePrincipals {
+		if containsFold(servicePrincipalIds, *s.GetId()) {
+			servicePrincipals = append(servicePrincipals, s)
+		}
+	}
+
+	return servicePrincipals, nil
+}
+
+func (b *Backend) ListServicePrincipalsByNames(ctx context.Context, servicePrincipalNames []string) ([]models.ServicePrincipalable, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var servicePrincipals []models.ServicePrincipalable
+	for _, s := range b.servicePrincipals {
+		if containsFold(servicePrincipalNames, *s.GetId()) ||
+			containsFold(servicePrincipalNames, *s.GetAppId()) ||
+			containsFold(servicePrincipalNames, *s.GetDisplayName()) {
+			servicePrincipals = append(servicePrincipals, s)
+		}
+	}
+
+	return servicePrincipals, nil
+}
+
+// containsFold determines whether values contains value, ignoring case as Graph filters do.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
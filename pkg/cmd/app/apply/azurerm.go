@@ -5,30 +5,43 @@ import (
 	_ "embed"
 	"fmt"
 	"strings"
+	"sync"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armsubscriptions"
 	"github.com/ahmetb/go-linq/v3"
 	"github.com/go-test/deep"
 	"github.com/gofrontier-com/go-utils/output"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/backend/azure"
 	"github.com/gofrontier-com/sheriff/pkg/core"
 	"github.com/gofrontier-com/sheriff/pkg/util/azurerm_config"
+	"github.com/gofrontier-com/sheriff/pkg/util/config_schema"
 	"github.com/gofrontier-com/sheriff/pkg/util/group"
+	"github.com/gofrontier-com/sheriff/pkg/util/output_format"
+	"github.com/gofrontier-com/sheriff/pkg/util/parallel"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_assignment_schedule"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_assignment_schedule_create"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_assignment_schedule_delete"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_assignment_schedule_update"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
+	"github.com/gofrontier-com/sheriff/pkg/util/role_definition_create"
+	"github.com/gofrontier-com/sheriff/pkg/util/role_definition_update"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_eligibility_schedule"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_eligibility_schedule_create"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_eligibility_schedule_delete"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_eligibility_schedule_update"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_management_policy_update"
+	"github.com/gofrontier-com/sheriff/pkg/util/saved_plan"
+	"github.com/gofrontier-com/sheriff/pkg/util/schedule"
+	"github.com/gofrontier-com/sheriff/pkg/util/service_principal"
+	"github.com/gofrontier-com/sheriff/pkg/util/subscription"
 	"github.com/gofrontier-com/sheriff/pkg/util/user"
 	"github.com/golang-jwt/jwt/v5"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
 const (
@@ -36,7 +49,7 @@ const (
 )
 
 //go:embed default_role_management_policy.json
-var defaultRoleManagementPolicyPropertiesData string
+var DefaultRoleManagementPolicyPropertiesData string
 
 var (
 	requiredActionsToApply = []string{
@@ -51,50 +64,270 @@ var (
 	}
 )
 
+var credential *azidentity.DefaultAzureCredential
+
 func init() {
 	deep.NilSlicesAreEmpty = true
 }
 
-func ApplyAzureRm(configDir string, subscriptionId string, planOnly bool) error {
-	scope := fmt.Sprintf("/subscriptions/%s", subscriptionId)
+// GetSubscriptions gets the subscriptions with the given display names or Ids.
+func GetSubscriptions(ctx context.Context, subscriptionNames []string) ([]*armsubscriptions.Subscription, error) {
+	credential, err := getCredential()
+	if err != nil {
+		return nil, err
+	}
 
-	var warnings []string
+	client, err := armsubscriptions.NewClient(credential, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []*armsubscriptions.Subscription
+	for _, n := range subscriptionNames {
+		s, err := subscription.GetSubscriptionByName(ctx, client, n)
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, s)
+	}
+
+	return subscriptions, nil
+}
+
+// ApplyAzureRm plans and, unless planOnly is set, applies the config in the given config dir at the given
+// scopes. If planFilePath is set, the plan is saved to it so that it can be applied by ApplyAzureRmPlan. If
+// outputFormat is a machine-readable format, a document of the plan is written to stdout once it succeeds. The
+// plan is returned so that the caller can tell whether it has changes.
+func ApplyAzureRm(ctx context.Context, configDir string, varFilePaths []string, vars []string, scopes []string, defaults *core.ScheduleRequestDefaults, planOnly bool, planFilePath string, outputFormat string) (*core.Plan, error) {
+	output.PrintlnInfo("Initialising...")
+
+	output.PrintlnInfo("- Loading and validating config")
+
+	config, configHash, warnings, err := loadAndValidateConfig(configDir, varFilePaths, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	output.PrintlnfInfo("- Authenticating to Azure Management and Microsoft Graph APIs")
+
+	credential, err := getCredential()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationClient, err := azure.NewAuthorizationClient(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	graphClient, err := azure.NewGraphClient(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	principalId, err := getPrincipalId(ctx, credential)
+	if err != nil {
+		return nil, err
+	}
+
+	// The live state is hashed before planning, so that anything that changes while planning is caught when the
+	// saved plan is applied.
+	var stateHash string
+	if planFilePath != "" {
+		stateHash, err = saved_plan.GetStateHash(ctx, authorizationClient, config, scopes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan, err := applyAzureRm(ctx, authorizationClient, graphClient, principalId, config, scopes, defaults, planOnly, warnings)
+	if err != nil {
+		return nil, err
+	}
+
+	if planFilePath != "" {
+		err = savePlan(planFilePath, plan, scopes, configHash, stateHash)
+		if err != nil {
+			return nil, err
+		}
+	}
 
+	err = writePlanDocument(outputFormat, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// ApplyAzureRmPlan applies a plan that was saved by ApplyAzureRm, without planning again. The plan is refused
+// if the config in the given config dir, or the live state, that it was based on has changed since it was saved.
+// If outputFormat is a machine-readable format, a document of the plan is written to stdout once it succeeds.
+func ApplyAzureRmPlan(ctx context.Context, configDir string, varFilePaths []string, vars []string, savedPlan *core.SavedPlan, outputFormat string) error {
 	output.PrintlnInfo("Initialising...")
 
 	output.PrintlnInfo("- Loading and validating config")
 
-	config, err := azurerm_config.Load(configDir)
+	config, configHash, _, err := loadAndValidateConfig(configDir, varFilePaths, vars)
+	if err != nil {
+		return err
+	}
+
+	output.PrintlnfInfo("- Authenticating to Azure Management and Microsoft Graph APIs")
+
+	credential, err := getCredential()
+	if err != nil {
+		return err
+	}
+
+	authorizationClient, err := azure.NewAuthorizationClient(credential)
+	if err != nil {
+		return err
+	}
+
+	graphClient, err := azure.NewGraphClient(credential)
+	if err != nil {
+		return err
+	}
+
+	principalId, err := getPrincipalId(ctx, credential)
+	if err != nil {
+		return err
+	}
+
+	err = applySavedPlan(ctx, authorizationClient, graphClient, principalId, savedPlan, config, configHash)
+	if err != nil {
+		return err
+	}
+
+	return writePlanDocument(outputFormat, savedPlan.Plan)
+}
+
+// loadAndValidateConfig loads the config in the given config dir, interpolated with the given variables, and
+// validates it. The hash of the config is returned along with it so that a saved plan can be checked against it,
+// as are any warnings to show before planning.
+func loadAndValidateConfig(configDir string, varFilePaths []string, vars []string) (*core.AzureRmConfig, string, []string, error) {
+	var warnings []string
+
+	variables, err := azurerm_config.LoadVariables(configDir, varFilePaths, vars)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	config, err := azurerm_config.Load(configDir, variables)
 	if err != nil {
 		if _, ok := err.(*core.ConfigurationEmptyError); ok {
 			warnings = append(warnings, "Configuration is empty, is the config path correct?")
 		} else {
-			return err
+			return nil, "", nil, err
 		}
 	}
 
 	err = config.Validate()
 	if err != nil {
-		return err
+		return nil, "", nil, err
 	}
 
-	output.PrintlnfInfo("- Authenticating to Azure Management and Microsoft Graph APIs")
+	rulesetConfigErrors, err := config_schema.ValidateRulesets(config.Rulesets, DefaultRoleManagementPolicyPropertiesData)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if len(rulesetConfigErrors) > 0 {
+		return nil, "", nil, rulesetConfigErrors
+	}
+
+	configHash, err := azurerm_config.GetHash(configDir, variables)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return config, configHash, warnings, nil
+}
+
+// writePlanDocument writes the document of a plan to stdout if outputFormat is a machine-readable format.
+func writePlanDocument(outputFormat string, plan *core.Plan) error {
+	if !output_format.IsMachineReadable(outputFormat) {
+		return nil
+	}
+
+	return output_format.Write(outputFormat, core.NewPlanDocument(plan))
+}
 
-	credential, err := azidentity.NewDefaultAzureCredential(nil)
+// savePlan saves a plan to a file, along with the hashes of the config and the live state that it is based on. The
+// state hash must be taken before planning, as the live state may change while planning.
+func savePlan(planFilePath string, plan *core.Plan, scopes []string, configHash string, stateHash string) error {
+	err := saved_plan.Save(planFilePath, &core.SavedPlan{
+		ConfigHash: configHash,
+		Plan:       plan,
+		Scopes:     scopes,
+		StateHash:  stateHash,
+	})
 	if err != nil {
 		return err
 	}
 
-	clientFactory, err := armauthorization.NewClientFactory(subscriptionId, credential, nil)
+	output.PrintlnfInfo("\nPlan saved to %s. To apply exactly this plan, run: sheriff apply azurerm %s", planFilePath, planFilePath)
+
+	return nil
+}
+
+// applySavedPlan applies a saved plan using the given backends, on behalf of the principal with the given Id.
+// config and configHash are the config as it is now and its hash, which must match the config that the plan was
+// generated from.
+func applySavedPlan(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
+	principalId string,
+	savedPlan *core.SavedPlan,
+	config *core.AzureRmConfig,
+	configHash string,
+) error {
+	if configHash != savedPlan.ConfigHash {
+		return fmt.Errorf("the config has changed since the plan was saved, run plan again")
+	}
+
+	output.PrintlnInfo("- Checking for necessary permissions")
+
+	err := checkPermissions(ctx, authorizationClient, graphClient, principalId, savedPlan.Scopes, requiredActionsToApply)
 	if err != nil {
 		return err
 	}
 
-	graphServiceClient, err := msgraphsdkgo.NewGraphServiceClientWithCredentials(credential, []string{"https://graph.microsoft.com/.default"})
+	output.PrintlnInfo("- Checking for changes since the plan was saved\n")
+
+	stateHash, err := saved_plan.GetStateHash(ctx, authorizationClient, config, savedPlan.Scopes)
 	if err != nil {
 		return err
 	}
 
+	if stateHash != savedPlan.StateHash {
+		return fmt.Errorf("the custom role definitions, role assignments or role management policies in Azure have changed since the plan was saved, run plan again")
+	}
+
+	output.PrintlnInfo("Sheriff is ready to go!\n")
+
+	output.PrintlnInfo("Sheriff will perform the following actions:\n")
+
+	printPlan(savedPlan.Plan)
+
+	return executePlan(ctx, authorizationClient, savedPlan.Plan)
+}
+
+// applyAzureRm plans and, unless planOnly is set, applies the given config using the given backends, on behalf
+// of the principal with the given Id. The plan is returned so that it can be saved.
+func applyAzureRm(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
+	principalId string,
+	config *core.AzureRmConfig,
+	scopes []string,
+	defaults *core.ScheduleRequestDefaults,
+	planOnly bool,
+	warnings []string,
+) (*core.Plan, error) {
 	output.PrintlnInfo("- Checking for necessary permissions\n")
 
 	var requiredActions []string
@@ -103,31 +336,607 @@ func ApplyAzureRm(configDir string, subscriptionId string, planOnly bool) error
 	} else {
 		requiredActions = requiredActionsToApply
 	}
-	err = checkPermissions(clientFactory, graphServiceClient, credential, scope, requiredActions)
+	err := checkPermissions(ctx, authorizationClient, graphClient, principalId, scopes, requiredActions)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(warnings) > 0 {
+		output.PrintlnWarn("!!! One or more warnings were generated !!!")
+
+		for _, w := range warnings {
+			output.PrintlnfWarn("- %s", w)
+		}
+
+		output.Printf("\n")
+	}
+
+	output.PrintlnInfo("Sheriff is ready to go!\n")
+
+	output.PrintlnInfo("Resolving principals...\n")
+
+	err = resolvePrincipals(ctx, authorizationClient, graphClient, config, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Service principals can be referenced by display name, app Id or object Id, so resolve each to its object
+	// Id once in order to match existing schedules. The name is kept as written in config.
+	for _, p := range config.ServicePrincipals {
+		servicePrincipal, err := service_principal.GetServicePrincipalByName(ctx, graphClient, p.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Id = *servicePrincipal.GetId()
+	}
+
+	output.PrintlnInfo("Generating plan for role definitions...\n")
+
+	roleDefinitionCreates, err := role_definition_create.GetRoleDefinitionCreates(ctx, authorizationClient, config)
+	if err != nil {
+		return nil, err
+	}
+
+	roleDefinitionUpdates, err := role_definition_update.GetRoleDefinitionUpdates(ctx, authorizationClient, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Role definitions that are yet to be created are cached so that schedules can reference them.
+	var pendingRoleNames []string
+	for _, c := range roleDefinitionCreates {
+		for _, scope := range scopes {
+			role_definition.SetRoleDefinition(scope, &armauthorization.RoleDefinition{
+				ID:         to.Ptr(fmt.Sprintf("%s/providers/Microsoft.Authorization/roleDefinitions/%s", scope, c.RoleDefinitionName)),
+				Name:       to.Ptr(c.RoleDefinitionName),
+				Properties: c.RoleDefinition.Properties,
+			})
+		}
+
+		pendingRoleNames = append(pendingRoleNames, c.RoleName)
+	}
+
+	plans := []*core.Plan{}
+	for _, scope := range scopes {
+		scopePlan, err := getPlan(ctx, authorizationClient, graphClient, config, scope, pendingRoleNames, defaults)
+		if err != nil {
+			return nil, err
+		}
+
+		plans = append(plans, scopePlan)
+	}
+
+	plan := &core.Plan{
+		RoleDefinitionCreates: roleDefinitionCreates,
+		RoleDefinitionUpdates: roleDefinitionUpdates,
+	}
+	for _, p := range plans {
+		plan.Append(p)
+	}
+
+	if len(pendingRoleNames) > 0 {
+		output.PrintlnWarn("!!! Role management policies for new role definitions will be updated on the next run !!!\n")
+	}
+
+	if planOnly {
+		output.PrintlnInfo("Sheriff would perform the following actions:\n")
+	} else {
+		output.PrintlnInfo("Sheriff will perform the following actions:\n")
+	}
+
+	printPlan(plan)
+
+	if len(plans) > 1 {
+		printPlanTotals(plans)
+	}
+
+	if planOnly {
+		return plan, nil
+	}
+
+	return plan, executePlan(ctx, authorizationClient, plan)
+}
+
+// executePlan applies a plan that has been printed, and reports how much of it was applied.
+func executePlan(ctx context.Context, authorizationClient backend.AuthorizationClient, plan *core.Plan) error {
+	if plan.IsEmpty() {
+		output.PrintlnInfo("\nNothing to do!")
+		return nil
+	}
+
+	output.PrintlnInfo("\nApplying plan...\n")
+
+	applied, err := applyPlan(ctx, authorizationClient, plan)
+	if err != nil {
+		output.PrintlnfError(
+			"\nApply stopped: %d added, %d changed, %d deleted; %d of %d actions were not applied",
+			applied.GetAddCount(),
+			applied.GetChangeCount(),
+			applied.GetDeleteCount(),
+			plan.GetActionCount()-applied.GetActionCount(),
+			plan.GetActionCount(),
+		)
+
+		if ctx.Err() != nil {
+			return fmt.Errorf("apply interrupted: %w", context.Cause(ctx))
+		}
+
+		return err
+	}
+
+	output.PrintlnfInfo("\nApply complete: %d added, %d changed, %d deleted", applied.GetAddCount(), applied.GetChangeCount(), applied.GetDeleteCount())
+
+	return nil
+}
+
+// applyPlan applies the plan and returns the part of it that was applied. Each type of change is applied in
+// turn, with the changes of a type applied in parallel. If ctx is cancelled, for example by an interrupt or a
+// timeout, requests that are in flight are allowed to complete and no further requests are made.
+func applyPlan(ctx context.Context, authorizationClient backend.AuthorizationClient, plan *core.Plan) (*core.Plan, error) {
+	var err error
+	applied := &core.Plan{}
+
+	applied.RoleDefinitionCreates, err = applyAll(
+		ctx,
+		plan.RoleDefinitionCreates,
+		func(c *core.RoleDefinitionCreate) string {
+			return fmt.Sprintf("Created role definition \"%s\" at scope \"%s\"", c.RoleName, c.Scope)
+		},
+		func(ctx context.Context, c *core.RoleDefinitionCreate) error {
+			return authorizationClient.CreateOrUpdateRoleDefinition(ctx, c.Scope, c.RoleDefinitionName, *c.RoleDefinition)
+		},
+	)
+	if err != nil {
+		return applied, err
+	}
+
+	applied.RoleDefinitionUpdates, err = applyAll(
+		ctx,
+		plan.RoleDefinitionUpdates,
+		func(u *core.RoleDefinitionUpdate) string {
+			return fmt.Sprintf("Updated role definition \"%s\" at scope \"%s\"", u.RoleName, u.Scope)
+		},
+		func(ctx context.Context, u *core.RoleDefinitionUpdate) error {
+			return authorizationClient.CreateOrUpdateRoleDefinition(ctx, u.Scope, u.RoleDefinitionName, *u.RoleDefinition)
+		},
+	)
+	if err != nil {
+		return applied, err
+	}
+
+	applied.RoleManagementPolicyUpdates, err = applyAll(
+		ctx,
+		plan.RoleManagementPolicyUpdates,
+		func(u *core.RoleManagementPolicyUpdate) string {
+			return fmt.Sprintf("Updated role management policy for role \"%s\" at scope \"%s\"", u.RoleName, u.Scope)
+		},
+		func(ctx context.Context, u *core.RoleManagementPolicyUpdate) error {
+			return authorizationClient.UpdateRoleManagementPolicy(ctx, u.Scope, *u.RoleManagementPolicy.Name, *u.RoleManagementPolicy)
+		},
+	)
+	if err != nil {
+		return applied, err
+	}
+
+	applied.RoleAssignmentScheduleCreates, err = applyAll(
+		ctx,
+		plan.RoleAssignmentScheduleCreates,
+		func(c *core.RoleAssignmentScheduleCreate) string {
+			return fmt.Sprintf("Created active assignment for %s \"%s\" with role \"%s\" at scope \"%s\"", c.PrincipalType, c.PrincipalName, c.RoleName, c.Scope)
+		},
+		func(ctx context.Context, c *core.RoleAssignmentScheduleCreate) error {
+			return authorizationClient.CreateRoleAssignmentScheduleRequest(ctx, c.Scope, c.RoleAssignmentScheduleRequestName, *c.RoleAssignmentScheduleRequest)
+		},
+	)
+	if err != nil {
+		return applied, err
+	}
+
+	applied.RoleAssignmentScheduleUpdates, err = applyAll(
+		ctx,
+		plan.RoleAssignmentScheduleUpdates,
+		func(u *core.RoleAssignmentScheduleUpdate) string {
+			return fmt.Sprintf("Updated active assignment for %s \"%s\" with role \"%s\" at scope \"%s\"", u.PrincipalType, u.PrincipalName, u.RoleName, u.Scope)
+		},
+		func(ctx context.Context, u *core.RoleAssignmentScheduleUpdate) error {
+			return authorizationClient.CreateRoleAssignmentScheduleRequest(ctx, u.Scope, u.RoleAssignmentScheduleRequestName, *u.RoleAssignmentScheduleRequest)
+		},
+	)
+	if err != nil {
+		return applied, err
+	}
+
+	applied.RoleAssignmentScheduleDeletes, err = applyAll(
+		ctx,
+		plan.RoleAssignmentScheduleDeletes,
+		func(d *core.RoleAssignmentScheduleDelete) string {
+			return fmt.Sprintf("Deleted active assignment for %s \"%s\" with role \"%s\" at scope \"%s\"", d.PrincipalType, d.PrincipalName, d.RoleName, d.Scope)
+		},
+		func(ctx context.Context, d *core.RoleAssignmentScheduleDelete) error {
+			if d.Cancel {
+				return authorizationClient.CancelRoleAssignmentScheduleRequest(ctx, d.Scope, d.RoleAssignmentScheduleRequestName)
+			}
+
+			return authorizationClient.CreateRoleAssignmentScheduleRequest(ctx, d.Scope, d.RoleAssignmentScheduleRequestName, *d.RoleAssignmentScheduleRequest)
+		},
+	)
+	if err != nil {
+		return applied, err
+	}
+
+	applied.RoleEligibilityScheduleCreates, err = applyAll(
+		ctx,
+		plan.RoleEligibilityScheduleCreates,
+		func(c *core.RoleEligibilityScheduleCreate) string {
+			return fmt.Sprintf("Created eligible assignment for %s \"%s\" with role \"%s\" at scope \"%s\"", c.PrincipalType, c.PrincipalName, c.RoleName, c.Scope)
+		},
+		func(ctx context.Context, c *core.RoleEligibilityScheduleCreate) error {
+			return authorizationClient.CreateRoleEligibilityScheduleRequest(ctx, c.Scope, c.RoleEligibilityScheduleRequestName, *c.RoleEligibilityScheduleRequest)
+		},
+	)
+	if err != nil {
+		return applied, err
+	}
+
+	applied.RoleEligibilityScheduleUpdates, err = applyAll(
+		ctx,
+		plan.RoleEligibilityScheduleUpdates,
+		func(u *core.RoleEligibilityScheduleUpdate) string {
+			return fmt.Sprintf("Updated eligible assignment for %s \"%s\" with role \"%s\" at scope \"%s\"", u.PrincipalType, u.PrincipalName, u.RoleName, u.Scope)
+		},
+		func(ctx context.Context, u *core.RoleEligibilityScheduleUpdate) error {
+			return authorizationClient.CreateRoleEligibilityScheduleRequest(ctx, u.Scope, u.RoleEligibilityScheduleRequestName, *u.RoleEligibilityScheduleRequest)
+		},
+	)
+	if err != nil {
+		return applied, err
+	}
+
+	applied.RoleEligibilityScheduleDeletes, err = applyAll(
+		ctx,
+		plan.RoleEligibilityScheduleDeletes,
+		func(d *core.RoleEligibilityScheduleDelete) string {
+			return fmt.Sprintf("Deleted eligible assignment for %s \"%s\" with role \"%s\" at scope \"%s\"", d.PrincipalType, d.PrincipalName, d.RoleName, d.Scope)
+		},
+		func(ctx context.Context, d *core.RoleEligibilityScheduleDelete) error {
+			if d.Cancel {
+				return authorizationClient.CancelRoleEligibilityScheduleRequest(ctx, d.Scope, d.RoleEligibilityScheduleRequestName)
+			}
+
+			return authorizationClient.CreateRoleEligibilityScheduleRequest(ctx, d.Scope, d.RoleEligibilityScheduleRequestName, *d.RoleEligibilityScheduleRequest)
+		},
+	)
+	if err != nil {
+		return applied, err
+	}
+
+	return applied, nil
+}
+
+// applyAll makes the requests for one type of change in parallel and returns the changes that were applied.
+// A line is printed for each applied change in plan order, whatever order the requests complete in.
+func applyAll[T any](
+	ctx context.Context,
+	changes []T,
+	describe func(T) string,
+	request func(context.Context, T) error,
+) ([]T, error) {
+	var mu sync.Mutex
+	done := make([]bool, len(changes))
+	succeeded := make([]bool, len(changes))
+	next := 0
+
+	printDone := func() {
+		for next < len(changes) && done[next] {
+			if succeeded[next] {
+				output.PrintlnInfo(describe(changes[next]))
+			}
+			next++
+		}
+	}
+
+	err := parallel.ForEach(ctx, len(changes), func(ctx context.Context, i int) error {
+		err := applyRequest(ctx, func(ctx context.Context) error {
+			return request(ctx, changes[i])
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		done[i] = true
+		succeeded[i] = err == nil
+		printDone()
+
+		return err
+	})
+
+	// Changes after one that was never started may still have been applied.
+	for i := next; i < len(changes); i++ {
+		done[i] = true
+	}
+	printDone()
+
+	var applied []T
+	for i, c := range changes {
+		if succeeded[i] {
+			applied = append(applied, c)
+		}
+	}
+
+	return applied, err
+}
+
+// applyRequest makes a single write request. The request is not cancelled with ctx, so that an interrupted
+// apply does not abandon a request part way through, but it is still bound by any deadline that ctx has.
+func applyRequest(ctx context.Context, request func(context.Context) error) error {
+	requestCtx := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		requestCtx, cancel = context.WithDeadline(requestCtx, deadline)
+		defer cancel()
+	}
+
+	return request(requestCtx)
+}
+
+// getCredential gets the credential used to authenticate to Azure, which is shared so that
+// authentication happens only once per run.
+func getCredential() (*azidentity.DefaultAzureCredential, error) {
+	if credential == nil {
+		c, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		credential = c
+	}
+
+	return credential, nil
+}
+
+// getPrincipalId gets the object Id of the principal that the given credential authenticates as.
+func getPrincipalId(ctx context.Context, credential azcore.TokenCredential) (string, error) {
+	accessToken, err := credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := jwt.Parse(accessToken.Token, nil)
+	if err != nil {
+		if err.Error() != "token is unverifiable: no keyfunc was provided" {
+			return "", err
+		}
+	}
+
+	return token.Claims.(jwt.MapClaims)["oid"].(string), nil
+}
+
+// resolvePrincipals looks up, in bulk, every principal that is referenced by config or by an existing schedule
+// at the given scopes, so that the lookups made while planning are served from cache. Principals that are not
+// found, or that are ambiguous, are reported together rather than stopping at the first.
+func resolvePrincipals(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
+	config *core.AzureRmConfig,
+	scopes []string,
+) error {
+	var groupIds, userIds, servicePrincipalIds []string
+	addPrincipalId := func(principalType armauthorization.PrincipalType, principalId string) {
+		switch principalType {
+		case armauthorization.PrincipalTypeGroup:
+			groupIds = append(groupIds, principalId)
+		case armauthorization.PrincipalTypeUser:
+			userIds = append(userIds, principalId)
+		case armauthorization.PrincipalTypeServicePrincipal:
+			if config.ManagesServicePrincipals() {
+				servicePrincipalIds = append(servicePrincipalIds, principalId)
+			}
+		}
+	}
+
+	var groupNames, upns, servicePrincipalNames []string
+	addPrincipalNames := func(names *[]string, schedules []*core.Schedule) {
+		for _, s := range schedules {
+			*names = append(*names, s.PrincipalName)
+		}
+	}
+
+	for _, scope := range scopes {
+		existingRoleAssignmentSchedules, err := role_assignment_schedule.GetRoleAssignmentSchedules(
+			ctx,
+			authorizationClient,
+			scope,
+			func(s *armauthorization.RoleAssignmentSchedule) bool {
+				return true
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range existingRoleAssignmentSchedules {
+			addPrincipalId(*s.Properties.PrincipalType, *s.Properties.PrincipalID)
+		}
+
+		existingRoleEligibilitySchedules, err := role_eligibility_schedule.GetRoleEligibilitySchedules(
+			ctx,
+			authorizationClient,
+			scope,
+			func(s *armauthorization.RoleEligibilitySchedule) bool {
+				return true
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range existingRoleEligibilitySchedules {
+			addPrincipalId(*s.Properties.PrincipalType, *s.Properties.PrincipalID)
+		}
+
+		addPrincipalNames(&groupNames, config.GetGroupAssignmentSchedules(scope))
+		addPrincipalNames(&groupNames, config.GetGroupEligibilitySchedules(scope))
+		addPrincipalNames(&upns, config.GetUserAssignmentSchedules(scope))
+		addPrincipalNames(&upns, config.GetUserEligibilitySchedules(scope))
+	}
+
+	// Every service principal is resolved, as each is resolved to its object Id before planning.
+	for _, p := range config.ServicePrincipals {
+		servicePrincipalNames = append(servicePrincipalNames, p.Name)
+	}
+
+	prefetches := []func(context.Context) (core.PrincipalErrors, error){
+		func(ctx context.Context) (core.PrincipalErrors, error) {
+			return group.PrefetchGroupsByIds(ctx, graphClient, groupIds)
+		},
+		func(ctx context.Context) (core.PrincipalErrors, error) {
+			return group.PrefetchGroupsByNames(ctx, graphClient, groupNames)
+		},
+		func(ctx context.Context) (core.PrincipalErrors, error) {
+			return user.PrefetchUsersByIds(ctx, graphClient, userIds)
+		},
+		func(ctx context.Context) (core.PrincipalErrors, error) {
+			return user.PrefetchUsersByUpns(ctx, graphClient, upns)
+		},
+		func(ctx context.Context) (core.PrincipalErrors, error) {
+			return service_principal.PrefetchServicePrincipalsByIds(ctx, graphClient, servicePrincipalIds)
+		},
+		func(ctx context.Context) (core.PrincipalErrors, error) {
+			return service_principal.PrefetchServicePrincipalsByNames(ctx, graphClient, servicePrincipalNames)
+		},
+	}
+
+	principalErrors := make([]core.PrincipalErrors, len(prefetches))
+	err := parallel.ForEach(ctx, len(prefetches), func(ctx context.Context, i int) error {
+		var err error
+		principalErrors[i], err = prefetches[i](ctx)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	if len(warnings) > 0 {
-		output.PrintlnWarn("!!! One or more warnings were generated !!!")
+	var allPrincipalErrors core.PrincipalErrors
+	for _, e := range principalErrors {
+		allPrincipalErrors = append(allPrincipalErrors, e...)
+	}
+
+	if len(allPrincipalErrors) > 0 {
+		core.SortPrincipalErrors(allPrincipalErrors)
+		return allPrincipalErrors
+	}
+
+	return nil
+}
+
+// prefetchLookups resolves, in parallel, the role definitions that planning the given scope looks up, so that
+// the lookups made while planning are served from cache. Failed lookups are not cached, so they are left to be
+// made again, and reported, by the planning that needs them. Principals are resolved up front by
+// resolvePrincipals.
+func prefetchLookups(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	config *core.AzureRmConfig,
+	scope string,
+) error {
+	existingRoleAssignmentSchedules, err := role_assignment_schedule.GetRoleAssignmentSchedules(
+		ctx,
+		authorizationClient,
+		scope,
+		func(s *armauthorization.RoleAssignmentSchedule) bool {
+			return true
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	existingRoleEligibilitySchedules, err := role_eligibility_schedule.GetRoleEligibilitySchedules(
+		ctx,
+		authorizationClient,
+		scope,
+		func(s *armauthorization.RoleEligibilitySchedule) bool {
+			return true
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	var lookups []func(context.Context) error
+	seen := map[string]bool{}
+	addLookup := func(key string, lookup func(context.Context) error) {
+		if !seen[key] {
+			seen[key] = true
+			lookups = append(lookups, lookup)
+		}
+	}
+
+	addRoleDefinitionIdLookup := func(roleDefinitionId string) {
+		addLookup(fmt.Sprintf("role-definition-id::%s", roleDefinitionId), func(ctx context.Context) error {
+			_, err := role_definition.GetRoleDefinitionById(ctx, authorizationClient, roleDefinitionId)
+			return err
+		})
+	}
+
+	for _, s := range existingRoleAssignmentSchedules {
+		addRoleDefinitionIdLookup(*s.Properties.RoleDefinitionID)
+	}
+
+	for _, s := range existingRoleEligibilitySchedules {
+		addRoleDefinitionIdLookup(*s.Properties.RoleDefinitionID)
+	}
 
-		for _, w := range warnings {
-			output.PrintlnfWarn("- %s", w)
+	addRoleNameLookups := func(schedules []*core.Schedule) {
+		for _, s := range schedules {
+			roleName := s.RoleName
+			addLookup(fmt.Sprintf("role-name::%s", roleName), func(ctx context.Context) error {
+				_, err := role_definition.GetRoleDefinitionByName(ctx, authorizationClient, scope, roleName)
+				return err
+			})
 		}
-
-		output.Printf("\n")
 	}
 
-	output.PrintlnInfo("Sheriff is ready to go!\n")
+	addRoleNameLookups(config.GetGroupAssignmentSchedules(scope))
+	addRoleNameLookups(config.GetGroupEligibilitySchedules(scope))
+	addRoleNameLookups(config.GetUserAssignmentSchedules(scope))
+	addRoleNameLookups(config.GetUserEligibilitySchedules(scope))
+	addRoleNameLookups(config.GetServicePrincipalAssignmentSchedules(scope))
+	addRoleNameLookups(config.GetServicePrincipalEligibilitySchedules(scope))
+
+	return parallel.ForEach(ctx, len(lookups), func(ctx context.Context, i int) error {
+		// Only cancellation stops prefetching; other errors are reported when planning repeats the lookup.
+		if err := lookups[i](ctx); err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		return nil
+	})
+}
 
-	output.PrintlnfInfo("Generating plan for...")
+func getPlan(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
+	config *core.AzureRmConfig,
+	scope string,
+	pendingRoleNames []string,
+	defaults *core.ScheduleRequestDefaults,
+) (*core.Plan, error) {
+	output.PrintlnfInfo("Generating plan for %s...", scope)
 
-	output.PrintlnInfo("- Active assignments")
+	err := prefetchLookups(ctx, authorizationClient, config, scope)
+	if err != nil {
+		return nil, err
+	}
 
-	groupAssignmentSchedules := config.GetGroupAssignmentSchedules(subscriptionId)
+	output.PrintlnInfo("- Active assignments")
 
 	existingGroupRoleAssignmentSchedules, err := role_assignment_schedule.GetRoleAssignmentSchedules(
-		clientFactory,
+		ctx,
+		authorizationClient,
 		scope,
 		func(s *armauthorization.RoleAssignmentSchedule) bool {
 			return *s.Properties.PrincipalType == armauthorization.PrincipalTypeGroup &&
@@ -135,13 +944,25 @@ func ApplyAzureRm(configDir string, subscriptionId string, planOnly bool) error
 		},
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	userAssignmentSchedules := config.GetUserAssignmentSchedules(subscriptionId)
+	groupAssignmentSchedules, err := schedule.KeyRoleAssignmentSchedules(
+		ctx,
+		authorizationClient,
+		graphClient,
+		scope,
+		config.GetGroupAssignmentSchedules(scope),
+		existingGroupRoleAssignmentSchedules,
+		group.GetGroupIdByName,
+	)
+	if err != nil {
+		return nil, err
+	}
 
 	existingUserRoleAssignmentSchedules, err := role_assignment_schedule.GetRoleAssignmentSchedules(
-		clientFactory,
+		ctx,
+		authorizationClient,
 		scope,
 		func(s *armauthorization.RoleAssignmentSchedule) bool {
 			return *s.Properties.PrincipalType == armauthorization.PrincipalTypeUser &&
@@ -149,371 +970,258 @@ func ApplyAzureRm(configDir string, subscriptionId string, planOnly bool) error
 		},
 	)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	userAssignmentSchedules, err := schedule.KeyRoleAssignmentSchedules(
+		ctx,
+		authorizationClient,
+		graphClient,
+		scope,
+		config.GetUserAssignmentSchedules(scope),
+		existingUserRoleAssignmentSchedules,
+		user.GetUserIdByUpn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	existingServicePrincipalRoleAssignmentSchedules, err := role_assignment_schedule.GetRoleAssignmentSchedules(
+		ctx,
+		authorizationClient,
+		scope,
+		func(s *armauthorization.RoleAssignmentSchedule) bool {
+			return config.ManagesServicePrincipals() &&
+				*s.Properties.PrincipalType == armauthorization.PrincipalTypeServicePrincipal &&
+				*s.Properties.AssignmentType == armauthorization.AssignmentTypeAssigned
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	servicePrincipalAssignmentSchedules, err := schedule.KeyRoleAssignmentSchedules(
+		ctx,
+		authorizationClient,
+		graphClient,
+		scope,
+		config.GetServicePrincipalAssignmentSchedules(scope),
+		existingServicePrincipalRoleAssignmentSchedules,
+		nil,
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	roleAssignmentScheduleCreates, err := role_assignment_schedule_create.GetRoleAssignmentScheduleCreates(
-		clientFactory,
-		graphServiceClient,
+		ctx,
+		authorizationClient,
+		graphClient,
 		scope,
 		groupAssignmentSchedules,
-		existingGroupRoleAssignmentSchedules,
 		userAssignmentSchedules,
-		existingUserRoleAssignmentSchedules,
+		servicePrincipalAssignmentSchedules,
+		defaults,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	roleAssignmentScheduleUpdates, err := role_assignment_schedule_update.GetRoleAssignmentScheduleUpdates(
-		clientFactory,
-		graphServiceClient,
+		ctx,
+		authorizationClient,
+		graphClient,
 		scope,
 		groupAssignmentSchedules,
-		existingGroupRoleAssignmentSchedules,
 		userAssignmentSchedules,
-		existingUserRoleAssignmentSchedules,
+		servicePrincipalAssignmentSchedules,
+		defaults,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	roleAssignmentScheduleDeletes, err := role_assignment_schedule_delete.GetRoleAssignmentScheduleDeletes(
-		clientFactory,
-		graphServiceClient,
+		ctx,
+		authorizationClient,
+		graphClient,
 		scope,
 		groupAssignmentSchedules,
-		existingGroupRoleAssignmentSchedules,
 		userAssignmentSchedules,
-		existingUserRoleAssignmentSchedules,
+		servicePrincipalAssignmentSchedules,
+		defaults,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	output.PrintlnInfo("- Eligible assignments")
 
-	groupEligibilitySchedules := config.GetGroupEligibilitySchedules(subscriptionId)
-
 	existingGroupRoleEligibilitySchedules, err := role_eligibility_schedule.GetRoleEligibilitySchedules(
-		clientFactory,
+		ctx,
+		authorizationClient,
 		scope,
 		func(s *armauthorization.RoleEligibilitySchedule) bool {
 			return *s.Properties.PrincipalType == armauthorization.PrincipalTypeGroup
 		},
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	userEligibilitySchedules := config.GetUserEligibilitySchedules(subscriptionId)
+	groupEligibilitySchedules, err := schedule.KeyRoleEligibilitySchedules(
+		ctx,
+		authorizationClient,
+		graphClient,
+		scope,
+		config.GetGroupEligibilitySchedules(scope),
+		existingGroupRoleEligibilitySchedules,
+		group.GetGroupIdByName,
+	)
+	if err != nil {
+		return nil, err
+	}
 
 	existingUserRoleEligibilitySchedules, err := role_eligibility_schedule.GetRoleEligibilitySchedules(
-		clientFactory,
+		ctx,
+		authorizationClient,
 		scope,
 		func(s *armauthorization.RoleEligibilitySchedule) bool {
 			return *s.Properties.PrincipalType == armauthorization.PrincipalTypeUser
 		},
 	)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	userEligibilitySchedules, err := schedule.KeyRoleEligibilitySchedules(
+		ctx,
+		authorizationClient,
+		graphClient,
+		scope,
+		config.GetUserEligibilitySchedules(scope),
+		existingUserRoleEligibilitySchedules,
+		user.GetUserIdByUpn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	existingServicePrincipalRoleEligibilitySchedules, err := role_eligibility_schedule.GetRoleEligibilitySchedules(
+		ctx,
+		authorizationClient,
+		scope,
+		func(s *armauthorization.RoleEligibilitySchedule) bool {
+			return config.ManagesServicePrincipals() &&
+				*s.Properties.PrincipalType == armauthorization.PrincipalTypeServicePrincipal
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	servicePrincipalEligibilitySchedules, err := schedule.KeyRoleEligibilitySchedules(
+		ctx,
+		authorizationClient,
+		graphClient,
+		scope,
+		config.GetServicePrincipalEligibilitySchedules(scope),
+		existingServicePrincipalRoleEligibilitySchedules,
+		nil,
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	roleEligibilityScheduleCreates, err := role_eligibility_schedule_create.GetRoleEligibilityScheduleCreates(
-		clientFactory,
-		graphServiceClient,
+		ctx,
+		authorizationClient,
+		graphClient,
 		scope,
 		groupEligibilitySchedules,
-		existingGroupRoleEligibilitySchedules,
 		userEligibilitySchedules,
-		existingUserRoleEligibilitySchedules,
+		servicePrincipalEligibilitySchedules,
+		defaults,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	roleEligibilityScheduleUpdates, err := role_eligibility_schedule_update.GetRoleEligibilityScheduleUpdates(
-		clientFactory,
-		graphServiceClient,
+		ctx,
+		authorizationClient,
+		graphClient,
 		scope,
 		groupEligibilitySchedules,
-		existingGroupRoleEligibilitySchedules,
 		userEligibilitySchedules,
-		existingUserRoleEligibilitySchedules,
+		servicePrincipalEligibilitySchedules,
+		defaults,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	roleEligibilityScheduleDeletes, err := role_eligibility_schedule_delete.GetRoleEligibilityScheduleDeletes(
-		clientFactory,
-		graphServiceClient,
+		ctx,
+		authorizationClient,
+		graphClient,
 		scope,
 		groupEligibilitySchedules,
-		existingGroupRoleEligibilitySchedules,
 		userEligibilitySchedules,
-		existingUserRoleEligibilitySchedules,
+		servicePrincipalEligibilitySchedules,
+		defaults,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	output.PrintlnInfo("- Role management policies\n")
 
 	roleManagementPolicyUpdates, err := role_management_policy_update.GetRoleManagementPolicyUpdates(
-		clientFactory,
-		defaultRoleManagementPolicyPropertiesData,
+		ctx,
+		authorizationClient,
+		DefaultRoleManagementPolicyPropertiesData,
 		config,
-		subscriptionId,
+		scope,
+		pendingRoleNames,
 	)
 	if err != nil {
-		return err
-	}
-
-	if planOnly {
-		output.PrintlnInfo("Sheriff would perform the following actions:\n")
-	} else {
-		output.PrintlnInfo("Sheriff will perform the following actions:\n")
-	}
-
-	printPlan(
-		roleAssignmentScheduleCreates,
-		roleAssignmentScheduleUpdates,
-		roleAssignmentScheduleDeletes,
-		roleEligibilityScheduleCreates,
-		roleEligibilityScheduleUpdates,
-		roleEligibilityScheduleDeletes,
-		roleManagementPolicyUpdates,
-	)
-
-	if planOnly {
-		return nil
-	}
-
-	if len(roleAssignmentScheduleCreates)+len(roleAssignmentScheduleUpdates)+len(roleAssignmentScheduleDeletes)+len(roleEligibilityScheduleCreates)+len(roleEligibilityScheduleUpdates)+len(roleManagementPolicyUpdates)+len(roleEligibilityScheduleDeletes) == 0 {
-		output.PrintlnInfo("\nNothing to do!")
-		return nil
-	}
-
-	output.PrintlnInfo("\nApplying plan...\n")
-
-	roleManagementPoliciesClient := clientFactory.NewRoleManagementPoliciesClient()
-	roleAssignmentScheduleRequestsClient := clientFactory.NewRoleAssignmentScheduleRequestsClient()
-	roleEligibilityScheduleRequestsClient := clientFactory.NewRoleEligibilityScheduleRequestsClient()
-
-	for _, u := range roleManagementPolicyUpdates {
-		output.PrintlnfInfo(
-			"Updating role management policy for role \"%s\" at scope \"%s\"",
-			u.RoleName,
-			u.Scope,
-		)
-
-		_, err = roleManagementPoliciesClient.Update(
-			context.Background(),
-			u.Scope,
-			*u.RoleManagementPolicy.Name,
-			*u.RoleManagementPolicy,
-			nil,
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, c := range roleAssignmentScheduleCreates {
-		output.PrintlnfInfo(
-			"Creating active assignment for %s \"%s\" with role \"%s\" at scope \"%s\"",
-			c.PrincipalType,
-			c.PrincipalName,
-			c.RoleName,
-			c.Scope,
-		)
-
-		_, err = roleAssignmentScheduleRequestsClient.Create(
-			context.Background(),
-			c.Scope,
-			c.RoleAssignmentScheduleRequestName,
-			*c.RoleAssignmentScheduleRequest,
-			nil,
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, u := range roleAssignmentScheduleUpdates {
-		output.PrintlnfInfo(
-			"Updating active assignment for %s \"%s\" with role \"%s\" at scope \"%s\"",
-			u.PrincipalType,
-			u.PrincipalName,
-			u.RoleName,
-			u.Scope,
-		)
-
-		_, err = roleAssignmentScheduleRequestsClient.Create(
-			context.Background(),
-			u.Scope,
-			u.RoleAssignmentScheduleRequestName,
-			*u.RoleAssignmentScheduleRequest,
-			nil,
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, d := range roleAssignmentScheduleDeletes {
-		output.PrintlnfInfo(
-			"Deleting active assignment for %s \"%s\" with role \"%s\" at scope \"%s\"",
-			d.PrincipalType,
-			d.PrincipalName,
-			d.RoleName,
-			d.Scope,
-		)
-
-		if d.Cancel {
-			_, err = roleAssignmentScheduleRequestsClient.Cancel(
-				context.Background(),
-				d.Scope,
-				d.RoleAssignmentScheduleRequestName,
-				nil,
-			)
-			if err != nil {
-				return err
-			}
-		} else {
-			_, err = roleAssignmentScheduleRequestsClient.Create(
-				context.Background(),
-				d.Scope,
-				d.RoleAssignmentScheduleRequestName,
-				*d.RoleAssignmentScheduleRequest,
-				nil,
-			)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	for _, c := range roleEligibilityScheduleCreates {
-		output.PrintlnfInfo(
-			"Creating eligible assignment for %s \"%s\" with role \"%s\" at scope \"%s\"",
-			c.PrincipalType,
-			c.PrincipalName,
-			c.RoleName,
-			c.Scope,
-		)
-
-		_, err = roleEligibilityScheduleRequestsClient.Create(
-			context.Background(),
-			c.Scope,
-			c.RoleEligibilityScheduleRequestName,
-			*c.RoleEligibilityScheduleRequest,
-			nil,
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, u := range roleEligibilityScheduleUpdates {
-		output.PrintlnfInfo(
-			"Updating eligible assignment for %s \"%s\" with role \"%s\" at scope \"%s\"",
-			u.PrincipalType,
-			u.PrincipalName,
-			u.RoleName,
-			u.Scope,
-		)
-
-		_, err = roleEligibilityScheduleRequestsClient.Create(
-			context.Background(),
-			u.Scope,
-			u.RoleEligibilityScheduleRequestName,
-			*u.RoleEligibilityScheduleRequest,
-			nil,
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, d := range roleEligibilityScheduleDeletes {
-		output.PrintlnfInfo(
-			"Deleting eligible assignment for %s \"%s\" with role \"%s\" at scope \"%s\"",
-			d.PrincipalType,
-			d.PrincipalName,
-			d.RoleName,
-			d.Scope,
-		)
-
-		if d.Cancel {
-			_, err = roleEligibilityScheduleRequestsClient.Cancel(
-				context.Background(),
-				d.Scope,
-				d.RoleEligibilityScheduleRequestName,
-				nil,
-			)
-			if err != nil {
-				return err
-			}
-		} else {
-			_, err = roleEligibilityScheduleRequestsClient.Create(
-				context.Background(),
-				d.Scope,
-				d.RoleEligibilityScheduleRequestName,
-				*d.RoleEligibilityScheduleRequest,
-				nil,
-			)
-			if err != nil {
-				return err
-			}
-		}
+		return nil, err
 	}
 
-	output.PrintlnfInfo("\nApply complete: %d added, %d changed, %d deleted", len(roleAssignmentScheduleCreates)+len(roleEligibilityScheduleCreates), len(roleAssignmentScheduleUpdates)+len(roleEligibilityScheduleUpdates)+len(roleManagementPolicyUpdates), len(roleAssignmentScheduleDeletes)+len(roleEligibilityScheduleDeletes))
-
-	return nil
+	return &core.Plan{
+		RoleAssignmentScheduleCreates:  roleAssignmentScheduleCreates,
+		RoleAssignmentScheduleDeletes:  roleAssignmentScheduleDeletes,
+		RoleAssignmentScheduleUpdates:  roleAssignmentScheduleUpdates,
+		RoleEligibilityScheduleCreates: roleEligibilityScheduleCreates,
+		RoleEligibilityScheduleDeletes: roleEligibilityScheduleDeletes,
+		RoleEligibilityScheduleUpdates: roleEligibilityScheduleUpdates,
+		RoleManagementPolicyUpdates:    roleManagementPolicyUpdates,
+		Scope:                          scope,
+	}, nil
 }
 
 func checkPermissions(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
-	credential *azidentity.DefaultAzureCredential,
-	scope string,
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
+	principalId string,
+	scopes []string,
 	requiredActions []string,
 ) error {
 	errors := []string{}
 
-	accessToken, err := credential.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
-	if err != nil {
-		return err
-	}
-
-	token, err := jwt.Parse(accessToken.Token, nil)
-	if err != nil {
-		if err.Error() != "token is unverifiable: no keyfunc was provided" {
-			return err
-		}
-	}
-
-	principalId := token.Claims.(jwt.MapClaims)["oid"].(string)
-
-	roleAssignmentsClient := clientFactory.NewRoleAssignmentsClient()
-
-	hasRequiredActions := false
-	roleAssignmentsClientListForScopeOptions := &armauthorization.RoleAssignmentsClientListForScopeOptions{
-		Filter: to.Ptr(fmt.Sprintf("assignedTo('%s')", principalId)),
-	}
-	pager := roleAssignmentsClient.NewListForScopePager(scope, roleAssignmentsClientListForScopeOptions)
-	for pager.More() {
-		page, err := pager.NextPage(context.Background())
+	for _, scope := range scopes {
+		hasRequiredActions := false
+		roleAssignments, err := authorizationClient.ListRoleAssignmentsForPrincipal(ctx, scope, principalId)
 		if err != nil {
 			return err
 		}
 
-		for _, r := range page.Value {
+		for _, r := range roleAssignments {
 			roleDefinition, err := role_definition.GetRoleDefinitionById(
-				clientFactory,
+				ctx,
+				authorizationClient,
 				*r.Properties.RoleDefinitionID,
 			)
 			if err != nil {
@@ -531,13 +1239,13 @@ func checkPermissions(
 				break
 			}
 		}
-	}
 
-	if !hasRequiredActions {
-		errors = append(errors, fmt.Sprintf("at least one of the following azurerm actions are required at scope %s: %s", scope, strings.Join(requiredActions, ", ")))
+		if !hasRequiredActions {
+			errors = append(errors, fmt.Sprintf("at least one of the following azurerm actions are required at scope %s: %s", scope, strings.Join(requiredActions, ", ")))
+		}
 	}
 
-	_, err = user.GetUserByUpn(graphServiceClient, "foo@bar.com")
+	_, err := user.GetUserByUpn(ctx, graphClient, "foo@bar.com")
 	if err != nil {
 		message := err.Error()
 		if message == "Insufficient privileges to complete the operation." {
@@ -545,7 +1253,7 @@ func checkPermissions(
 		}
 	}
 
-	_, err = group.GetGroupByName(graphServiceClient, "foo bar")
+	_, err = group.GetGroupByName(ctx, graphClient, "foo bar")
 	if err != nil {
 		message := err.Error()
 		if message == "Insufficient privileges to complete the operation." {
@@ -553,6 +1261,14 @@ func checkPermissions(
 		}
 	}
 
+	_, err = service_principal.GetServicePrincipalByName(ctx, graphClient, "foo bar")
+	if err != nil {
+		message := err.Error()
+		if message == "Insufficient privileges to complete the operation." {
+			errors = append(errors, "at least one of the following microsoft graph permissions are required: Application.Read.All, Directory.Read.All")
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("authenticated principal failed the permissions check with the following error(s):\n- %s", strings.Join(errors, "\n- "))
 	}
@@ -560,24 +1276,35 @@ func checkPermissions(
 	return nil
 }
 
-func printPlan(
-	roleAssignmentScheduleCreates []*core.RoleAssignmentScheduleCreate,
-	roleAssignmentScheduleUpdates []*core.RoleAssignmentScheduleUpdate,
-	roleAssignmentScheduleDeletes []*core.RoleAssignmentScheduleDelete,
-	roleEligibilityScheduleCreates []*core.RoleEligibilityScheduleCreate,
-	roleEligibilityScheduleUpdates []*core.RoleEligibilityScheduleUpdate,
-	roleEligibilityScheduleDeletes []*core.RoleEligibilityScheduleDelete,
-	roleManagementPolicyUpdates []*core.RoleManagementPolicyUpdate,
-) {
+func printPlan(plan *core.Plan) {
 	builder := &strings.Builder{}
 
-	if len(roleAssignmentScheduleCreates)+len(roleAssignmentScheduleUpdates)+len(roleAssignmentScheduleDeletes)+len(roleEligibilityScheduleCreates)+len(roleEligibilityScheduleUpdates)+len(roleEligibilityScheduleDeletes)+len(roleManagementPolicyUpdates) == 0 {
+	if plan.IsEmpty() {
 		builder.WriteString("(none)\n\n")
 	} else {
 
-		if len(roleAssignmentScheduleCreates) > 0 {
-			builder.WriteString("  # Create active assignments:\n\n")
-			for _, c := range roleAssignmentScheduleCreates {
+		if len(plan.RoleDefinitionCreates) > 0 {
+			builder.WriteString("  # Create role definitions:\n\n")
+			for _, c := range plan.RoleDefinitionCreates {
+				builder.WriteString(fmt.Sprintf("    + Role: %s\n", c.RoleName))
+				builder.WriteString(fmt.Sprintf("      Scope: %s\n\n", c.Scope))
+			}
+		}
+
+		if len(plan.RoleDefinitionUpdates) > 0 {
+			builder.WriteString("  # Update role definitions:\n\n")
+			for _, u := range plan.RoleDefinitionUpdates {
+				builder.WriteString(fmt.Sprintf("    ~ Role: %s\n", u.RoleName))
+				builder.WriteString(fmt.Sprintf("      Scope: %s\n\n", u.Scope))
+			}
+		}
+
+		writeScheduleSections(
+			builder,
+			"Create active assignments",
+			plan.RoleAssignmentScheduleCreates,
+			func(c *core.RoleAssignmentScheduleCreate) armauthorization.PrincipalType { return c.PrincipalType },
+			func(c *core.RoleAssignmentScheduleCreate) {
 				builder.WriteString(fmt.Sprintf("    + %s: %s\n", c.PrincipalType, c.PrincipalName))
 				builder.WriteString(fmt.Sprintf("      Role:  %s\n", c.RoleName))
 				builder.WriteString(fmt.Sprintf("      Scope: %s\n", c.Scope))
@@ -585,13 +1312,20 @@ func printPlan(
 				if c.EndDateTime != nil {
 					builder.WriteString(fmt.Sprintf("      End:   %s\n", c.EndDateTime.Format(dateFormat)))
 				}
+				if c.Condition != "" {
+					builder.WriteString(fmt.Sprintf("      Condition: %s\n", c.Condition))
+				}
+				writeRequestInfo(builder, c.Justification, c.Ticket)
 				builder.WriteString("\n")
-			}
-		}
+			},
+		)
 
-		if len(roleEligibilityScheduleCreates) > 0 {
-			builder.WriteString("  # Create eligible assignments:\n\n")
-			for _, c := range roleEligibilityScheduleCreates {
+		writeScheduleSections(
+			builder,
+			"Create eligible assignments",
+			plan.RoleEligibilityScheduleCreates,
+			func(c *core.RoleEligibilityScheduleCreate) armauthorization.PrincipalType { return c.PrincipalType },
+			func(c *core.RoleEligibilityScheduleCreate) {
 				builder.WriteString(fmt.Sprintf("    + %s: %s\n", c.PrincipalType, c.PrincipalName))
 				builder.WriteString(fmt.Sprintf("      Role:  %s\n", c.RoleName))
 				builder.WriteString(fmt.Sprintf("      Scope: %s\n", c.Scope))
@@ -599,13 +1333,20 @@ func printPlan(
 				if c.EndDateTime != nil {
 					builder.WriteString(fmt.Sprintf("      End:   %s\n", c.EndDateTime.Format(dateFormat)))
 				}
+				if c.Condition != "" {
+					builder.WriteString(fmt.Sprintf("      Condition: %s\n", c.Condition))
+				}
+				writeRequestInfo(builder, c.Justification, c.Ticket)
 				builder.WriteString("\n")
-			}
-		}
+			},
+		)
 
-		if len(roleAssignmentScheduleUpdates) > 0 {
-			builder.WriteString("  # Update active assignments:\n\n")
-			for _, u := range roleAssignmentScheduleUpdates {
+		writeScheduleSections(
+			builder,
+			"Update active assignments",
+			plan.RoleAssignmentScheduleUpdates,
+			func(u *core.RoleAssignmentScheduleUpdate) armauthorization.PrincipalType { return u.PrincipalType },
+			func(u *core.RoleAssignmentScheduleUpdate) {
 				builder.WriteString(fmt.Sprintf("    ~ %s: %s\n", u.PrincipalType, u.PrincipalName))
 				builder.WriteString(fmt.Sprintf("      Role:  %s\n", u.RoleName))
 				builder.WriteString(fmt.Sprintf("      Scope: %s\n", u.Scope))
@@ -613,13 +1354,20 @@ func printPlan(
 				if u.EndDateTime != nil {
 					builder.WriteString(fmt.Sprintf("      End:   %s\n", u.EndDateTime.Format(dateFormat)))
 				}
+				if u.Condition != "" {
+					builder.WriteString(fmt.Sprintf("      Condition: %s\n", u.Condition))
+				}
+				writeRequestInfo(builder, u.Justification, u.Ticket)
 				builder.WriteString("\n")
-			}
-		}
+			},
+		)
 
-		if len(roleEligibilityScheduleUpdates) > 0 {
-			builder.WriteString("  # Update eligible assignments:\n\n")
-			for _, u := range roleEligibilityScheduleUpdates {
+		writeScheduleSections(
+			builder,
+			"Update eligible assignments",
+			plan.RoleEligibilityScheduleUpdates,
+			func(u *core.RoleEligibilityScheduleUpdate) armauthorization.PrincipalType { return u.PrincipalType },
+			func(u *core.RoleEligibilityScheduleUpdate) {
 				builder.WriteString(fmt.Sprintf("    ~ %s: %s\n", u.PrincipalType, u.PrincipalName))
 				builder.WriteString(fmt.Sprintf("      Role:  %s\n", u.RoleName))
 				builder.WriteString(fmt.Sprintf("      Scope: %s\n", u.Scope))
@@ -627,21 +1375,28 @@ func printPlan(
 				if u.EndDateTime != nil {
 					builder.WriteString(fmt.Sprintf("      End:   %s\n", u.EndDateTime.Format(dateFormat)))
 				}
+				if u.Condition != "" {
+					builder.WriteString(fmt.Sprintf("      Condition: %s\n", u.Condition))
+				}
+				writeRequestInfo(builder, u.Justification, u.Ticket)
 				builder.WriteString("\n")
-			}
-		}
+			},
+		)
 
-		if len(roleManagementPolicyUpdates) > 0 {
+		if len(plan.RoleManagementPolicyUpdates) > 0 {
 			builder.WriteString("  # Update role management policies:\n\n")
-			for _, u := range roleManagementPolicyUpdates {
+			for _, u := range plan.RoleManagementPolicyUpdates {
 				builder.WriteString(fmt.Sprintf("    ~ Role: %s\n", u.RoleName))
 				builder.WriteString(fmt.Sprintf("      Scope: %s\n\n", u.Scope))
 			}
 		}
 
-		if len(roleAssignmentScheduleDeletes) > 0 {
-			builder.WriteString("  # Delete active assignments:\n\n")
-			for _, d := range roleAssignmentScheduleDeletes {
+		writeScheduleSections(
+			builder,
+			"Delete active assignments",
+			plan.RoleAssignmentScheduleDeletes,
+			func(d *core.RoleAssignmentScheduleDelete) armauthorization.PrincipalType { return d.PrincipalType },
+			func(d *core.RoleAssignmentScheduleDelete) {
 				builder.WriteString(fmt.Sprintf("    - %s: %s\n", d.PrincipalType, d.PrincipalName))
 				builder.WriteString(fmt.Sprintf("      Role:  %s\n", d.RoleName))
 				builder.WriteString(fmt.Sprintf("      Scope: %s\n", d.Scope))
@@ -649,13 +1404,17 @@ func printPlan(
 				if d.EndDateTime != nil {
 					builder.WriteString(fmt.Sprintf("      End:   %s\n", d.EndDateTime.Format(dateFormat)))
 				}
+				writeRequestInfo(builder, d.Justification, d.Ticket)
 				builder.WriteString("\n")
-			}
-		}
+			},
+		)
 
-		if len(roleEligibilityScheduleDeletes) > 0 {
-			builder.WriteString("  # Delete eligible assignments:\n\n")
-			for _, d := range roleEligibilityScheduleDeletes {
+		writeScheduleSections(
+			builder,
+			"Delete eligible assignments",
+			plan.RoleEligibilityScheduleDeletes,
+			func(d *core.RoleEligibilityScheduleDelete) armauthorization.PrincipalType { return d.PrincipalType },
+			func(d *core.RoleEligibilityScheduleDelete) {
 				builder.WriteString(fmt.Sprintf("    - %s: %s\n", d.PrincipalType, d.PrincipalName))
 				builder.WriteString(fmt.Sprintf("      Role:  %s\n", d.RoleName))
 				builder.WriteString(fmt.Sprintf("      Scope: %s\n", d.Scope))
@@ -663,12 +1422,72 @@ func printPlan(
 				if d.EndDateTime != nil {
 					builder.WriteString(fmt.Sprintf("      End:   %s\n", d.EndDateTime.Format(dateFormat)))
 				}
+				writeRequestInfo(builder, d.Justification, d.Ticket)
 				builder.WriteString("\n")
-			}
+			},
+		)
+	}
+
+	builder.WriteString(fmt.Sprintf("Plan: %d to add, %d to change, %d to delete.", plan.GetAddCount(), plan.GetChangeCount(), plan.GetDeleteCount()))
+
+	output.PrintlnInfo(builder.String())
+}
+
+// writeScheduleSections writes schedule changes to the plan under the given heading. Changes for service
+// principals are written under a heading of their own, after the changes for groups and users.
+func writeScheduleSections[T any](
+	builder *strings.Builder,
+	heading string,
+	changes []T,
+	getPrincipalType func(T) armauthorization.PrincipalType,
+	write func(T),
+) {
+	var principalChanges, servicePrincipalChanges []T
+	for _, c := range changes {
+		if getPrincipalType(c) == armauthorization.PrincipalTypeServicePrincipal {
+			servicePrincipalChanges = append(servicePrincipalChanges, c)
+		} else {
+			principalChanges = append(principalChanges, c)
+		}
+	}
+
+	sections := []struct {
+		heading string
+		changes []T
+	}{
+		{heading: heading, changes: principalChanges},
+		{heading: fmt.Sprintf("%s for service principals", heading), changes: servicePrincipalChanges},
+	}
+	for _, s := range sections {
+		if len(s.changes) == 0 {
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("  # %s:\n\n", s.heading))
+		for _, c := range s.changes {
+			write(c)
 		}
 	}
+}
+
+// writeRequestInfo writes the justification and ticket of a schedule request to the plan.
+func writeRequestInfo(builder *strings.Builder, justification string, ticket *core.Ticket) {
+	if justification != "" {
+		builder.WriteString(fmt.Sprintf("      Justification: %s\n", justification))
+	}
+
+	if ticket != nil {
+		builder.WriteString(fmt.Sprintf("      Ticket: %s\n", strings.TrimSpace(fmt.Sprintf("%s %s", ticket.System, ticket.Number))))
+	}
+}
+
+func printPlanTotals(plans []*core.Plan) {
+	builder := &strings.Builder{}
 
-	builder.WriteString(fmt.Sprintf("Plan: %d to add, %d to change, %d to delete.", len(roleAssignmentScheduleCreates)+len(roleEligibilityScheduleCreates), len(roleAssignmentScheduleUpdates)+len(roleEligibilityScheduleUpdates)+len(roleManagementPolicyUpdates), len(roleAssignmentScheduleDeletes)+len(roleEligibilityScheduleDeletes)))
+	builder.WriteString("Plan by scope:\n\n")
+	for _, p := range plans {
+		builder.WriteString(fmt.Sprintf("  %s: %d to add, %d to change, %d to delete.\n", p.Scope, p.GetAddCount(), p.GetChangeCount(), p.GetDeleteCount()))
+	}
 
 	output.PrintlnInfo(builder.String())
 }
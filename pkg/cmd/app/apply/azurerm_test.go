@@ -1 +1,775 @@
 package apply
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend/fake"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/gofrontier-com/sheriff/pkg/util/azurerm_config"
+	"github.com/gofrontier-com/sheriff/pkg/util/parallel"
+	"github.com/gofrontier-com/sheriff/pkg/util/saved_plan"
+)
+
+const (
+	principalId = "00000000-0000-0000-0000-00000000000a"
+	groupId     = "00000000-0000-0000-0000-00000000000b"
+	userId      = "00000000-0000-0000-0000-00000000000c"
+)
+
+// newBackend creates a fake backend with the principals and built-in roles used by the tests, where the
+// principal that runs Sheriff is an owner of the given scope.
+func newBackend(scope string) (*fake.Backend, map[string]*armauthorization.RoleDefinition) {
+	b := fake.NewBackend()
+	b.AddGroup(groupId, "Engineers")
+	b.AddUser(userId, "jane@example.com")
+
+	roleDefinitions := map[string]*armauthorization.RoleDefinition{}
+	for _, roleName := range []string{"Owner", "Contributor", "Reader"} {
+		roleDefinitions[roleName] = b.AddRoleDefinition("/", &armauthorization.RoleDefinition{
+			Properties: &armauthorization.RoleDefinitionProperties{
+				Permissions: []*armauthorization.Permission{
+					{Actions: []*string{to.Ptr("*")}},
+				},
+				RoleName: to.Ptr(roleName),
+				RoleType: to.Ptr("BuiltInRole"),
+			},
+		})
+	}
+
+	b.AddRoleAssignment(scope, principalId, *roleDefinitions["Owner"].ID)
+
+	return b, roleDefinitions
+}
+
+// loadConfig writes the given files to a config dir and loads it.
+func loadConfig(t *testing.T, files map[string]string) *core.AzureRmConfig {
+	t.Helper()
+
+	configDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(configDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := azurerm_config.Load(configDir, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+func TestApplyAzureRmCreatesSchedules(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000001"
+	b, roleDefinitions := newBackend(scope)
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml":       "subscription:\n  active:\n    - roleName: Reader\n",
+		"users/jane@example.com.yml": "subscription:\n  eligible:\n    - roleName: Contributor\n",
+	})
+
+	_, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roleAssignmentSchedules := b.RoleAssignmentSchedules()
+	if len(roleAssignmentSchedules) != 1 {
+		t.Fatalf("expected 1 role assignment schedule, got %d", len(roleAssignmentSchedules))
+	}
+	if s := roleAssignmentSchedules[0].Properties; *s.PrincipalID != groupId || *s.RoleDefinitionID != *roleDefinitions["Reader"].ID || *s.Scope != scope {
+		t.Errorf("unexpected role assignment schedule: %s %s %s", *s.PrincipalID, *s.RoleDefinitionID, *s.Scope)
+	}
+
+	roleEligibilitySchedules := b.RoleEligibilitySchedules()
+	if len(roleEligibilitySchedules) != 1 {
+		t.Fatalf("expected 1 role eligibility schedule, got %d", len(roleEligibilitySchedules))
+	}
+	if s := roleEligibilitySchedules[0].Properties; *s.PrincipalID != userId || *s.RoleDefinitionID != *roleDefinitions["Contributor"].ID || *s.Scope != scope {
+		t.Errorf("unexpected role eligibility schedule: %s %s %s", *s.PrincipalID, *s.RoleDefinitionID, *s.Scope)
+	}
+
+	policyUpdates := 0
+	for _, r := range b.Requests() {
+		if r.Operation == "UpdateRoleManagementPolicy" {
+			policyUpdates++
+		}
+	}
+	if policyUpdates != 2 {
+		t.Errorf("expected 2 role management policy updates, got %d", policyUpdates)
+	}
+}
+
+func TestApplyAzureRmDeletesUnmanagedSchedules(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000002"
+	b, roleDefinitions := newBackend(scope)
+
+	b.AddRoleAssignmentSchedule(&armauthorization.RoleAssignmentSchedule{
+		ID:   to.Ptr(scope + "/providers/Microsoft.Authorization/roleAssignmentSchedules/unmanaged"),
+		Name: to.Ptr("unmanaged"),
+		Properties: &armauthorization.RoleAssignmentScheduleProperties{
+			AssignmentType:   to.Ptr(armauthorization.AssignmentTypeAssigned),
+			MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+			PrincipalID:      to.Ptr(userId),
+			PrincipalType:    to.Ptr(armauthorization.PrincipalTypeUser),
+			RoleDefinitionID: roleDefinitions["Contributor"].ID,
+			Scope:            to.Ptr(scope),
+			StartDateTime:    to.Ptr(time.Now().UTC().Add(-time.Hour)),
+			Status:           to.Ptr(armauthorization.StatusProvisioned),
+		},
+	})
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	_, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roleAssignmentSchedules := b.RoleAssignmentSchedules()
+	if len(roleAssignmentSchedules) != 1 {
+		t.Fatalf("expected 1 role assignment schedule, got %d", len(roleAssignmentSchedules))
+	}
+	if s := roleAssignmentSchedules[0].Properties; *s.PrincipalID != groupId {
+		t.Errorf("expected unmanaged role assignment schedule to be deleted, found schedule for principal %s", *s.PrincipalID)
+	}
+}
+
+func TestApplyAzureRmPlanOnlyMakesNoChanges(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000003"
+	b, _ := newBackend(scope)
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	_, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests := b.Requests(); len(requests) != 0 {
+		t.Errorf("expected no requests, got %d", len(requests))
+	}
+}
+
+// interruptingBackend cancels a context when the first role assignment schedule request is made, as an
+// interrupt would while the request is in flight.
+type interruptingBackend struct {
+	*fake.Backend
+	cancel          context.CancelFunc
+	requestCanceled bool
+}
+
+func (b *interruptingBackend) CreateRoleAssignmentScheduleRequest(ctx context.Context, scope string, requestName string, request armauthorization.RoleAssignmentScheduleRequest) error {
+	b.cancel()
+	b.requestCanceled = ctx.Err() != nil
+
+	return b.Backend.CreateRoleAssignmentScheduleRequest(ctx, scope, requestName, request)
+}
+
+func TestApplyAzureRmStopsAfterInFlightRequestWhenInterrupted(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000004"
+	b, _ := newBackend(scope)
+	b.AddGroup("00000000-0000-0000-0000-00000000000d", "Operators")
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+		"groups/Operators.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ib := &interruptingBackend{Backend: b, cancel: cancel}
+
+	_, err := applyAzureRm(ctx, ib, ib, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, false, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if ib.requestCanceled {
+		t.Error("expected the in-flight request not to be cancelled")
+	}
+
+	if roleAssignmentSchedules := b.RoleAssignmentSchedules(); len(roleAssignmentSchedules) != 1 {
+		t.Errorf("expected 1 role assignment schedule, got %d", len(roleAssignmentSchedules))
+	}
+}
+
+func TestApplyAzureRmInParallel(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000005"
+	b, _ := newBackend(scope)
+
+	files := map[string]string{}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("Team %d", i)
+		b.AddGroup(fmt.Sprintf("00000000-0000-0000-0001-%012d", i), name)
+		files[fmt.Sprintf("groups/%s.yml", name)] = "subscription:\n  active:\n    - roleName: Reader\n  eligible:\n    - roleName: Contributor\n"
+	}
+	config := loadConfig(t, files)
+
+	ctx := parallel.WithLimit(context.Background(), 8)
+
+	_, err := applyAzureRm(ctx, b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if roleAssignmentSchedules := b.RoleAssignmentSchedules(); len(roleAssignmentSchedules) != 20 {
+		t.Errorf("expected 20 role assignment schedules, got %d", len(roleAssignmentSchedules))
+	}
+
+	if roleEligibilitySchedules := b.RoleEligibilitySchedules(); len(roleEligibilitySchedules) != 20 {
+		t.Errorf("expected 20 role eligibility schedules, got %d", len(roleEligibilitySchedules))
+	}
+
+	// Each type of change is applied before the next starts.
+	var operations []string
+	for _, r := range b.Requests() {
+		if len(operations) == 0 || operations[len(operations)-1] != r.Operation {
+			operations = append(operations, r.Operation)
+		}
+	}
+	expectedOperations := []string{"UpdateRoleManagementPolicy", "CreateRoleAssignmentScheduleRequest", "CreateRoleEligibilityScheduleRequest"}
+	if fmt.Sprint(operations) != fmt.Sprint(expectedOperations) {
+		t.Errorf("expected operations %v, got %v", expectedOperations, operations)
+	}
+}
+
+func TestApplyAzureRmReportsAllUnresolvedPrincipals(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000006"
+	b, roleDefinitions := newBackend(scope)
+	b.AddGroup("00000000-0000-0000-0000-00000000000e", "Duplicate")
+	b.AddGroup("00000000-0000-0000-0000-00000000000f", "Duplicate")
+
+	b.AddRoleAssignmentSchedule(&armauthorization.RoleAssignmentSchedule{
+		ID:   to.Ptr(scope + "/providers/Microsoft.Authorization/roleAssignmentSchedules/deleted"),
+		Name: to.Ptr("deleted"),
+		Properties: &armauthorization.RoleAssignmentScheduleProperties{
+			AssignmentType:   to.Ptr(armauthorization.AssignmentTypeAssigned),
+			MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+			PrincipalID:      to.Ptr("00000000-0000-0000-0000-0000000000ff"),
+			PrincipalType:    to.Ptr(armauthorization.PrincipalTypeUser),
+			RoleDefinitionID: roleDefinitions["Reader"].ID,
+			Scope:            to.Ptr(scope),
+			StartDateTime:    to.Ptr(time.Now().UTC().Add(-time.Hour)),
+			Status:           to.Ptr(armauthorization.StatusProvisioned),
+		},
+	})
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml":          "subscription:\n  active:\n    - roleName: Reader\n",
+		"groups/Duplicate.yml":          "subscription:\n  active:\n    - roleName: Reader\n",
+		"groups/Missing.yml":            "subscription:\n  active:\n    - roleName: Reader\n",
+		"users/missing@example.com.yml": "subscription:\n  eligible:\n    - roleName: Contributor\n",
+	})
+
+	_, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, true, nil)
+
+	principalErrors, ok := err.(core.PrincipalErrors)
+	if !ok {
+		t.Fatalf("expected principal errors, got %v", err)
+	}
+
+	expectedMessages := []string{
+		"group with display name \"Missing\" not found",
+		"multiple groups with display name \"Duplicate\" found",
+		"user with Id \"00000000-0000-0000-0000-0000000000ff\" not found",
+		"user with upn \"missing@example.com\" not found",
+	}
+	if len(principalErrors) != len(expectedMessages) {
+		t.Fatalf("expected %d principal errors, got %d: %v", len(expectedMessages), len(principalErrors), err)
+	}
+	for i, e := range principalErrors {
+		if e.Error() != expectedMessages[i] {
+			t.Errorf("expected principal error %d to be %q, got %q", i, expectedMessages[i], e.Error())
+		}
+	}
+}
+
+func TestApplyAzureRmMatchesExistingSchedulesByKey(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000007"
+	b, roleDefinitions := newBackend(scope)
+
+	// Azure returns role definition Ids of the subscription scope, and Ids can differ in case.
+	b.AddRoleAssignmentSchedule(&armauthorization.RoleAssignmentSchedule{
+		ID:   to.Ptr(scope + "/providers/Microsoft.Authorization/roleAssignmentSchedules/existing"),
+		Name: to.Ptr("existing"),
+		Properties: &armauthorization.RoleAssignmentScheduleProperties{
+			AssignmentType:   to.Ptr(armauthorization.AssignmentTypeAssigned),
+			MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+			PrincipalID:      to.Ptr(strings.ToUpper(groupId)),
+			PrincipalType:    to.Ptr(armauthorization.PrincipalTypeGroup),
+			RoleDefinitionID: to.Ptr(scope + "/providers/Microsoft.Authorization/roleDefinitions/" + *roleDefinitions["Reader"].Name),
+			Scope:            to.Ptr(scope),
+			StartDateTime:    to.Ptr(time.Now().UTC().Add(-time.Hour)),
+			Status:           to.Ptr(armauthorization.StatusProvisioned),
+		},
+	})
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	_, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range b.Requests() {
+		if r.Operation != "UpdateRoleManagementPolicy" {
+			t.Errorf("expected no schedule requests, got %s", r.Operation)
+		}
+	}
+}
+
+func TestApplyAzureRmResolvesServicePrincipalsToIds(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000012"
+	b, roleDefinitions := newBackend(scope)
+
+	// Display names are not unique, so service principals are matched with existing schedules by object Id.
+	servicePrincipalId := "00000000-0000-0000-0000-00000000000d"
+	servicePrincipalAppId := "00000000-0000-0000-0000-00000000000e"
+	otherServicePrincipalId := "00000000-0000-0000-0000-00000000000f"
+	b.AddServicePrincipal(servicePrincipalId, servicePrincipalAppId, "deploy")
+	b.AddServicePrincipal(otherServicePrincipalId, "00000000-0000-0000-0000-000000000010", "deploy")
+
+	b.AddRoleAssignmentSchedule(&armauthorization.RoleAssignmentSchedule{
+		ID:   to.Ptr(scope + "/providers/Microsoft.Authorization/roleAssignmentSchedules/other"),
+		Name: to.Ptr("other"),
+		Properties: &armauthorization.RoleAssignmentScheduleProperties{
+			AssignmentType:   to.Ptr(armauthorization.AssignmentTypeAssigned),
+			MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+			PrincipalID:      to.Ptr(otherServicePrincipalId),
+			PrincipalType:    to.Ptr(armauthorization.PrincipalTypeServicePrincipal),
+			RoleDefinitionID: roleDefinitions["Reader"].ID,
+			Scope:            to.Ptr(scope),
+			StartDateTime:    to.Ptr(time.Now().UTC().Add(-time.Hour)),
+			Status:           to.Ptr(armauthorization.StatusProvisioned),
+		},
+	})
+
+	config := loadConfig(t, map[string]string{
+		fmt.Sprintf("servicePrincipals/%s.yml", servicePrincipalAppId): "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	plan, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.ServicePrincipals[0].Name != servicePrincipalAppId {
+		t.Errorf("expected service principal name to be kept as %q, got %q", servicePrincipalAppId, config.ServicePrincipals[0].Name)
+	}
+
+	if len(plan.RoleAssignmentScheduleCreates) != 1 {
+		t.Fatalf("expected 1 role assignment schedule create, got %d", len(plan.RoleAssignmentScheduleCreates))
+	}
+	if c := plan.RoleAssignmentScheduleCreates[0]; c.PrincipalId != servicePrincipalId {
+		t.Errorf("expected role assignment schedule create for principal %s, got %s", servicePrincipalId, c.PrincipalId)
+	}
+
+	if len(plan.RoleAssignmentScheduleDeletes) != 1 {
+		t.Fatalf("expected 1 role assignment schedule delete, got %d", len(plan.RoleAssignmentScheduleDeletes))
+	}
+	if d := plan.RoleAssignmentScheduleDeletes[0]; d.PrincipalId != otherServicePrincipalId {
+		t.Errorf("expected role assignment schedule delete for principal %s, got %s", otherServicePrincipalId, d.PrincipalId)
+	}
+}
+
+func TestApplyAzureRmKeepsServicePrincipalSchedulesWhenNoneConfigured(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000013"
+	b, roleDefinitions := newBackend(scope)
+
+	managedIdentityId := "00000000-0000-0000-0000-000000000011"
+	b.AddServicePrincipal(managedIdentityId, "00000000-0000-0000-0000-000000000012", "id-app")
+
+	b.AddRoleAssignmentSchedule(&armauthorization.RoleAssignmentSchedule{
+		ID:   to.Ptr(scope + "/providers/Microsoft.Authorization/roleAssignmentSchedules/managed-identity"),
+		Name: to.Ptr("managed-identity"),
+		Properties: &armauthorization.RoleAssignmentScheduleProperties{
+			AssignmentType:   to.Ptr(armauthorization.AssignmentTypeAssigned),
+			MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+			PrincipalID:      to.Ptr(managedIdentityId),
+			PrincipalType:    to.Ptr(armauthorization.PrincipalTypeServicePrincipal),
+			RoleDefinitionID: roleDefinitions["Contributor"].ID,
+			Scope:            to.Ptr(scope),
+			StartDateTime:    to.Ptr(time.Now().UTC().Add(-time.Hour)),
+			Status:           to.Ptr(armauthorization.StatusProvisioned),
+		},
+	})
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	plan, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.RoleAssignmentScheduleDeletes) != 0 {
+		t.Errorf("expected no role assignment schedule deletes, got %d", len(plan.RoleAssignmentScheduleDeletes))
+	}
+}
+
+// configHash is the hash of the config that savePlanForTest saves plans with.
+const configHash = "sha256:config"
+
+// savePlanForTest plans the given config at the given scope and saves the plan to a file, returning the plan as
+// it is loaded from the file.
+func savePlanForTest(t *testing.T, b *fake.Backend, config *core.AzureRmConfig, scope string) *core.SavedPlan {
+	t.Helper()
+
+	stateHash, err := saved_plan.GetStateHash(context.Background(), b, config, []string{scope})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planFilePath := filepath.Join(t.TempDir(), "plan.json")
+	err = savePlan(planFilePath, plan, []string{scope}, configHash, stateHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedPlan, err := saved_plan.Load(planFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return savedPlan
+}
+
+func TestApplySavedPlanAppliesPlan(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000008"
+	b, _ := newBackend(scope)
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml":       "subscription:\n  active:\n    - roleName: Reader\n",
+		"users/jane@example.com.yml": "subscription:\n  eligible:\n    - roleName: Contributor\n",
+	})
+
+	savedPlan := savePlanForTest(t, b, config, scope)
+	if len(savedPlan.Scopes) != 1 || savedPlan.Scopes[0] != scope {
+		t.Errorf("expected scopes to be saved, got %v", savedPlan.Scopes)
+	}
+
+	if requests := b.Requests(); len(requests) != 0 {
+		t.Fatalf("expected no requests before the plan is applied, got %d", len(requests))
+	}
+
+	err := applySavedPlan(context.Background(), b, b, principalId, savedPlan, config, configHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if roleAssignmentSchedules := b.RoleAssignmentSchedules(); len(roleAssignmentSchedules) != 1 {
+		t.Errorf("expected 1 role assignment schedule, got %d", len(roleAssignmentSchedules))
+	}
+
+	if roleEligibilitySchedules := b.RoleEligibilitySchedules(); len(roleEligibilitySchedules) != 1 {
+		t.Errorf("expected 1 role eligibility schedule, got %d", len(roleEligibilitySchedules))
+	}
+
+	if requests := b.Requests(); len(requests) != savedPlan.Plan.GetActionCount() {
+		t.Errorf("expected %d requests, got %d", savedPlan.Plan.GetActionCount(), len(requests))
+	}
+}
+
+func TestApplySavedPlanRefusesWhenStateHasChanged(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000009"
+	b, roleDefinitions := newBackend(scope)
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	savedPlan := savePlanForTest(t, b, config, scope)
+
+	b.AddRoleAssignmentSchedule(&armauthorization.RoleAssignmentSchedule{
+		ID:   to.Ptr(scope + "/providers/Microsoft.Authorization/roleAssignmentSchedules/manual"),
+		Name: to.Ptr("manual"),
+		Properties: &armauthorization.RoleAssignmentScheduleProperties{
+			AssignmentType:   to.Ptr(armauthorization.AssignmentTypeAssigned),
+			MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+			PrincipalID:      to.Ptr(groupId),
+			PrincipalType:    to.Ptr(armauthorization.PrincipalTypeGroup),
+			RoleDefinitionID: roleDefinitions["Reader"].ID,
+			Scope:            to.Ptr(scope),
+			StartDateTime:    to.Ptr(time.Now().UTC().Add(-time.Hour)),
+			Status:           to.Ptr(armauthorization.StatusProvisioned),
+		},
+	})
+
+	err := applySavedPlan(context.Background(), b, b, principalId, savedPlan, config, configHash)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if requests := b.Requests(); len(requests) != 0 {
+		t.Errorf("expected no requests, got %d", len(requests))
+	}
+}
+
+func TestApplySavedPlanRefusesWhenUnchangedRoleManagementPolicyHasChanged(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000017"
+	b, _ := newBackend(scope)
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	// Plan at another scope to find the rules that the config wants for the Reader policy, and give the Reader
+	// policy those rules so that the saved plan does not update it.
+	otherScope := "/subscriptions/00000000-0000-0000-0000-000000000018"
+	otherBackend, _ := newBackend(otherScope)
+	otherPlan, err := applyAzureRm(context.Background(), otherBackend, otherBackend, principalId, config, []string{otherScope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(otherPlan.RoleManagementPolicyUpdates) != 1 {
+		t.Fatalf("expected 1 role management policy update, got %d", len(otherPlan.RoleManagementPolicyUpdates))
+	}
+
+	roleManagementPolicyAssignments, err := b.ListRoleManagementPolicyAssignments(context.Background(), scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var policyName string
+	for _, a := range roleManagementPolicyAssignments {
+		if *a.Properties.PolicyAssignmentProperties.RoleDefinition.DisplayName == "Reader" {
+			policyName = path.Base(*a.Properties.PolicyID)
+		}
+	}
+
+	err = b.UpdateRoleManagementPolicy(context.Background(), scope, policyName, *otherPlan.RoleManagementPolicyUpdates[0].RoleManagementPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedPlan := savePlanForTest(t, b, config, scope)
+	if len(savedPlan.Plan.RoleManagementPolicyUpdates) != 0 {
+		t.Fatalf("expected no role management policy updates, got %d", len(savedPlan.Plan.RoleManagementPolicyUpdates))
+	}
+
+	err = b.UpdateRoleManagementPolicy(context.Background(), scope, policyName, armauthorization.RoleManagementPolicy{
+		Properties: &armauthorization.RoleManagementPolicyProperties{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requestCount := len(b.Requests())
+
+	err = applySavedPlan(context.Background(), b, b, principalId, savedPlan, config, configHash)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if requests := b.Requests(); len(requests) != requestCount {
+		t.Errorf("expected no requests, got %d", len(requests)-requestCount)
+	}
+}
+
+func TestApplySavedPlanRefusesWhenConfigHasChanged(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000016"
+	b, _ := newBackend(scope)
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	savedPlan := savePlanForTest(t, b, config, scope)
+
+	err := applySavedPlan(context.Background(), b, b, principalId, savedPlan, config, "sha256:other")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if requests := b.Requests(); len(requests) != 0 {
+		t.Errorf("expected no requests, got %d", len(requests))
+	}
+}
+
+func TestApplySavedPlanRefusesWhenRoleDefinitionHasChanged(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000015"
+	b, _ := newBackend(scope)
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	savedPlan := savePlanForTest(t, b, config, scope)
+
+	b.AddRoleDefinition(scope, &armauthorization.RoleDefinition{
+		Properties: &armauthorization.RoleDefinitionProperties{
+			AssignableScopes: []*string{to.Ptr(scope)},
+			Permissions: []*armauthorization.Permission{
+				{Actions: []*string{to.Ptr("Microsoft.Resources/subscriptions/read")}},
+			},
+			RoleName: to.Ptr("Subscription Reader"),
+			RoleType: to.Ptr("CustomRole"),
+		},
+	})
+
+	err := applySavedPlan(context.Background(), b, b, principalId, savedPlan, config, configHash)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if requests := b.Requests(); len(requests) != 0 {
+		t.Errorf("expected no requests, got %d", len(requests))
+	}
+}
+
+func TestPlanDocumentListsEveryChange(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000010"
+	b, roleDefinitions := newBackend(scope)
+
+	b.AddRoleAssignmentSchedule(&armauthorization.RoleAssignmentSchedule{
+		ID:   to.Ptr(scope + "/providers/Microsoft.Authorization/roleAssignmentSchedules/pending"),
+		Name: to.Ptr("pending"),
+		Properties: &armauthorization.RoleAssignmentScheduleProperties{
+			AssignmentType:   to.Ptr(armauthorization.AssignmentTypeAssigned),
+			MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+			PrincipalID:      to.Ptr(userId),
+			PrincipalType:    to.Ptr(armauthorization.PrincipalTypeUser),
+			RoleDefinitionID: roleDefinitions["Contributor"].ID,
+			Scope:            to.Ptr(scope),
+			StartDateTime:    to.Ptr(time.Now().UTC().Add(time.Hour)),
+			Status:           to.Ptr(armauthorization.StatusPendingApproval),
+		},
+	})
+
+	config := loadConfig(t, map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	})
+
+	plan, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document := core.NewPlanDocument(plan)
+	if document.Version != 1 {
+		t.Errorf("expected version 1, got %d", document.Version)
+	}
+	if s := document.Summary; s.Add != plan.GetAddCount() || s.Change != plan.GetChangeCount() || s.Delete != plan.GetDeleteCount() {
+		t.Errorf("unexpected summary: %d to add, %d to change, %d to delete", s.Add, s.Change, s.Delete)
+	}
+	if len(document.Changes) != plan.GetActionCount() {
+		t.Fatalf("expected %d changes, got %d", plan.GetActionCount(), len(document.Changes))
+	}
+
+	var create, delete, policyUpdate *core.PlanDocumentChange
+	for _, c := range document.Changes {
+		switch {
+		case c.Action == "create" && c.ResourceType == "activeAssignment":
+			create = c
+		case c.Action == "delete" && c.ResourceType == "activeAssignment":
+			delete = c
+		case c.Action == "update" && c.ResourceType == "roleManagementPolicy":
+			policyUpdate = c
+		}
+	}
+
+	if create == nil || create.PrincipalId != groupId || create.PrincipalName != "Engineers" || create.PrincipalType != "Group" || create.RoleName != "Reader" || create.Scope != scope || create.StartDateTime == nil {
+		t.Errorf("unexpected create: %+v", create)
+	}
+
+	if delete == nil || delete.PrincipalId != userId || delete.PrincipalName != "jane@example.com" || delete.PrincipalType != "User" || delete.RoleName != "Contributor" {
+		t.Errorf("unexpected delete: %+v", delete)
+	}
+
+	if policyUpdate == nil || policyUpdate.RoleName != "Reader" || len(policyUpdate.ChangedRuleIds) == 0 {
+		t.Errorf("unexpected role management policy update: %+v", policyUpdate)
+	}
+}
+
+func TestPlanDocumentListsUserUpdates(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000014"
+	b, roleDefinitions := newBackend(scope)
+
+	// Both schedules have an end date that config does not, so both are updated.
+	b.AddRoleAssignmentSchedule(&armauthorization.RoleAssignmentSchedule{
+		ID:   to.Ptr(scope + "/providers/Microsoft.Authorization/roleAssignmentSchedules/active"),
+		Name: to.Ptr("active"),
+		Properties: &armauthorization.RoleAssignmentScheduleProperties{
+			AssignmentType:   to.Ptr(armauthorization.AssignmentTypeAssigned),
+			EndDateTime:      to.Ptr(time.Now().UTC().Add(24 * time.Hour)),
+			MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+			PrincipalID:      to.Ptr(userId),
+			PrincipalType:    to.Ptr(armauthorization.PrincipalTypeUser),
+			RoleDefinitionID: roleDefinitions["Reader"].ID,
+			Scope:            to.Ptr(scope),
+			StartDateTime:    to.Ptr(time.Now().UTC().Add(-time.Hour)),
+			Status:           to.Ptr(armauthorization.StatusProvisioned),
+		},
+	})
+	b.AddRoleEligibilitySchedule(&armauthorization.RoleEligibilitySchedule{
+		ID:   to.Ptr(scope + "/providers/Microsoft.Authorization/roleEligibilitySchedules/eligible"),
+		Name: to.Ptr("eligible"),
+		Properties: &armauthorization.RoleEligibilityScheduleProperties{
+			EndDateTime:      to.Ptr(time.Now().UTC().Add(24 * time.Hour)),
+			MemberType:       to.Ptr(armauthorization.MemberTypeDirect),
+			PrincipalID:      to.Ptr(userId),
+			PrincipalType:    to.Ptr(armauthorization.PrincipalTypeUser),
+			RoleDefinitionID: roleDefinitions["Contributor"].ID,
+			Scope:            to.Ptr(scope),
+			StartDateTime:    to.Ptr(time.Now().UTC().Add(-time.Hour)),
+			Status:           to.Ptr(armauthorization.StatusProvisioned),
+		},
+	})
+
+	config := loadConfig(t, map[string]string{
+		"users/jane@example.com.yml": "subscription:\n  active:\n    - roleName: Reader\n  eligible:\n    - roleName: Contributor\n",
+	})
+
+	plan, err := applyAzureRm(context.Background(), b, b, principalId, config, []string{scope}, &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var updates []*core.PlanDocumentChange
+	for _, c := range core.NewPlanDocument(plan).Changes {
+		if c.Action == "update" && c.ResourceType != "roleManagementPolicy" {
+			updates = append(updates, c)
+		}
+	}
+
+	if len(updates) != 2 {
+		t.Fatalf("expected 2 schedule updates, got %d", len(updates))
+	}
+	for _, u := range updates {
+		if u.PrincipalId != userId || u.PrincipalName != "jane@example.com" || u.PrincipalType != "User" {
+			t.Errorf("unexpected %s update: %+v", u.ResourceType, u)
+		}
+	}
+}
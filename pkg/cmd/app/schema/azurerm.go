@@ -0,0 +1,55 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrontier-com/go-utils/output"
+	"github.com/gofrontier-com/sheriff/pkg/cmd/app/apply"
+	"github.com/gofrontier-com/sheriff/pkg/util/config_schema"
+)
+
+func SchemaAzureRm(outputDir string) error {
+	output.PrintlnInfo("Generating schemas...")
+
+	rulesetSchema, err := config_schema.GetRulesetSchema(apply.DefaultRoleManagementPolicyPropertiesData)
+	if err != nil {
+		return err
+	}
+
+	schemas := []struct {
+		fileName string
+		schema   *config_schema.Schema
+	}{
+		{"principal.schema.json", config_schema.GetPrincipalSchema()},
+		{"policy.schema.json", config_schema.GetPolicySchema()},
+		{"profile.schema.json", config_schema.GetProfileSchema()},
+		{"roleDefinition.schema.json", config_schema.GetRoleDefinitionSchema()},
+		{"ruleset.schema.json", rulesetSchema},
+	}
+
+	err = os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range schemas {
+		data, err := json.MarshalIndent(s.schema, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		filePath := filepath.Join(outputDir, s.fileName)
+		err = os.WriteFile(filePath, append(data, '\n'), 0644)
+		if err != nil {
+			return err
+		}
+
+		output.PrintlnfInfo("- %s", filePath)
+	}
+
+	output.PrintlnInfo("\nSchemas generated!\n")
+
+	return nil
+}
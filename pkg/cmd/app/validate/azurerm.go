@@ -1,24 +1,60 @@
 package validate
 
 import (
+	"fmt"
+
 	"github.com/gofrontier-com/go-utils/output"
+	"github.com/gofrontier-com/sheriff/pkg/cmd/app/apply"
+	"github.com/gofrontier-com/sheriff/pkg/core"
 	"github.com/gofrontier-com/sheriff/pkg/util/azurerm_config"
+	"github.com/gofrontier-com/sheriff/pkg/util/config_schema"
 )
 
-func ValidateAzureRm(configDir string) error {
+func ValidateAzureRm(configDir string, varFilePaths []string, vars []string) error {
 	output.PrintlnInfo("Initialising...")
 
 	output.PrintlnInfo("- Loading and validating config\n")
 
-	config, err := azurerm_config.Load(configDir)
+	var configErrors core.ConfigErrors
+
+	variables, err := azurerm_config.LoadVariables(configDir, varFilePaths, vars)
 	if err != nil {
 		return err
 	}
 
+	config, err := azurerm_config.Load(configDir, variables)
+	if err != nil {
+		if e, ok := err.(core.ConfigErrors); ok {
+			configErrors = append(configErrors, e...)
+		} else {
+			return err
+		}
+	}
+
 	err = config.Validate()
+	if err != nil {
+		if e, ok := err.(core.ConfigErrors); ok {
+			configErrors = append(configErrors, e...)
+		} else {
+			return err
+		}
+	}
+
+	rulesetConfigErrors, err := config_schema.ValidateRulesets(config.Rulesets, apply.DefaultRoleManagementPolicyPropertiesData)
 	if err != nil {
 		return err
 	}
+	configErrors = append(configErrors, rulesetConfigErrors...)
+
+	if len(configErrors) > 0 {
+		core.SortConfigErrors(configErrors)
+
+		for _, e := range configErrors {
+			output.PrintlnError(e.Error())
+		}
+
+		return fmt.Errorf("configuration is invalid, %d problem(s) found", len(configErrors))
+	}
 
 	output.PrintlnInfo("Configuration is valid!\n")
 
@@ -1,19 +1,23 @@
 package apply
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armsubscriptions"
 	"github.com/gofrontier-com/go-utils/output"
 	"github.com/gofrontier-com/sheriff/pkg/cmd/app/apply"
+	"github.com/gofrontier-com/sheriff/pkg/cmd/cli/azurerm_flags"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/gofrontier-com/sheriff/pkg/util/output_format"
+	"github.com/gofrontier-com/sheriff/pkg/util/saved_plan"
 	"github.com/spf13/cobra"
 )
 
 var (
-	configDir      string
-	planOnly       bool
-	subscriptionId string
+	flags    *azurerm_flags.Flags
+	planOnly bool
 )
 
 // NewCmdApplyAzureRm creates a command to apply the Azure RM config
@@ -21,10 +25,31 @@ func NewCmdApplyAzureRm() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "azurerm",
 		Short: "Apply Azure Resource Manager config",
-		RunE: func(_ *cobra.Command, _ []string) error {
-			printHeader(configDir, subscriptionId)
+		Long: "Apply Azure Resource Manager config. If a plan file saved by \"plan azurerm --out\" is given, as in\n" +
+			"\"apply azurerm plan.json\", that plan is applied without planning again.",
+		Args: cobra.MaximumNArgs(1),
+		Annotations: map[string]string{
+			output_format.Annotation: "",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel, err := flags.NewContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			if len(args) == 1 {
+				return applyPlanFile(ctx, cmd, args[0])
+			}
 
-			if err := apply.ApplyAzureRm(configDir, subscriptionId, planOnly); err != nil {
+			scopes, subscriptions, err := flags.GetScopes(ctx)
+			if err != nil {
+				return err
+			}
+
+			printHeader(flags.ConfigDir, subscriptions)
+
+			if _, err := apply.ApplyAzureRm(ctx, flags.ConfigDir, flags.VarFilePaths, flags.Vars, scopes, flags.GetScheduleRequestDefaults(), planOnly, "", flags.OutputFormat); err != nil {
 				return err
 			}
 
@@ -32,21 +57,51 @@ func NewCmdApplyAzureRm() *cobra.Command {
 		},
 	}
 
-	wd, err := os.Getwd()
+	flags = azurerm_flags.Add(cmd, "Maximum number of concurrent API requests when resolving principals and role definitions and applying changes")
+	cmd.Flags().BoolVarP(&planOnly, "plan-only", "p", false, "Plan-only")
+
+	return cmd
+}
+
+// applyPlanFile applies a plan file saved by "plan azurerm --out". The plan determines the scopes and schedule
+// requests, so the flags that would otherwise determine them cannot be used. The config flags can be, as the
+// config is checked against the one that the plan was generated from.
+func applyPlanFile(ctx context.Context, cmd *cobra.Command, planFilePath string) error {
+	for _, name := range append([]string{"plan-only"}, azurerm_flags.PlanFlagNames...) {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s cannot be used with a plan file", name)
+		}
+	}
+
+	savedPlan, err := saved_plan.Load(planFilePath)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	cmd.Flags().StringVarP(&configDir, "config-dir", "c", wd, "Config directory")
-	cmd.Flags().BoolVarP(&planOnly, "plan-only", "p", false, "Plan-only")
-	cmd.Flags().StringVarP(&subscriptionId, "subscription-id", "s", "", "Subscription Id") // TODO: Support name
+	printPlanFileHeader(flags.ConfigDir, planFilePath, savedPlan)
 
-	cobra.MarkFlagRequired(cmd.Flags(), "subscription-id")
+	return apply.ApplyAzureRmPlan(ctx, flags.ConfigDir, flags.VarFilePaths, flags.Vars, savedPlan, flags.OutputFormat)
+}
 
-	return cmd
+func printPlanFileHeader(configDir string, planFilePath string, savedPlan *core.SavedPlan) {
+	builder := &strings.Builder{}
+	builder.WriteString(fmt.Sprintf("%s\n", strings.Repeat("~", 92)))
+	builder.WriteString(fmt.Sprintf("Action           | %s\n", "Apply (plan file)"))
+	builder.WriteString(fmt.Sprintf("Mode             | %s\n", "Azure RM"))
+	builder.WriteString(fmt.Sprintf("Config path      | %s\n", configDir))
+	builder.WriteString(fmt.Sprintf("Plan file        | %s\n", planFilePath))
+	for i, s := range savedPlan.Scopes {
+		label := "Scope"
+		if i > 0 {
+			label = ""
+		}
+		builder.WriteString(fmt.Sprintf("%-16s | %s\n", label, s))
+	}
+	builder.WriteString(fmt.Sprintf("%s\n", strings.Repeat("~", 92)))
+	output.PrintlnInfo(builder.String())
 }
 
-func printHeader(configDir string, scope string) {
+func printHeader(configDir string, subscriptions []*armsubscriptions.Subscription) {
 	var action string
 	if planOnly {
 		action = "Apply (plan-only)"
@@ -59,7 +114,17 @@ func printHeader(configDir string, scope string) {
 	builder.WriteString(fmt.Sprintf("Action           | %s\n", action))
 	builder.WriteString(fmt.Sprintf("Mode             | %s\n", "Azure RM"))
 	builder.WriteString(fmt.Sprintf("Config path      | %s\n", configDir))
-	builder.WriteString(fmt.Sprintf("Subscription Id  | %s\n", scope))
+	if flags.ManagementGroupId != "" {
+		builder.WriteString(fmt.Sprintf("Management group | %s\n", flags.ManagementGroupId))
+	} else {
+		for i, s := range subscriptions {
+			label := "Subscription"
+			if i > 0 {
+				label = ""
+			}
+			builder.WriteString(fmt.Sprintf("%-16s | %s (%s)\n", label, *s.DisplayName, *s.SubscriptionID))
+		}
+	}
 	builder.WriteString(fmt.Sprintf("%s\n", strings.Repeat("~", 92)))
 	output.PrintlnInfo(builder.String())
 }
@@ -0,0 +1,35 @@
+package azurerm_flags
+
+import (
+	"os"
+
+	"github.com/gofrontier-com/sheriff/pkg/util/output_format"
+	"github.com/spf13/cobra"
+)
+
+// Add adds the shared flags to a command, describing --parallelism with the given usage as what it bounds
+// depends on the command.
+func Add(cmd *cobra.Command, parallelismUsage string) *Flags {
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	flags := &Flags{}
+
+	cmd.Flags().StringVarP(&flags.ConfigDir, "config-dir", "c", wd, "Config directory")
+	cmd.Flags().StringArrayVar(&flags.Vars, "var", nil, "Variable in the form <name>=<value> (can be repeated)")
+	cmd.Flags().StringArrayVar(&flags.VarFilePaths, "var-file", nil, "Variables file (can be repeated)")
+	cmd.Flags().StringVarP(&flags.ManagementGroupId, "management-group-id", "m", "", "Management group Id")
+	cmd.Flags().StringArrayVarP(&flags.SubscriptionNames, "subscription-id", "s", nil, "Subscription name or Id (can be repeated)")
+	cmd.Flags().StringVar(&flags.Justification, "justification", "Managed by Sheriff", "Justification for schedule requests that do not set one")
+	cmd.Flags().StringVar(&flags.TicketNumber, "ticket-number", "", "Ticket number for schedule requests that do not set a ticket")
+	cmd.Flags().StringVar(&flags.TicketSystem, "ticket-system", "", "Ticket system for schedule requests that do not set a ticket")
+	cmd.Flags().StringVarP(&flags.OutputFormat, "output", "o", output_format.Text, "Output format: text, or json or yaml to write a document of the plan to stdout")
+	cmd.Flags().IntVar(&flags.Parallelism, "parallelism", 10, parallelismUsage)
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", 0, "Maximum time to run for, e.g. 30m (0 for no limit)")
+
+	cmd.MarkFlagsMutuallyExclusive("management-group-id", "subscription-id")
+
+	return flags
+}
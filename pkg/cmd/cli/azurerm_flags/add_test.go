@@ -0,0 +1,22 @@
+package azurerm_flags
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestAddParsesSubscriptionNamesWithCommas(t *testing.T) {
+	cmd := &cobra.Command{Use: "azurerm"}
+	flags := Add(cmd, "Maximum number of concurrent API requests")
+
+	err := cmd.ParseFlags([]string{"-s", "Contoso, Ltd (Production)", "--subscription-id", "Development"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"Contoso, Ltd (Production)", "Development"}; !slices.Equal(flags.SubscriptionNames, expected) {
+		t.Errorf("expected %v, got %v", expected, flags.SubscriptionNames)
+	}
+}
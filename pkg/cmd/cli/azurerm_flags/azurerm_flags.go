@@ -0,0 +1,29 @@
+package azurerm_flags
+
+import "time"
+
+// PlanFlagNames are the names of the flags that determine the scopes and schedule requests of a plan, which a
+// saved plan determines instead. The config flags are not included, as the config is loaded again to check that
+// it has not changed since the plan was saved.
+var PlanFlagNames = []string{
+	"management-group-id",
+	"subscription-id",
+	"justification",
+	"ticket-number",
+	"ticket-system",
+}
+
+// Flags are the flags shared by the plan and apply azurerm commands.
+type Flags struct {
+	ConfigDir         string
+	Justification     string
+	ManagementGroupId string
+	OutputFormat      string
+	Parallelism       int
+	SubscriptionNames []string
+	TicketNumber      string
+	TicketSystem      string
+	Timeout           time.Duration
+	VarFilePaths      []string
+	Vars              []string
+}
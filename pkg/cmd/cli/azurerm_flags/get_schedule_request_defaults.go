@@ -0,0 +1,18 @@
+package azurerm_flags
+
+import "github.com/gofrontier-com/sheriff/pkg/core"
+
+// GetScheduleRequestDefaults gets the justification and ticket for schedule requests that do not set their own.
+func (f *Flags) GetScheduleRequestDefaults() *core.ScheduleRequestDefaults {
+	defaults := &core.ScheduleRequestDefaults{
+		Justification: f.Justification,
+	}
+	if f.TicketNumber != "" || f.TicketSystem != "" {
+		defaults.Ticket = &core.Ticket{
+			Number: f.TicketNumber,
+			System: f.TicketSystem,
+		}
+	}
+
+	return defaults
+}
@@ -0,0 +1,45 @@
+package azurerm_flags
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+func TestGetScheduleRequestDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    *Flags
+		expected *core.ScheduleRequestDefaults
+	}{
+		{
+			name:     "no ticket",
+			flags:    &Flags{Justification: "Managed by Sheriff"},
+			expected: &core.ScheduleRequestDefaults{Justification: "Managed by Sheriff"},
+		},
+		{
+			name:  "ticket number only",
+			flags: &Flags{Justification: "Managed by Sheriff", TicketNumber: "CHG-1"},
+			expected: &core.ScheduleRequestDefaults{
+				Justification: "Managed by Sheriff",
+				Ticket:        &core.Ticket{Number: "CHG-1"},
+			},
+		},
+		{
+			name:  "ticket",
+			flags: &Flags{TicketNumber: "CHG-1", TicketSystem: "ServiceNow"},
+			expected: &core.ScheduleRequestDefaults{
+				Ticket: &core.Ticket{Number: "CHG-1", System: "ServiceNow"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if defaults := tt.flags.GetScheduleRequestDefaults(); !reflect.DeepEqual(defaults, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, defaults)
+			}
+		})
+	}
+}
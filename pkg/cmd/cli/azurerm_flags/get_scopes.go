@@ -0,0 +1,33 @@
+package azurerm_flags
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armsubscriptions"
+	"github.com/gofrontier-com/sheriff/pkg/cmd/app/apply"
+)
+
+// GetScopes gets the scopes to plan, which are either the management group or the subscriptions, along with
+// the subscriptions so that they can be listed in the header.
+func (f *Flags) GetScopes(ctx context.Context) ([]string, []*armsubscriptions.Subscription, error) {
+	if f.ManagementGroupId == "" && len(f.SubscriptionNames) == 0 {
+		return nil, nil, fmt.Errorf("one of --management-group-id or --subscription-id is required")
+	}
+
+	if f.ManagementGroupId != "" {
+		return []string{fmt.Sprintf("/providers/Microsoft.Management/managementGroups/%s", f.ManagementGroupId)}, nil, nil
+	}
+
+	subscriptions, err := apply.GetSubscriptions(ctx, f.SubscriptionNames)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var scopes []string
+	for _, s := range subscriptions {
+		scopes = append(scopes, fmt.Sprintf("/subscriptions/%s", *s.SubscriptionID))
+	}
+
+	return scopes, subscriptions, nil
+}
@@ -0,0 +1,30 @@
+package azurerm_flags
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestGetScopesForManagementGroup(t *testing.T) {
+	flags := &Flags{ManagementGroupId: "mg-platform"}
+
+	scopes, subscriptions, err := flags.GetScopes(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"/providers/Microsoft.Management/managementGroups/mg-platform"}; !slices.Equal(scopes, expected) {
+		t.Errorf("expected %v, got %v", expected, scopes)
+	}
+	if subscriptions != nil {
+		t.Errorf("expected no subscriptions, got %v", subscriptions)
+	}
+}
+
+func TestGetScopesRequiresScope(t *testing.T) {
+	_, _, err := (&Flags{}).GetScopes(context.Background())
+	if expected := "one of --management-group-id or --subscription-id is required"; err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,31 @@
+package azurerm_flags
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofrontier-com/sheriff/pkg/util/output_format"
+	"github.com/gofrontier-com/sheriff/pkg/util/parallel"
+)
+
+// NewContext validates the output format and parallelism, and returns a context that is limited to the
+// parallelism and, if a timeout is set, cancelled when it expires. The cancel function must be called once the
+// command has run.
+func (f *Flags) NewContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if err := output_format.Validate(f.OutputFormat); err != nil {
+		return nil, nil, err
+	}
+
+	if f.Parallelism < 1 {
+		return nil, nil, fmt.Errorf("--parallelism must be at least 1")
+	}
+
+	ctx = parallel.WithLimit(ctx, f.Parallelism)
+	if f.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, f.Timeout)
+		return ctx, cancel, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, cancel, nil
+}
@@ -0,0 +1,78 @@
+package azurerm_flags
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrontier-com/sheriff/pkg/util/parallel"
+)
+
+func TestNewContext(t *testing.T) {
+	tests := []struct {
+		name          string
+		flags         *Flags
+		expectedError string
+	}{
+		{
+			name:  "valid",
+			flags: &Flags{OutputFormat: "json", Parallelism: 4},
+		},
+		{
+			name:          "invalid output format",
+			flags:         &Flags{OutputFormat: "xml", Parallelism: 4},
+			expectedError: "--output must be one of text, json or yaml",
+		},
+		{
+			name:          "parallelism below 1",
+			flags:         &Flags{OutputFormat: "text", Parallelism: 0},
+			expectedError: "--parallelism must be at least 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel, err := tt.flags.NewContext(context.Background())
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Errorf("expected error %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer cancel()
+
+			if limit := parallel.GetLimit(ctx); limit != tt.flags.Parallelism {
+				t.Errorf("expected limit %d, got %d", tt.flags.Parallelism, limit)
+			}
+		})
+	}
+}
+
+func TestNewContextSetsTimeout(t *testing.T) {
+	flags := &Flags{OutputFormat: "text", Parallelism: 1, Timeout: time.Minute}
+
+	ctx, cancel, err := flags.NewContext(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); !ok {
+		t.Errorf("expected a deadline")
+	}
+}
+
+func TestNewContextWithoutTimeout(t *testing.T) {
+	ctx, cancel, err := (&Flags{OutputFormat: "text", Parallelism: 1}).NewContext(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("expected no deadline")
+	}
+}
@@ -2,17 +2,21 @@ package plan
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armsubscriptions"
 	"github.com/gofrontier-com/go-utils/output"
 	"github.com/gofrontier-com/sheriff/pkg/cmd/app/apply"
+	"github.com/gofrontier-com/sheriff/pkg/cmd/cli/azurerm_flags"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/gofrontier-com/sheriff/pkg/util/output_format"
 	"github.com/spf13/cobra"
 )
 
 var (
-	configDir      string
-	subscriptionId string
+	detailedExitCode bool
+	flags            *azurerm_flags.Flags
+	planFilePath     string
 )
 
 // NewCmdPlanAzureRm creates a command to llan the Azure RM config changes
@@ -20,37 +24,63 @@ func NewCmdPlanAzureRm() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "azurerm",
 		Short: "Plan Azure Resource Manager config changes",
-		RunE: func(_ *cobra.Command, _ []string) error {
-			printHeader(configDir, subscriptionId)
+		Annotations: map[string]string{
+			output_format.Annotation: "",
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel, err := flags.NewContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			scopes, subscriptions, err := flags.GetScopes(ctx)
+			if err != nil {
+				return err
+			}
+
+			printHeader(flags.ConfigDir, subscriptions)
 
-			if err := apply.ApplyAzureRm(configDir, subscriptionId, true); err != nil {
+			plan, err := apply.ApplyAzureRm(ctx, flags.ConfigDir, flags.VarFilePaths, flags.Vars, scopes, flags.GetScheduleRequestDefaults(), true, planFilePath, flags.OutputFormat)
+			if err != nil {
 				return err
 			}
 
+			// Changes are reported by exit code alone, so there is no error or usage to print.
+			if detailedExitCode && !plan.IsEmpty() {
+				cmd.SilenceErrors = true
+				cmd.SilenceUsage = true
+				return &core.ChangesPresentError{}
+			}
+
 			return nil
 		},
 	}
 
-	wd, err := os.Getwd()
-	if err != nil {
-		panic(err)
-	}
-
-	cmd.Flags().StringVarP(&configDir, "config-dir", "c", wd, "Config directory")
-	cmd.Flags().StringVarP(&subscriptionId, "subscription-id", "s", "", "Subscription Id") // TODO: Support name
-
-	cobra.MarkFlagRequired(cmd.Flags(), "subscription-id")
+	flags = azurerm_flags.Add(cmd, "Maximum number of concurrent API requests when resolving principals and role definitions")
+	cmd.Flags().BoolVar(&detailedExitCode, "detailed-exitcode", false, "Exit with 0 if there are no changes, 2 if there are changes and 1 on error")
+	cmd.Flags().StringVar(&planFilePath, "out", "", "Save the plan to a file, which can be applied with \"apply azurerm <file>\" (no short form, -o is --output)")
 
 	return cmd
 }
 
-func printHeader(configDir string, scope string) {
+func printHeader(configDir string, subscriptions []*armsubscriptions.Subscription) {
 	builder := &strings.Builder{}
 	builder.WriteString(fmt.Sprintf("%s\n", strings.Repeat("~", 92)))
 	builder.WriteString(fmt.Sprintf("Action           | %s\n", "Plan"))
 	builder.WriteString(fmt.Sprintf("Mode             | %s\n", "Azure RM"))
 	builder.WriteString(fmt.Sprintf("Config path      | %s\n", configDir))
-	builder.WriteString(fmt.Sprintf("Subscription Id  | %s\n", scope))
+	if flags.ManagementGroupId != "" {
+		builder.WriteString(fmt.Sprintf("Management group | %s\n", flags.ManagementGroupId))
+	} else {
+		for i, s := range subscriptions {
+			label := "Subscription"
+			if i > 0 {
+				label = ""
+			}
+			builder.WriteString(fmt.Sprintf("%-16s | %s (%s)\n", label, *s.DisplayName, *s.SubscriptionID))
+		}
+	}
 	builder.WriteString(fmt.Sprintf("%s\n", strings.Repeat("~", 92)))
 	output.PrintlnInfo(builder.String())
 }
@@ -0,0 +1,51 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofrontier-com/go-utils/output"
+	"github.com/gofrontier-com/sheriff/pkg/cmd/app/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outputDir string
+)
+
+// NewCmdSchemaAzureRm creates a command to generate JSON Schemas for the Azure RM config
+func NewCmdSchemaAzureRm() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "azurerm",
+		Short: "Generate JSON Schemas for Azure RM config",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			printHeader(outputDir)
+
+			if err := schema.SchemaAzureRm(outputDir); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", wd, "Output directory")
+
+	return cmd
+}
+
+func printHeader(outputDir string) {
+	builder := &strings.Builder{}
+	builder.WriteString(fmt.Sprintf("%s\n", strings.Repeat("~", 92)))
+	builder.WriteString(fmt.Sprintf("Action       | %s\n", "Schema"))
+	builder.WriteString(fmt.Sprintf("Mode         | %s\n", "Azure RM"))
+	builder.WriteString(fmt.Sprintf("Output path  | %s\n", outputDir))
+	builder.WriteString(fmt.Sprintf("%s\n", strings.Repeat("~", 92)))
+	output.PrintlnInfo(builder.String())
+}
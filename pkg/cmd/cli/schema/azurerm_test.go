@@ -0,0 +1,13 @@
+package schema
+
+import (
+	"testing"
+)
+
+func TestNewCmdSchemaAzureRm(t *testing.T) {
+	cmd := NewCmdSchemaAzureRm()
+
+	if cmd.Use != "azurerm" {
+		t.Errorf("Use is not correct")
+	}
+}
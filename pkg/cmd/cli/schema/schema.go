@@ -0,0 +1,17 @@
+package schema
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewCmdSchema creates a command to generate config schemas
+func NewCmdSchema() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Generate config schemas",
+	}
+
+	cmd.AddCommand(NewCmdSchemaAzureRm())
+
+	return cmd
+}
@@ -0,0 +1,13 @@
+package schema
+
+import (
+	"testing"
+)
+
+func TestNewCmdSchema(t *testing.T) {
+	cmd := NewCmdSchema()
+
+	if cmd.Use != "schema" {
+		t.Errorf("Use is not correct")
+	}
+}
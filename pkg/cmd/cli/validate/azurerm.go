@@ -11,7 +11,9 @@ import (
 )
 
 var (
-	configDir string
+	configDir    string
+	varFilePaths []string
+	vars         []string
 )
 
 // NewCmdValidate creates a command to validate the Azure Rm config
@@ -22,7 +24,7 @@ func NewCmdValidateAzureRm() *cobra.Command {
 		RunE: func(_ *cobra.Command, _ []string) error {
 			printHeader(configDir)
 
-			if err := validate.ValidateAzureRm(configDir); err != nil {
+			if err := validate.ValidateAzureRm(configDir, varFilePaths, vars); err != nil {
 				return err
 			}
 
@@ -36,6 +38,8 @@ func NewCmdValidateAzureRm() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&configDir, "config-dir", "c", wd, "Config directory")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Variable in the form <name>=<value> (can be repeated)")
+	cmd.Flags().StringArrayVar(&varFilePaths, "var-file", nil, "Variables file (can be repeated)")
 
 	return cmd
 }
@@ -1,18 +1,37 @@
 package sheriff
 
 import (
+	"sync"
+
+	"github.com/common-nighthawk/go-figure"
+	"github.com/gofrontier-com/go-utils/output"
 	"github.com/gofrontier-com/sheriff/pkg/cmd/cli/apply"
 	"github.com/gofrontier-com/sheriff/pkg/cmd/cli/plan"
+	"github.com/gofrontier-com/sheriff/pkg/cmd/cli/schema"
 	"github.com/gofrontier-com/sheriff/pkg/cmd/cli/validate"
 	vers "github.com/gofrontier-com/sheriff/pkg/cmd/cli/version"
+	"github.com/gofrontier-com/sheriff/pkg/util/output_format"
 	"github.com/spf13/cobra"
 )
 
 func NewRootCmd(version string, commit string, date string) *cobra.Command {
+	var printBannerOnce sync.Once
+
 	rootCmd := &cobra.Command{
 		Use:                   "sheriff",
 		DisableFlagsInUseLine: true,
 		Short:                 "Sheriff is a command line tool to manage Azure role-based access control (Azure RBAC) and Microsoft Entra Privileged Identity Management (Microsoft Entra PIM) using desired state configuration",
+		PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+			// The banner is printed once the flags have been parsed, so that it can be kept off stdout when a
+			// command writes a machine-readable document there.
+			if _, ok := cmd.Annotations[output_format.Annotation]; ok {
+				if f := cmd.Flags().Lookup("output"); f != nil && output_format.IsMachineReadable(f.Value.String()) {
+					output_format.RedirectHumanOutput()
+				}
+			}
+
+			printBannerOnce.Do(printBanner)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := cmd.Help(); err != nil {
 				return err
@@ -22,10 +41,24 @@ func NewRootCmd(version string, commit string, date string) *cobra.Command {
 		},
 	}
 
+	// Help requested with --help skips PersistentPreRun, so the banner is printed with the help instead.
+	helpFunc := rootCmd.HelpFunc()
+	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		printBannerOnce.Do(printBanner)
+		helpFunc(cmd, args)
+	})
+
 	rootCmd.AddCommand(apply.NewCmdApply())
 	rootCmd.AddCommand(plan.NewCmdPlan())
+	rootCmd.AddCommand(schema.NewCmdSchema())
 	rootCmd.AddCommand(validate.NewCmdValidate())
 	rootCmd.AddCommand(vers.NewCmdVersion(version, commit, date))
 
 	return rootCmd
 }
+
+func printBanner() {
+	myFigure := figure.NewFigure("Sheriff for Azure", "doom", true)
+	myFigure.Print()
+	output.PrintlnInfo()
+}
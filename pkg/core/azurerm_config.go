@@ -2,17 +2,33 @@ package core
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/ahmetb/go-linq/v3"
 	"github.com/go-playground/validator/v10"
 )
 
-func (c *AzureRmConfig) GetGroupAssignmentSchedules(subscriptionId string) []*Schedule {
-	return getAssignmentSchedules(c.Groups, subscriptionId)
+var (
+	namespaceRegex = regexp.MustCompile(`^AzureRmConfig\.(\w+)\[(\d+)\](.*)$`)
+)
+
+func (c *AzureRmConfig) GetGroupAssignmentSchedules(scope string) []*Schedule {
+	return getAssignmentSchedules(c.withProfiles(c.Groups), c.GetManagedScopes(scope), scope)
 }
 
-func (c *AzureRmConfig) GetGroupEligibilitySchedules(subscriptionId string) []*Schedule {
-	return getEligibilitySchedules(c.Groups, subscriptionId)
+func (c *AzureRmConfig) GetGroupEligibilitySchedules(scope string) []*Schedule {
+	return getEligibilitySchedules(c.withProfiles(c.Groups), c.GetManagedScopes(scope), scope)
+}
+
+// GetManagedScopes returns every scope that Sheriff manages beneath the given root scope. For a
+// subscription, this is the subscription and every resource group and resource referenced by at
+// least one principal. For a management group, this is the management group only.
+func (c *AzureRmConfig) GetManagedScopes(scope string) []string {
+	return getManagedScopes(c.withProfiles(c.getPrincipals()), scope)
 }
 
 func (c *AzureRmConfig) GetPolicyByRoleName(roleName string) *Policy {
@@ -33,15 +49,19 @@ func (c *AzureRmConfig) GetPolicyByRoleName(roleName string) *Policy {
 	}
 }
 
-func (c *AzureRmConfig) GetScopeRoleNameCombinations(subscriptionId string) []*ScopeRoleNameCombination {
-	groupAssignmentSchedules := c.GetGroupAssignmentSchedules(subscriptionId)
-	userAssignmentSchedules := c.GetUserAssignmentSchedules(subscriptionId)
-	groupEligibilitySchedules := c.GetGroupEligibilitySchedules(subscriptionId)
-	userEligibilitySchedules := c.GetUserEligibilitySchedules(subscriptionId)
+func (c *AzureRmConfig) GetScopeRoleNameCombinations(scope string) []*ScopeRoleNameCombination {
+	groupAssignmentSchedules := c.GetGroupAssignmentSchedules(scope)
+	userAssignmentSchedules := c.GetUserAssignmentSchedules(scope)
+	servicePrincipalAssignmentSchedules := c.GetServicePrincipalAssignmentSchedules(scope)
+	groupEligibilitySchedules := c.GetGroupEligibilitySchedules(scope)
+	userEligibilitySchedules := c.GetUserEligibilitySchedules(scope)
+	servicePrincipalEligibilitySchedules := c.GetServicePrincipalEligibilitySchedules(scope)
 
 	allSchedules := append(groupAssignmentSchedules, userAssignmentSchedules...)
+	allSchedules = append(allSchedules, servicePrincipalAssignmentSchedules...)
 	allSchedules = append(allSchedules, groupEligibilitySchedules...)
 	allSchedules = append(allSchedules, userEligibilitySchedules...)
+	allSchedules = append(allSchedules, servicePrincipalEligibilitySchedules...)
 
 	var scopeRoleNameCombinations []*ScopeRoleNameCombination
 	linq.From(allSchedules).SelectT(func(s *Schedule) *ScopeRoleNameCombination {
@@ -56,66 +76,314 @@ func (c *AzureRmConfig) GetScopeRoleNameCombinations(subscriptionId string) []*S
 	return scopeRoleNameCombinations
 }
 
-func (c *AzureRmConfig) GetUserAssignmentSchedules(subscriptionId string) []*Schedule {
-	return getAssignmentSchedules(c.Users, subscriptionId)
+func (c *AzureRmConfig) GetServicePrincipalAssignmentSchedules(scope string) []*Schedule {
+	return getAssignmentSchedules(c.withProfiles(c.ServicePrincipals), c.GetManagedScopes(scope), scope)
+}
+
+func (c *AzureRmConfig) GetServicePrincipalEligibilitySchedules(scope string) []*Schedule {
+	return getEligibilitySchedules(c.withProfiles(c.ServicePrincipals), c.GetManagedScopes(scope), scope)
+}
+
+func (c *AzureRmConfig) GetUserAssignmentSchedules(scope string) []*Schedule {
+	return getAssignmentSchedules(c.withProfiles(c.Users), c.GetManagedScopes(scope), scope)
+}
+
+func (c *AzureRmConfig) GetUserEligibilitySchedules(scope string) []*Schedule {
+	return getEligibilitySchedules(c.withProfiles(c.Users), c.GetManagedScopes(scope), scope)
 }
 
-func (c *AzureRmConfig) GetUserEligibilitySchedules(subscriptionId string) []*Schedule {
-	return getEligibilitySchedules(c.Users, subscriptionId)
+// ManagesServicePrincipals returns whether schedules for service principals, including managed identities, are
+// managed. They are only managed when at least one service principal is configured, so that existing schedules
+// for service principals are not deleted as unmanaged by config that does not reference them.
+func (c *AzureRmConfig) ManagesServicePrincipals() bool {
+	return len(c.ServicePrincipals) > 0
 }
 
 func (c *AzureRmConfig) Validate() error {
 	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(getFieldName)
 	validate.RegisterStructValidation(AzureRmConfigStructLevelValidation, AzureRmConfig{})
 	validate.RegisterStructValidation(ScopeConfigurationStructLevelValidation, ScopeConfiguration{})
+	validate.RegisterStructValidation(ScheduleStructLevelValidation, Schedule{})
+	validate.RegisterValidation("iso8601_duration", validateDuration)
 
 	err := validate.Struct(c)
 	if err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			return c.getConfigErrors(validationErrors)
+		}
+
 		return err
 	}
 
 	return nil
 }
 
+// getConfigErrors converts validation errors into config errors that reference the file,
+// line and column of the invalid value.
+func (c *AzureRmConfig) getConfigErrors(validationErrors validator.ValidationErrors) ConfigErrors {
+	var configErrors ConfigErrors
+
+	for _, e := range validationErrors {
+		configError := &ConfigError{
+			Message: getValidationErrorMessage(e),
+		}
+
+		groups := namespaceRegex.FindStringSubmatch(e.Namespace())
+		if groups != nil {
+			index, _ := strconv.Atoi(groups[2])
+
+			var source *Source
+			switch groups[1] {
+			case "Groups":
+				source = c.Groups[index].Source
+			case "Policies":
+				source = c.Policies[index].Source
+			case "Profiles":
+				source = c.Profiles[index].Source
+			case "RoleDefinitions":
+				source = c.RoleDefinitions[index].Source
+			case "Rulesets":
+				source = c.Rulesets[index].Source
+			case "ServicePrincipals":
+				source = c.ServicePrincipals[index].Source
+			case "Users":
+				source = c.Users[index].Source
+			}
+
+			if source != nil {
+				position := source.GetPosition(groups[3])
+				configError.FilePath = source.FilePath
+				configError.Line = position.Line
+				configError.Column = position.Column
+			}
+		}
+
+		configErrors = append(configErrors, configError)
+	}
+
+	SortConfigErrors(configErrors)
+
+	return configErrors
+}
+
+func (c *AzureRmConfig) getPrincipals() []*Principal {
+	var principals []*Principal
+	principals = append(principals, c.Groups...)
+	principals = append(principals, c.ServicePrincipals...)
+	principals = append(principals, c.Users...)
+
+	return principals
+}
+
+// withProfiles returns the given principals with the scope configurations of their profiles merged in.
+func (c *AzureRmConfig) withProfiles(principals []*Principal) []*Principal {
+	var merged []*Principal
+	for _, p := range principals {
+		merged = append(merged, p.WithProfiles(c.Profiles))
+	}
+
+	return merged
+}
+
 func AzureRmConfigStructLevelValidation(sl validator.StructLevel) {
 	azureRmConfig := sl.Current().Interface().(AzureRmConfig)
 
-	var rulesetReferences []*RulesetReference
-	for _, p := range azureRmConfig.Policies {
-		rulesetReferences = append(rulesetReferences, p.Default...)
-		rulesetReferences = append(rulesetReferences, p.Subscription...)
-		for _, r := range p.ResourceGroups {
-			rulesetReferences = append(rulesetReferences, r...)
+	for i, p := range azureRmConfig.Policies {
+		rulesetReferences := map[string][]*RulesetReference{
+			"default":      p.Default,
+			"subscription": p.Subscription,
+		}
+		for k, r := range p.ResourceGroups {
+			rulesetReferences[fmt.Sprintf("resourceGroups[%s]", k)] = r
 		}
-		for _, r := range p.Resources {
-			rulesetReferences = append(rulesetReferences, r...)
+		for k, r := range p.Resources {
+			rulesetReferences[fmt.Sprintf("resources[%s]", k)] = r
+		}
+		for k, r := range p.ManagementGroups {
+			rulesetReferences[fmt.Sprintf("managementGroups[%s]", k)] = r
+		}
+
+		for path, references := range rulesetReferences {
+			for j, r := range references {
+				any := linq.From(azureRmConfig.Rulesets).WhereT(func(s *RoleManagementPolicyRuleset) bool {
+					return s.Name == r.RulesetName
+				}).Any()
+				if !any {
+					sl.ReportError(r.RulesetName, fmt.Sprintf("Policies[%d].%s[%d].rulesetName", i, path, j), "", fmt.Sprintf("ruleset %s not found", r.RulesetName), "")
+				}
+			}
+
+			validateRulesetConflicts(sl, fmt.Sprintf("Policies[%d].%s", i, path), references, azureRmConfig.Rulesets)
+		}
+	}
+
+	for i, p := range azureRmConfig.Policies {
+		validatePatternKeys(sl, fmt.Sprintf("Policies[%d].resourceGroups", i), p.ResourceGroups)
+		validatePatternKeys(sl, fmt.Sprintf("Policies[%d].resources", i), p.Resources)
+	}
+
+	validatePrincipalProfiles(sl, "Groups", azureRmConfig.Groups, azureRmConfig.Profiles)
+	validatePrincipalProfiles(sl, "ServicePrincipals", azureRmConfig.ServicePrincipals, azureRmConfig.Profiles)
+	validatePrincipalProfiles(sl, "Users", azureRmConfig.Users, azureRmConfig.Profiles)
+}
+
+// validatePatternKeys reports pattern keys that can match the same name, as it would be ambiguous
+// which of them applies.
+func validatePatternKeys(sl validator.StructLevel, path string, rulesetReferences map[string][]*RulesetReference) {
+	var patterns []string
+	for k := range rulesetReferences {
+		if isPattern(k) {
+			patterns = append(patterns, k)
+		}
+	}
+	slices.Sort(patterns)
+
+	for j, b := range patterns {
+		for _, a := range patterns[:j] {
+			if patternsOverlap(a, b) {
+				sl.ReportError(b, fmt.Sprintf("%s[%s]", path, b), "", fmt.Sprintf("patterns %s and %s can match the same name", a, b), "")
+			}
 		}
 	}
+}
+
+// validateRulesetConflicts reports rulesets referenced at the same scope that patch the same field of
+// the same rule. Rulesets are applied in the order that they are referenced, so without this check
+// the result would depend on that order.
+func validateRulesetConflicts(sl validator.StructLevel, path string, references []*RulesetReference, rulesets []*RoleManagementPolicyRuleset) {
+	type patchedField struct {
+		field       string
+		ruleID      string
+		rulesetName string
+	}
+
+	var patchedFields []*patchedField
 
-	for _, r := range rulesetReferences {
-		any := linq.From(azureRmConfig.Rulesets).WhereT(func(s *RoleManagementPolicyRuleset) bool {
+	for j, r := range references {
+		idx := slices.IndexFunc(rulesets, func(s *RoleManagementPolicyRuleset) bool {
 			return s.Name == r.RulesetName
-		}).Any()
-		if !any {
-			sl.ReportError(r.RulesetName, "Rulesets", "", fmt.Sprintf("ruleset %s not found", r.RulesetName), "")
+		})
+		if idx == -1 {
+			continue
+		}
+
+		for _, rule := range rulesets[idx].Rules {
+			for _, field := range rule.GetPatchedFields() {
+				conflict := slices.IndexFunc(patchedFields, func(f *patchedField) bool {
+					return f.rulesetName != r.RulesetName && f.ruleID == rule.ID && patchedFieldsOverlap(f.field, field)
+				})
+				if conflict != -1 {
+					sl.ReportError(r.RulesetName, fmt.Sprintf("%s[%d].rulesetName", path, j), "", fmt.Sprintf("rulesets %s and %s both patch %s of rule %s", patchedFields[conflict].rulesetName, r.RulesetName, field, rule.ID), "")
+					continue
+				}
+
+				patchedFields = append(patchedFields, &patchedField{
+					field:       field,
+					ruleID:      rule.ID,
+					rulesetName: r.RulesetName,
+				})
+			}
 		}
 	}
+}
 
-	// TODO: Check for policy conflicts.
+// validatePrincipalProfiles reports profiles that do not exist and roles that are assigned at the
+// same scope by more than one of a principal's profiles, unless the principal overrides them.
+func validatePrincipalProfiles(sl validator.StructLevel, kind string, principals []*Principal, profiles []*Profile) {
+	for i, p := range principals {
+		ownScopeConfigurations := p.GetScopeConfigurationsByPath()
+		roleSources := map[string]string{}
+
+		for j, name := range p.Profiles {
+			idx := slices.IndexFunc(profiles, func(r *Profile) bool {
+				return r.Name == name
+			})
+			if idx == -1 {
+				sl.ReportError(name, fmt.Sprintf("%s[%d].profiles[%d]", kind, i, j), "", fmt.Sprintf("profile %s not found", name), "")
+				continue
+			}
+
+			profileScopeConfigurations := profiles[idx].GetScopeConfigurationsByPath()
+
+			paths := make([]string, 0, len(profileScopeConfigurations))
+			for path := range profileScopeConfigurations {
+				paths = append(paths, path)
+			}
+			slices.Sort(paths)
+
+			for _, path := range paths {
+				c := profileScopeConfigurations[path]
+				own := ownScopeConfigurations[path]
+
+				for _, assignmentType := range []string{"active", "eligible"} {
+					selector := func(c *ScopeConfiguration) []*Schedule {
+						if c == nil {
+							return nil
+						}
+						if assignmentType == "eligible" {
+							return c.Eligible
+						}
+						return c.Active
+					}
+					schedules := selector(c)
+					ownSchedules := selector(own)
+
+					for _, s := range schedules {
+						overridden := slices.ContainsFunc(ownSchedules, func(t *Schedule) bool {
+							return t.RoleName == s.RoleName
+						})
+						if overridden {
+							continue
+						}
+
+						key := fmt.Sprintf("%s:%s:%s", path, assignmentType, s.RoleName)
+						if existing, ok := roleSources[key]; ok && existing != name {
+							sl.ReportError(p.Profiles, fmt.Sprintf("%s[%d].profiles[%d]", kind, i, j), "", fmt.Sprintf("duplicate %s role name %s at %s from profiles %s and %s", assignmentType, s.RoleName, path, existing, name), "")
+							continue
+						}
+
+						roleSources[key] = name
+					}
+				}
+			}
+		}
+	}
 }
 
 func ScopeConfigurationStructLevelValidation(sl validator.StructLevel) {
 	scopeConfiguration := sl.Current().Interface().(ScopeConfiguration)
 
 	if countUniqueSchedules(scopeConfiguration.Active) != len(scopeConfiguration.Active) {
-		sl.ReportError(scopeConfiguration.Active, "Active", "", "duplicate active role name", "")
+		sl.ReportError(scopeConfiguration.Active, "active", "", "duplicate active role name", "")
 	}
 
 	if countUniqueSchedules(scopeConfiguration.Eligible) != len(scopeConfiguration.Eligible) {
-		sl.ReportError(scopeConfiguration.Eligible, "Eligible", "", "duplicate eligible role name", "")
+		sl.ReportError(scopeConfiguration.Eligible, "eligible", "", "duplicate eligible role name", "")
 	}
 }
 
+func ScheduleStructLevelValidation(sl validator.StructLevel) {
+	schedule := sl.Current().Interface().(Schedule)
+
+	if schedule.Duration != "" && schedule.EndDateTime != nil {
+		sl.ReportError(schedule.Duration, "duration", "", "duration and endDateTime cannot both be set", "")
+	}
+
+	if schedule.ConditionVersion != "" && schedule.Condition == "" {
+		sl.ReportError(schedule.ConditionVersion, "conditionVersion", "", "conditionVersion requires condition to be set", "")
+	}
+
+	if schedule.RenewalWindow != "" && schedule.Duration == "" {
+		sl.ReportError(schedule.RenewalWindow, "renewalWindow", "", "renewalWindow requires duration to be set", "")
+	}
+}
+
+func validateDuration(fl validator.FieldLevel) bool {
+	_, err := ParseDuration(fl.Field().String())
+	return err == nil
+}
+
 func countUniqueSchedules(schedules []*Schedule) int {
 	seen := make(map[string]bool)
 	unique := []string{}
@@ -129,83 +397,159 @@ func countUniqueSchedules(schedules []*Schedule) int {
 	return len(unique)
 }
 
-func getAssignmentSchedules(principals []*Principal, subscriptionId string) []*Schedule {
-	schedules := []*Schedule{}
+// getFieldName returns the name of a field as it appears in config files, so that
+// validation errors can be traced back to their source.
+func getFieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
 
-	for _, p := range principals {
-		if p.Subscription != nil {
-			for _, s := range p.Subscription.Active {
-				s.PrincipalName = p.Name
-				s.Scope = fmt.Sprintf("/subscriptions/%s", subscriptionId)
-				schedules = append(schedules, s)
-			}
-		}
+	return name
+}
+
+func getValidationErrorMessage(e validator.FieldError) string {
+	// Struct level validations report the error message as the tag.
+	if strings.Contains(e.Tag(), " ") {
+		return e.Tag()
+	}
+
+	if e.Tag() == "required" {
+		return fmt.Sprintf("%s is required", e.Field())
+	}
+
+	if e.Tag() == "iso8601_duration" {
+		return fmt.Sprintf("%s is not a valid ISO 8601 duration: %v", e.Field(), e.Value())
+	}
 
-		resourceGroupNames := make([]string, 0, len(p.ResourceGroups))
+	if e.Tag() == "oneof" {
+		return fmt.Sprintf("%s must be one of %s", e.Field(), strings.Join(strings.Fields(e.Param()), ", "))
+	}
+
+	if e.Tag() == "min" && e.Kind() == reflect.Slice {
+		return fmt.Sprintf("%s must contain at least %s item(s)", e.Field(), e.Param())
+	}
+
+	return fmt.Sprintf("%s failed on the '%s' validation", e.Field(), e.Tag())
+}
+
+func getAssignmentSchedules(principals []*Principal, managedScopes []string, scope string) []*Schedule {
+	return getSchedules(principals, managedScopes, scope, func(s *ScopeConfiguration) []*Schedule {
+		return s.Active
+	})
+}
+
+func getEligibilitySchedules(principals []*Principal, managedScopes []string, scope string) []*Schedule {
+	return getSchedules(principals, managedScopes, scope, func(s *ScopeConfiguration) []*Schedule {
+		return s.Eligible
+	})
+}
+
+func getManagedScopes(principals []*Principal, scope string) []string {
+	if managementGroupRegex.MatchString(scope) {
+		return []string{scope}
+	}
+
+	subscriptionId := strings.TrimPrefix(scope, "/subscriptions/")
+	scopes := []string{scope}
+
+	var resourceGroupNames []string
+	var resourceNames []string
+	for _, p := range principals {
 		for k := range p.ResourceGroups {
 			resourceGroupNames = append(resourceGroupNames, k)
 		}
+		for k := range p.Resources {
+			resourceNames = append(resourceNames, k)
+		}
+	}
 
-		for _, r := range resourceGroupNames {
-			for _, s := range p.ResourceGroups[r].Active {
-				s.PrincipalName = p.Name
-				s.Scope = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionId, r)
-				schedules = append(schedules, s)
-			}
+	slices.Sort(resourceGroupNames)
+	for _, r := range slices.Compact(resourceGroupNames) {
+		scopes = append(scopes, fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionId, r))
+	}
+
+	slices.Sort(resourceNames)
+	for _, r := range slices.Compact(resourceNames) {
+		scopes = append(scopes, fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionId, r))
+	}
+
+	return slices.Compact(scopes)
+}
+
+// getScopeConfigurations returns the scope configurations defined for the principal beneath the
+// given root scope, keyed by scope.
+func getScopeConfigurations(p *Principal, scope string) map[string]*ScopeConfiguration {
+	scopeConfigurations := map[string]*ScopeConfiguration{}
+
+	if groups := managementGroupRegex.FindStringSubmatch(scope); groups != nil {
+		if v := p.ManagementGroups[groups[1]]; v != nil {
+			scopeConfigurations[scope] = v
 		}
 
-		resourceNames := make([]string, 0, len(p.Resources))
-		for k := range p.Resources {
-			resourceNames = append(resourceNames, k)
+		return scopeConfigurations
+	}
+
+	subscriptionId := strings.TrimPrefix(scope, "/subscriptions/")
+
+	if p.Subscription != nil {
+		scopeConfigurations[scope] = p.Subscription
+	}
+
+	for k, v := range p.ResourceGroups {
+		if v != nil {
+			scopeConfigurations[fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionId, k)] = v
 		}
+	}
 
-		for _, r := range resourceNames {
-			for _, s := range p.Resources[r].Active {
-				s.PrincipalName = p.Name
-				s.Scope = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionId, r)
-				schedules = append(schedules, s)
-			}
+	for k, v := range p.Resources {
+		if v != nil {
+			scopeConfigurations[fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionId, k)] = v
 		}
 	}
 
-	return schedules
+	return scopeConfigurations
 }
 
-func getEligibilitySchedules(principals []*Principal, subscriptionId string) []*Schedule {
+func getSchedules(principals []*Principal, managedScopes []string, rootScope string, selector func(*ScopeConfiguration) []*Schedule) []*Schedule {
 	schedules := []*Schedule{}
 
 	for _, p := range principals {
-		if p.Subscription != nil {
-			for _, s := range p.Subscription.Eligible {
-				s.PrincipalName = p.Name
-				s.Scope = fmt.Sprintf("/subscriptions/%s", subscriptionId)
-				schedules = append(schedules, s)
-			}
-		}
+		scopeConfigurations := getScopeConfigurations(p, rootScope)
 
-		resourceGroupNames := make([]string, 0, len(p.ResourceGroups))
-		for k := range p.ResourceGroups {
-			resourceGroupNames = append(resourceGroupNames, k)
-		}
+		for _, scope := range managedScopes {
+			var scopeSchedules []*Schedule
+			if c, ok := scopeConfigurations[scope]; ok {
+				scopeSchedules = selector(c)
+			}
 
-		for _, r := range resourceGroupNames {
-			for _, s := range p.ResourceGroups[r].Eligible {
+			for _, c := range scopeSchedules {
+				s := *c
+				s.PrincipalId = p.Id
 				s.PrincipalName = p.Name
-				s.Scope = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionId, r)
-				schedules = append(schedules, s)
+				s.Scope = scope
+				schedules = append(schedules, &s)
 			}
-		}
 
-		resourceNames := make([]string, 0, len(p.Resources))
-		for k := range p.Resources {
-			resourceNames = append(resourceNames, k)
-		}
+			if p.Default == nil {
+				continue
+			}
 
-		for _, r := range resourceNames {
-			for _, s := range p.Resources[r].Eligible {
+			// Schedules defined under default apply at every managed scope, unless a schedule
+			// for the same role has been defined at the exact scope.
+			for _, d := range selector(p.Default) {
+				overridden := slices.ContainsFunc(scopeSchedules, func(s *Schedule) bool {
+					return s.RoleName == d.RoleName
+				})
+				if overridden {
+					continue
+				}
+
+				s := *d
+				s.PrincipalId = p.Id
 				s.PrincipalName = p.Name
-				s.Scope = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionId, r)
-				schedules = append(schedules, s)
+				s.Scope = scope
+				schedules = append(schedules, &s)
 			}
 		}
 	}
@@ -1 +1,315 @@
 package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// getValidationMessages validates the config and returns the message of each config error.
+func getValidationMessages(t *testing.T, config *AzureRmConfig) []string {
+	t.Helper()
+
+	err := config.Validate()
+	if err == nil {
+		return nil
+	}
+
+	configErrors, ok := err.(ConfigErrors)
+	if !ok {
+		t.Fatalf("expected config errors, got %v", err)
+	}
+
+	var messages []string
+	for _, e := range configErrors {
+		messages = append(messages, e.Message)
+	}
+
+	return messages
+}
+
+func TestAzureRmConfigValidatePatternKeys(t *testing.T) {
+	tests := []struct {
+		name           string
+		resourceGroups map[string][]*RulesetReference
+		resources      map[string][]*RulesetReference
+		expected       []string
+	}{
+		{
+			name: "exact key and pattern",
+			resourceGroups: map[string][]*RulesetReference{
+				"rg-app":  newRulesetReferences("ruleset"),
+				"rg-app*": newRulesetReferences("ruleset"),
+			},
+		},
+		{
+			name: "patterns that cannot match the same name",
+			resourceGroups: map[string][]*RulesetReference{
+				"rg-dev-*":  newRulesetReferences("ruleset"),
+				"rg-prod-*": newRulesetReferences("ruleset"),
+			},
+		},
+		{
+			name: "overlapping resource group patterns",
+			resourceGroups: map[string][]*RulesetReference{
+				"*-prod": newRulesetReferences("ruleset"),
+				"rg-*":   newRulesetReferences("ruleset"),
+			},
+			expected: []string{"patterns *-prod and rg-* can match the same name"},
+		},
+		{
+			name: "overlapping resource patterns",
+			resources: map[string][]*RulesetReference{
+				"rg-app/providers/*":                     newRulesetReferences("ruleset"),
+				"rg-app/providers/Microsoft.Web/sites/*": newRulesetReferences("ruleset"),
+			},
+			expected: []string{"patterns rg-app/providers/* and rg-app/providers/Microsoft.Web/sites/* can match the same name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &AzureRmConfig{
+				Policies: []*Policy{
+					{Name: "Reader", ResourceGroups: tt.resourceGroups, Resources: tt.resources},
+				},
+				Rulesets: []*RoleManagementPolicyRuleset{
+					{Name: "ruleset"},
+				},
+			}
+
+			messages := getValidationMessages(t, config)
+			if strings.Join(messages, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected %v, got %v", tt.expected, messages)
+			}
+		})
+	}
+}
+
+func newRuleset(name string, ruleId string, patch map[string]interface{}) *RoleManagementPolicyRuleset {
+	return &RoleManagementPolicyRuleset{
+		Name: name,
+		Rules: []*RoleManagementPolicyRule{
+			{ID: ruleId, Patch: patch},
+		},
+	}
+}
+
+func TestAzureRmConfigValidateRulesetConflicts(t *testing.T) {
+	rulesets := []*RoleManagementPolicyRuleset{
+		newRuleset("expiration-required", "Expiration_Admin_Eligibility", map[string]interface{}{"isExpirationRequired": true}),
+		newRuleset("expiration-optional", "Expiration_Admin_Eligibility", map[string]interface{}{"isExpirationRequired": false}),
+		newRuleset("expiration-duration", "Expiration_Admin_Eligibility", map[string]interface{}{"maximumDuration": "P90D"}),
+		newRuleset("approval", "Approval_EndUser_Assignment", map[string]interface{}{
+			"setting": map[string]interface{}{"isApprovalRequired": true},
+		}),
+		newRuleset("approval-stages", "Approval_EndUser_Assignment", map[string]interface{}{
+			"setting": map[string]interface{}{"approvalStages": []interface{}{}},
+		}),
+		newRuleset("approval-setting", "Approval_EndUser_Assignment", map[string]interface{}{
+			"setting": map[string]interface{}{"isApprovalRequired": false},
+		}),
+	}
+
+	tests := []struct {
+		name     string
+		policy   *Policy
+		expected []string
+	}{
+		{
+			name: "same rule and field at the same scope",
+			policy: &Policy{
+				Name:         "Reader",
+				Subscription: append(newRulesetReferences("expiration-required"), newRulesetReferences("expiration-optional")...),
+			},
+			expected: []string{"rulesets expiration-required and expiration-optional both patch isExpirationRequired of rule Expiration_Admin_Eligibility"},
+		},
+		{
+			name: "same rule and nested field at the same resource group",
+			policy: &Policy{
+				Name: "Reader",
+				ResourceGroups: map[string][]*RulesetReference{
+					"rg-app": append(newRulesetReferences("approval"), newRulesetReferences("approval-setting")...),
+				},
+			},
+			expected: []string{"rulesets approval and approval-setting both patch setting.isApprovalRequired of rule Approval_EndUser_Assignment"},
+		},
+		{
+			name: "same rule with different fields at the same scope",
+			policy: &Policy{
+				Name:         "Reader",
+				Subscription: append(newRulesetReferences("expiration-required"), newRulesetReferences("expiration-duration")...),
+			},
+		},
+		{
+			name: "same rule with different nested fields at the same scope",
+			policy: &Policy{
+				Name:    "Reader",
+				Default: append(newRulesetReferences("approval"), newRulesetReferences("approval-stages")...),
+			},
+		},
+		{
+			name: "default and exact scope patch the same field",
+			policy: &Policy{
+				Name:         "Reader",
+				Default:      newRulesetReferences("expiration-required"),
+				Subscription: newRulesetReferences("expiration-optional"),
+			},
+		},
+		{
+			name: "same ruleset referenced twice",
+			policy: &Policy{
+				Name:         "Reader",
+				Subscription: append(newRulesetReferences("expiration-required"), newRulesetReferences("expiration-required")...),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &AzureRmConfig{
+				Policies: []*Policy{tt.policy},
+				Rulesets: rulesets,
+			}
+
+			messages := getValidationMessages(t, config)
+			if strings.Join(messages, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected %v, got %v", tt.expected, messages)
+			}
+		})
+	}
+}
+
+// getScheduleDescriptions returns the principal name, scope and role name of each schedule, sorted.
+func getScheduleDescriptions(schedules []*Schedule) []string {
+	var descriptions []string
+	for _, s := range schedules {
+		descriptions = append(descriptions, fmt.Sprintf("%s %s %s", s.PrincipalName, s.Scope, s.RoleName))
+	}
+	slices.Sort(descriptions)
+
+	return descriptions
+}
+
+func TestAzureRmConfigGetSchedulesWithDefault(t *testing.T) {
+	subscription := "/subscriptions/00000000-0000-0000-0000-000000000001"
+	managementGroup := "/providers/Microsoft.Management/managementGroups/mg-platform"
+	resource := "rg-app/providers/Microsoft.Storage/storageAccounts/stapp"
+
+	tests := []struct {
+		name     string
+		config   *AzureRmConfig
+		scope    string
+		get      func(c *AzureRmConfig, scope string) []*Schedule
+		expected []string
+	}{
+		{
+			name: "default expands to every managed scope",
+			config: &AzureRmConfig{
+				Users: []*Principal{
+					{
+						Name:           "alice@example.com",
+						Default:        &ScopeConfiguration{Active: []*Schedule{{RoleName: "Reader"}}},
+						ResourceGroups: map[string]*ScopeConfiguration{"rg-app": {Active: []*Schedule{{RoleName: "Contributor"}}}},
+						Resources:      map[string]*ScopeConfiguration{resource: {Active: []*Schedule{{RoleName: "Storage Blob Data Reader"}}}},
+					},
+					{
+						Name:           "bob@example.com",
+						ResourceGroups: map[string]*ScopeConfiguration{"rg-data": {Active: []*Schedule{{RoleName: "Reader"}}}},
+					},
+				},
+			},
+			scope: subscription,
+			get:   (*AzureRmConfig).GetUserAssignmentSchedules,
+			expected: []string{
+				"alice@example.com " + subscription + " Reader",
+				"alice@example.com " + subscription + "/resourceGroups/rg-app Contributor",
+				"alice@example.com " + subscription + "/resourceGroups/rg-app Reader",
+				"alice@example.com " + subscription + "/resourceGroups/" + resource + " Reader",
+				"alice@example.com " + subscription + "/resourceGroups/" + resource + " Storage Blob Data Reader",
+				"alice@example.com " + subscription + "/resourceGroups/rg-data Reader",
+				"bob@example.com " + subscription + "/resourceGroups/rg-data Reader",
+			},
+		},
+		{
+			name: "exact scope overrides default for the same role",
+			config: &AzureRmConfig{
+				Groups: []*Principal{
+					{
+						Name:           "Engineers",
+						Default:        &ScopeConfiguration{Eligible: []*Schedule{{RoleName: "Contributor"}, {RoleName: "Reader"}}},
+						ResourceGroups: map[string]*ScopeConfiguration{"rg-app": {Eligible: []*Schedule{{RoleName: "Contributor", Duration: "P30D"}}}},
+					},
+				},
+			},
+			scope: subscription,
+			get:   (*AzureRmConfig).GetGroupEligibilitySchedules,
+			expected: []string{
+				"Engineers " + subscription + " Contributor",
+				"Engineers " + subscription + " Reader",
+				"Engineers " + subscription + "/resourceGroups/rg-app Contributor",
+				"Engineers " + subscription + "/resourceGroups/rg-app Reader",
+			},
+		},
+		{
+			name: "principal with only default",
+			config: &AzureRmConfig{
+				Groups: []*Principal{
+					{
+						Name:    "Auditors",
+						Default: &ScopeConfiguration{Eligible: []*Schedule{{RoleName: "Reader"}}},
+					},
+				},
+			},
+			scope:    subscription,
+			get:      (*AzureRmConfig).GetGroupEligibilitySchedules,
+			expected: []string{"Auditors " + subscription + " Reader"},
+		},
+		{
+			name: "default applies at management group scope",
+			config: &AzureRmConfig{
+				Users: []*Principal{
+					{
+						Name:             "alice@example.com",
+						Default:          &ScopeConfiguration{Active: []*Schedule{{RoleName: "Reader"}}},
+						ManagementGroups: map[string]*ScopeConfiguration{"mg-platform": {Active: []*Schedule{{RoleName: "Contributor"}}}},
+						ResourceGroups:   map[string]*ScopeConfiguration{"rg-app": {Active: []*Schedule{{RoleName: "Owner"}}}},
+					},
+				},
+			},
+			scope: managementGroup,
+			get:   (*AzureRmConfig).GetUserAssignmentSchedules,
+			expected: []string{
+				"alice@example.com " + managementGroup + " Contributor",
+				"alice@example.com " + managementGroup + " Reader",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			descriptions := getScheduleDescriptions(tt.get(tt.config, tt.scope))
+			if !slices.Equal(descriptions, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, descriptions)
+			}
+		})
+	}
+}
+
+func TestAzureRmConfigGetSchedulesKeepsExactScopeScheduleOverDefault(t *testing.T) {
+	config := &AzureRmConfig{
+		Groups: []*Principal{
+			{
+				Name:         "Engineers",
+				Default:      &ScopeConfiguration{Eligible: []*Schedule{{RoleName: "Contributor", Duration: "P1D"}}},
+				Subscription: &ScopeConfiguration{Eligible: []*Schedule{{RoleName: "Contributor", Duration: "P30D"}}},
+			},
+		},
+	}
+
+	schedules := config.GetGroupEligibilitySchedules("/subscriptions/00000000-0000-0000-0000-000000000001")
+	if len(schedules) != 1 || schedules[0].Duration != "P30D" {
+		t.Errorf("expected only the exact scope schedule, got %+v", schedules)
+	}
+}
@@ -0,0 +1,5 @@
+package core
+
+func (m *ChangesPresentError) Error() string {
+	return "the plan has changes"
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+func (e *ConfigError) Error() string {
+	if e.FilePath == "" {
+		return e.Message
+	}
+
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.FilePath, e.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", e.FilePath, e.Line, e.Column, e.Message)
+}
+
+func (e ConfigErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, c := range e {
+		messages[i] = c.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// SortConfigErrors sorts config errors by file, line and column.
+func SortConfigErrors(configErrors ConfigErrors) {
+	slices.SortStableFunc(configErrors, func(a, b *ConfigError) int {
+		if c := cmp.Compare(a.FilePath, b.FilePath); c != 0 {
+			return c
+		}
+
+		if c := cmp.Compare(a.Line, b.Line); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Column, b.Column)
+	})
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	durationRegex = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+)
+
+// ParseDuration parses an ISO 8601 duration, e.g. "P180D" or "PT8H".
+func ParseDuration(value string) (*Duration, error) {
+	groups := durationRegex.FindStringSubmatch(value)
+	if groups == nil || value == "P" || value[len(value)-1] == 'T' {
+		return nil, fmt.Errorf("invalid ISO 8601 duration: %s", value)
+	}
+
+	atoi := func(s string) int {
+		i, _ := strconv.Atoi(s)
+		return i
+	}
+
+	seconds, _ := strconv.ParseFloat(groups[7], 64)
+
+	return &Duration{
+		Days:   atoi(groups[3])*7 + atoi(groups[4]),
+		Months: atoi(groups[2]),
+		Time: time.Duration(atoi(groups[5]))*time.Hour +
+			time.Duration(atoi(groups[6]))*time.Minute +
+			time.Duration(seconds*float64(time.Second)),
+		Years: atoi(groups[1]),
+	}, nil
+}
+
+// AddTo returns the time that is the duration after t.
+func (d *Duration) AddTo(t time.Time) time.Time {
+	return t.AddDate(d.Years, d.Months, d.Days).Add(d.Time)
+}
+
+// SubtractFrom returns the time that is the duration before t.
+func (d *Duration) SubtractFrom(t time.Time) time.Time {
+	return t.AddDate(-d.Years, -d.Months, -d.Days).Add(-d.Time)
+}
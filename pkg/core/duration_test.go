@@ -0,0 +1,79 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected *Duration
+	}{
+		{value: "P180D", expected: &Duration{Days: 180}},
+		{value: "PT8H", expected: &Duration{Time: 8 * time.Hour}},
+		{value: "P1Y2M", expected: &Duration{Months: 2, Years: 1}},
+		{value: "P2W", expected: &Duration{Days: 14}},
+		{value: "P1DT1H30M", expected: &Duration{Days: 1, Time: 90 * time.Minute}},
+		{value: "PT0.5S", expected: &Duration{Time: 500 * time.Millisecond}},
+		{value: ""},
+		{value: "P"},
+		{value: "PT"},
+		{value: "P1DT"},
+		{value: "180D"},
+		{value: "P8H"},
+		{value: "PT1D"},
+		{value: "-P1D"},
+		{value: "P1.5D"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			duration, err := ParseDuration(tt.value)
+			if tt.expected == nil {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", duration)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *duration != *tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, duration)
+			}
+		})
+	}
+}
+
+func TestDurationAddToAndSubtractFrom(t *testing.T) {
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{value: "P180D", expected: time.Date(2024, 7, 13, 9, 0, 0, 0, time.UTC)},
+		{value: "PT8H", expected: time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)},
+		{value: "P1Y2M", expected: time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			duration, err := ParseDuration(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if end := duration.AddTo(start); !end.Equal(tt.expected) {
+				t.Errorf("expected %s, got %s", tt.expected, end)
+			}
+
+			if s := duration.SubtractFrom(tt.expected); !s.Equal(start) {
+				t.Errorf("expected %s, got %s", start, s)
+			}
+		})
+	}
+}
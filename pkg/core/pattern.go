@@ -0,0 +1,67 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+)
+
+// isPattern returns true if the key contains a wildcard, i.e. "*", which matches any sequence of
+// characters, or "?", which matches any single character.
+func isPattern(key string) bool {
+	return strings.ContainsAny(key, "*?")
+}
+
+// matchPattern returns true if the name matches the pattern in its entirety.
+func matchPattern(pattern string, name string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString("(?s:.*)")
+		case '?':
+			expr.WriteString("(?s:.)")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String()).MatchString(name)
+}
+
+// patternsOverlap returns true if there is at least one name that matches both patterns.
+func patternsOverlap(a string, b string) bool {
+	p, q := []rune(a), []rune(b)
+	seen := map[[2]int]bool{}
+
+	var overlap func(i, j int) bool
+	overlap = func(i, j int) bool {
+		key := [2]int{i, j}
+		if v, ok := seen[key]; ok {
+			return v
+		}
+		seen[key] = false
+
+		var result bool
+		switch {
+		case i == len(p) && j == len(q):
+			result = true
+		case i < len(p) && p[i] == '*':
+			// The wildcard either matches nothing, or the next character matched by the other pattern.
+			result = overlap(i+1, j) || (j < len(q) && overlap(i, j+1))
+		case j < len(q) && q[j] == '*':
+			result = overlap(i, j+1) || (i < len(p) && overlap(i+1, j))
+		case i == len(p) || j == len(q):
+			result = false
+		default:
+			result = (p[i] == '?' || q[j] == '?' || p[i] == q[j]) && overlap(i+1, j+1)
+		}
+
+		seen[key] = result
+
+		return result
+	}
+
+	return overlap(0, 0)
+}
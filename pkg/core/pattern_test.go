@@ -0,0 +1,57 @@
+package core
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{pattern: "rg-*", name: "rg-app", expected: true},
+		{pattern: "rg-*", name: "rg-", expected: true},
+		{pattern: "rg-*", name: "app-rg", expected: false},
+		{pattern: "rg-?", name: "rg-a", expected: true},
+		{pattern: "rg-?", name: "rg-ab", expected: false},
+		{pattern: "*-prod", name: "rg-app-prod", expected: true},
+		{pattern: "rg.app", name: "rg-app", expected: false},
+		{pattern: "rg-app/providers/Microsoft.Web/sites/*", name: "rg-app/providers/Microsoft.Web/sites/app-1", expected: true},
+		{pattern: "rg-app/providers/Microsoft.Web/sites/*", name: "rg-app/providers/Microsoft.Sql/servers/sql-1", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if actual := matchPattern(tt.pattern, tt.name); actual != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPatternsOverlap(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{a: "rg-*", b: "rg-app-*", expected: true},
+		{a: "rg-*", b: "*-prod", expected: true},
+		{a: "rg-?", b: "rg-a*", expected: true},
+		{a: "rg-dev-*", b: "rg-prod-*", expected: false},
+		{a: "rg-?", b: "rg-??", expected: false},
+		{a: "*-dev", b: "*-prod", expected: false},
+		{a: "rg-app/providers/*", b: "*/providers/Microsoft.Web/sites/*", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if actual := patternsOverlap(tt.a, tt.b); actual != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, actual)
+			}
+
+			if actual := patternsOverlap(tt.b, tt.a); actual != tt.expected {
+				t.Errorf("expected %t with the patterns swapped, got %t", tt.expected, actual)
+			}
+		})
+	}
+}
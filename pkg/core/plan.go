@@ -0,0 +1,40 @@
+package core
+
+// Append appends the changes in other to the plan.
+func (p *Plan) Append(other *Plan) {
+	p.RoleDefinitionCreates = append(p.RoleDefinitionCreates, other.RoleDefinitionCreates...)
+	p.RoleDefinitionUpdates = append(p.RoleDefinitionUpdates, other.RoleDefinitionUpdates...)
+	p.RoleAssignmentScheduleCreates = append(p.RoleAssignmentScheduleCreates, other.RoleAssignmentScheduleCreates...)
+	p.RoleAssignmentScheduleDeletes = append(p.RoleAssignmentScheduleDeletes, other.RoleAssignmentScheduleDeletes...)
+	p.RoleAssignmentScheduleUpdates = append(p.RoleAssignmentScheduleUpdates, other.RoleAssignmentScheduleUpdates...)
+	p.RoleEligibilityScheduleCreates = append(p.RoleEligibilityScheduleCreates, other.RoleEligibilityScheduleCreates...)
+	p.RoleEligibilityScheduleDeletes = append(p.RoleEligibilityScheduleDeletes, other.RoleEligibilityScheduleDeletes...)
+	p.RoleEligibilityScheduleUpdates = append(p.RoleEligibilityScheduleUpdates, other.RoleEligibilityScheduleUpdates...)
+	p.RoleManagementPolicyUpdates = append(p.RoleManagementPolicyUpdates, other.RoleManagementPolicyUpdates...)
+}
+
+// GetAddCount returns the number of role definitions and schedules the plan creates.
+func (p *Plan) GetAddCount() int {
+	return len(p.RoleDefinitionCreates) + len(p.RoleAssignmentScheduleCreates) + len(p.RoleEligibilityScheduleCreates)
+}
+
+// GetChangeCount returns the number of role definitions, schedules and role management policies
+// the plan updates.
+func (p *Plan) GetChangeCount() int {
+	return len(p.RoleDefinitionUpdates) + len(p.RoleAssignmentScheduleUpdates) + len(p.RoleEligibilityScheduleUpdates) + len(p.RoleManagementPolicyUpdates)
+}
+
+// GetDeleteCount returns the number of schedules the plan deletes.
+func (p *Plan) GetDeleteCount() int {
+	return len(p.RoleAssignmentScheduleDeletes) + len(p.RoleEligibilityScheduleDeletes)
+}
+
+// GetActionCount returns the total number of additions, changes and deletions the plan makes.
+func (p *Plan) GetActionCount() int {
+	return p.GetAddCount() + p.GetChangeCount() + p.GetDeleteCount()
+}
+
+// IsEmpty returns true if the plan makes no changes.
+func (p *Plan) IsEmpty() bool {
+	return p.GetActionCount() == 0
+}
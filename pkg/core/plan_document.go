@@ -0,0 +1,134 @@
+package core
+
+// planDocumentVersion is the version of the plan document format. It is incremented whenever a change to the
+// format could break a consumer of it, such as a field being removed or its meaning changing.
+const planDocumentVersion = 1
+
+// NewPlanDocument creates the machine-readable document of a plan. Changes are listed in the order in which
+// they are applied: role definitions, then role management policies, then active assignments and then eligible
+// assignments, with the creates, updates and deletes of each in turn.
+func NewPlanDocument(plan *Plan) *PlanDocument {
+	document := &PlanDocument{
+		Changes: []*PlanDocumentChange{},
+		Summary: &PlanDocumentSummary{
+			Add:    plan.GetAddCount(),
+			Change: plan.GetChangeCount(),
+			Delete: plan.GetDeleteCount(),
+		},
+		Version: planDocumentVersion,
+	}
+
+	for _, c := range plan.RoleDefinitionCreates {
+		document.Changes = append(document.Changes, &PlanDocumentChange{
+			Action:       "create",
+			ResourceType: "roleDefinition",
+			RoleName:     c.RoleName,
+			Scope:        c.Scope,
+		})
+	}
+
+	for _, u := range plan.RoleDefinitionUpdates {
+		document.Changes = append(document.Changes, &PlanDocumentChange{
+			Action:       "update",
+			ResourceType: "roleDefinition",
+			RoleName:     u.RoleName,
+			Scope:        u.Scope,
+		})
+	}
+
+	for _, u := range plan.RoleManagementPolicyUpdates {
+		document.Changes = append(document.Changes, &PlanDocumentChange{
+			Action:         "update",
+			ChangedRuleIds: u.ChangedRuleIds,
+			ResourceType:   "roleManagementPolicy",
+			RoleName:       u.RoleName,
+			Scope:          u.Scope,
+		})
+	}
+
+	for _, c := range plan.RoleAssignmentScheduleCreates {
+		document.Changes = append(document.Changes, &PlanDocumentChange{
+			Action:        "create",
+			EndDateTime:   c.EndDateTime,
+			PrincipalId:   c.PrincipalId,
+			PrincipalName: c.PrincipalName,
+			PrincipalType: string(c.PrincipalType),
+			ResourceType:  "activeAssignment",
+			RoleName:      c.RoleName,
+			Scope:         c.Scope,
+			StartDateTime: c.StartDateTime,
+		})
+	}
+
+	for _, u := range plan.RoleAssignmentScheduleUpdates {
+		document.Changes = append(document.Changes, &PlanDocumentChange{
+			Action:        "update",
+			EndDateTime:   u.EndDateTime,
+			PrincipalId:   u.PrincipalId,
+			PrincipalName: u.PrincipalName,
+			PrincipalType: string(u.PrincipalType),
+			ResourceType:  "activeAssignment",
+			RoleName:      u.RoleName,
+			Scope:         u.Scope,
+			StartDateTime: u.StartDateTime,
+		})
+	}
+
+	for _, d := range plan.RoleAssignmentScheduleDeletes {
+		document.Changes = append(document.Changes, &PlanDocumentChange{
+			Action:        "delete",
+			EndDateTime:   d.EndDateTime,
+			PrincipalId:   d.PrincipalId,
+			PrincipalName: d.PrincipalName,
+			PrincipalType: string(d.PrincipalType),
+			ResourceType:  "activeAssignment",
+			RoleName:      d.RoleName,
+			Scope:         d.Scope,
+			StartDateTime: d.StartDateTime,
+		})
+	}
+
+	for _, c := range plan.RoleEligibilityScheduleCreates {
+		document.Changes = append(document.Changes, &PlanDocumentChange{
+			Action:        "create",
+			EndDateTime:   c.EndDateTime,
+			PrincipalId:   c.PrincipalId,
+			PrincipalName: c.PrincipalName,
+			PrincipalType: string(c.PrincipalType),
+			ResourceType:  "eligibleAssignment",
+			RoleName:      c.RoleName,
+			Scope:         c.Scope,
+			StartDateTime: c.StartDateTime,
+		})
+	}
+
+	for _, u := range plan.RoleEligibilityScheduleUpdates {
+		document.Changes = append(document.Changes, &PlanDocumentChange{
+			Action:        "update",
+			EndDateTime:   u.EndDateTime,
+			PrincipalId:   u.PrincipalId,
+			PrincipalName: u.PrincipalName,
+			PrincipalType: string(u.PrincipalType),
+			ResourceType:  "eligibleAssignment",
+			RoleName:      u.RoleName,
+			Scope:         u.Scope,
+			StartDateTime: u.StartDateTime,
+		})
+	}
+
+	for _, d := range plan.RoleEligibilityScheduleDeletes {
+		document.Changes = append(document.Changes, &PlanDocumentChange{
+			Action:        "delete",
+			EndDateTime:   d.EndDateTime,
+			PrincipalId:   d.PrincipalId,
+			PrincipalName: d.PrincipalName,
+			PrincipalType: string(d.PrincipalType),
+			ResourceType:  "eligibleAssignment",
+			RoleName:      d.RoleName,
+			Scope:         d.Scope,
+			StartDateTime: d.StartDateTime,
+		})
+	}
+
+	return document
+}
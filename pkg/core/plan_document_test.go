@@ -0,0 +1,42 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewPlanDocumentListsChangesInApplyOrder(t *testing.T) {
+	plan := &Plan{
+		RoleAssignmentScheduleCreates:  []*RoleAssignmentScheduleCreate{{RoleName: "Reader"}},
+		RoleAssignmentScheduleDeletes:  []*RoleAssignmentScheduleDelete{{RoleName: "Reader"}},
+		RoleAssignmentScheduleUpdates:  []*RoleAssignmentScheduleUpdate{{RoleName: "Reader"}},
+		RoleDefinitionCreates:          []*RoleDefinitionCreate{{RoleName: "Custom"}},
+		RoleDefinitionUpdates:          []*RoleDefinitionUpdate{{RoleName: "Custom"}},
+		RoleEligibilityScheduleCreates: []*RoleEligibilityScheduleCreate{{RoleName: "Reader"}},
+		RoleEligibilityScheduleDeletes: []*RoleEligibilityScheduleDelete{{RoleName: "Reader"}},
+		RoleEligibilityScheduleUpdates: []*RoleEligibilityScheduleUpdate{{RoleName: "Reader"}},
+		RoleManagementPolicyUpdates:    []*RoleManagementPolicyUpdate{{RoleName: "Reader"}},
+	}
+
+	document := NewPlanDocument(plan)
+
+	var changes []string
+	for _, c := range document.Changes {
+		changes = append(changes, c.Action+" "+c.ResourceType)
+	}
+
+	expected := []string{
+		"create roleDefinition",
+		"update roleDefinition",
+		"update roleManagementPolicy",
+		"create activeAssignment",
+		"update activeAssignment",
+		"delete activeAssignment",
+		"create eligibleAssignment",
+		"update eligibleAssignment",
+		"delete eligibleAssignment",
+	}
+	if !slices.Equal(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+}
@@ -3,36 +3,73 @@ package core
 import (
 	"fmt"
 	"regexp"
+	"slices"
 )
 
 var (
-	resourceGroupRegex = regexp.MustCompile("^/subscriptions/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}/resourceGroups/([^/]+)$")
-	resourceRegex      = regexp.MustCompile("^/subscriptions/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}/resourceGroups/(.+)$")
-	subscriptionRegex  = regexp.MustCompile("^/subscriptions/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$")
+	managementGroupRegex = regexp.MustCompile("^/providers/Microsoft.Management/managementGroups/([^/]+)$")
+	resourceGroupRegex   = regexp.MustCompile("^/subscriptions/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}/resourceGroups/([^/]+)$")
+	resourceRegex        = regexp.MustCompile("^/subscriptions/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}/resourceGroups/(.+)$")
+	subscriptionRegex    = regexp.MustCompile("^/subscriptions/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$")
 )
 
-func (p *Policy) GetRulesetReferencesForScope(scope string, subscriptionId string) []*RulesetReference {
-	if subscriptionRegex.MatchString(scope) {
+// GetRulesetReferencesForScope returns the ruleset references that apply at the given scope. For
+// resource groups and resources, a key that matches the name exactly takes precedence over a
+// pattern that matches it, which in turn takes precedence over default. An error is returned if the
+// scope is not a management group, subscription, resource group or resource.
+func (p *Policy) GetRulesetReferencesForScope(scope string) ([]*RulesetReference, error) {
+	if managementGroupRegex.MatchString(scope) {
+		groups := managementGroupRegex.FindStringSubmatch(scope)
+		if p.ManagementGroups[groups[1]] != nil {
+			return p.ManagementGroups[groups[1]], nil
+		} else {
+			return p.Default, nil
+		}
+	} else if subscriptionRegex.MatchString(scope) {
 		if p.Subscription != nil {
-			return p.Subscription
+			return p.Subscription, nil
 		} else {
-			return p.Default
+			return p.Default, nil
 		}
 	} else if resourceGroupRegex.MatchString(scope) {
 		groups := resourceGroupRegex.FindStringSubmatch(scope)
-		if p.ResourceGroups[groups[1]] != nil {
-			return p.ResourceGroups[groups[1]]
+		if r := getRulesetReferencesForName(p.ResourceGroups, groups[1]); r != nil {
+			return r, nil
 		} else {
-			return p.Default
+			return p.Default, nil
 		}
 	} else if resourceRegex.MatchString(scope) {
 		groups := resourceRegex.FindStringSubmatch(scope)
-		if p.Resources[groups[1]] != nil {
-			return p.Resources[groups[1]]
+		if r := getRulesetReferencesForName(p.Resources, groups[1]); r != nil {
+			return r, nil
 		} else {
-			return p.Default
+			return p.Default, nil
+		}
+	}
+
+	return nil, fmt.Errorf("scope '%s' is not valid", scope)
+}
+
+// getRulesetReferencesForName returns the ruleset references with the key that matches the name
+// exactly or, failing that, the first pattern key in sorted order that matches it.
+func getRulesetReferencesForName(rulesetReferences map[string][]*RulesetReference, name string) []*RulesetReference {
+	if r, ok := rulesetReferences[name]; ok && r != nil {
+		return r
+	}
+
+	var patterns []string
+	for k := range rulesetReferences {
+		if isPattern(k) {
+			patterns = append(patterns, k)
+		}
+	}
+	slices.Sort(patterns)
+
+	for _, k := range patterns {
+		if rulesetReferences[k] != nil && matchPattern(k, name) {
+			return rulesetReferences[k]
 		}
 	}
 
-	panic(fmt.Sprintf("scope '%s' is not valid", scope))
+	return nil
 }
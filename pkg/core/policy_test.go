@@ -1 +1,126 @@
 package core
+
+import (
+	"testing"
+)
+
+func newRulesetReferences(rulesetName string) []*RulesetReference {
+	return []*RulesetReference{{RulesetName: rulesetName}}
+}
+
+func TestPolicyGetRulesetReferencesForScope(t *testing.T) {
+	subscriptionScope := "/subscriptions/00000000-0000-0000-0000-000000000001"
+
+	policy := &Policy{
+		Default: newRulesetReferences("default"),
+		ManagementGroups: map[string][]*RulesetReference{
+			"mg-platform": newRulesetReferences("management-group"),
+		},
+		ResourceGroups: map[string][]*RulesetReference{
+			"rg-app":   newRulesetReferences("exact"),
+			"rg-app*":  newRulesetReferences("pattern"),
+			"rg-data?": newRulesetReferences("single-character-pattern"),
+		},
+		Resources: map[string][]*RulesetReference{
+			"rg-app/providers/Microsoft.Web/sites/app-1": newRulesetReferences("exact-resource"),
+			"rg-app/providers/Microsoft.Web/sites/*":     newRulesetReferences("resource-pattern"),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *Policy
+		scope    string
+		expected string
+	}{
+		{
+			name:     "management group",
+			policy:   policy,
+			scope:    "/providers/Microsoft.Management/managementGroups/mg-platform",
+			expected: "management-group",
+		},
+		{
+			name:     "management group falls back to default",
+			policy:   policy,
+			scope:    "/providers/Microsoft.Management/managementGroups/mg-other",
+			expected: "default",
+		},
+		{
+			name:     "subscription falls back to default",
+			policy:   policy,
+			scope:    subscriptionScope,
+			expected: "default",
+		},
+		{
+			name:     "subscription",
+			policy:   &Policy{Default: newRulesetReferences("default"), Subscription: newRulesetReferences("subscription")},
+			scope:    subscriptionScope,
+			expected: "subscription",
+		},
+		{
+			name:     "exact resource group key takes precedence over pattern",
+			policy:   policy,
+			scope:    subscriptionScope + "/resourceGroups/rg-app",
+			expected: "exact",
+		},
+		{
+			name:     "resource group pattern takes precedence over default",
+			policy:   policy,
+			scope:    subscriptionScope + "/resourceGroups/rg-app-dev",
+			expected: "pattern",
+		},
+		{
+			name:     "resource group single character pattern",
+			policy:   policy,
+			scope:    subscriptionScope + "/resourceGroups/rg-data1",
+			expected: "single-character-pattern",
+		},
+		{
+			name:     "resource group falls back to default",
+			policy:   policy,
+			scope:    subscriptionScope + "/resourceGroups/rg-other",
+			expected: "default",
+		},
+		{
+			name:     "exact resource key takes precedence over pattern",
+			policy:   policy,
+			scope:    subscriptionScope + "/resourceGroups/rg-app/providers/Microsoft.Web/sites/app-1",
+			expected: "exact-resource",
+		},
+		{
+			name:     "resource pattern takes precedence over default",
+			policy:   policy,
+			scope:    subscriptionScope + "/resourceGroups/rg-app/providers/Microsoft.Web/sites/app-2",
+			expected: "resource-pattern",
+		},
+		{
+			name:     "resource falls back to default",
+			policy:   policy,
+			scope:    subscriptionScope + "/resourceGroups/rg-app/providers/Microsoft.Sql/servers/sql-1",
+			expected: "default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rulesetReferences, err := tt.policy.GetRulesetReferencesForScope(tt.scope)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(rulesetReferences) != 1 || rulesetReferences[0].RulesetName != tt.expected {
+				t.Errorf("expected ruleset %s, got %v", tt.expected, rulesetReferences)
+			}
+		})
+	}
+}
+
+func TestPolicyGetRulesetReferencesForScopeRejectsInvalidScope(t *testing.T) {
+	policy := &Policy{Default: newRulesetReferences("default")}
+
+	for _, scope := range []string{"", "/", "/subscriptions/not-a-guid", "/providers/Microsoft.Management/managementGroups/"} {
+		if _, err := policy.GetRulesetReferencesForScope(scope); err == nil {
+			t.Errorf("expected an error for scope %q", scope)
+		}
+	}
+}
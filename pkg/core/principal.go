@@ -0,0 +1,132 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+)
+
+// GetScopeConfigurationsByPath returns the scope configurations of the principal keyed by their
+// path in config, e.g. "resourceGroups[rg-dev]".
+func (p *Principal) GetScopeConfigurationsByPath() map[string]*ScopeConfiguration {
+	return getScopeConfigurationsByPath(p.Default, p.ManagementGroups, p.Subscription, p.ResourceGroups, p.Resources)
+}
+
+// WithProfiles returns a copy of the principal with the scope configurations of its profiles merged
+// in. Schedules are merged by role name, as a principal has at most one assignment of a role at a
+// scope, and schedules defined by the principal itself take precedence over those of its profiles.
+// The config must have been validated first, which reports profiles that do not exist and roles that
+// more than one profile assigns at the same scope; profiles that do not exist are skipped here.
+func (p *Principal) WithProfiles(profiles []*Profile) *Principal {
+	if len(p.Profiles) == 0 {
+		return p
+	}
+
+	merged := *p
+
+	for _, name := range p.Profiles {
+		idx := slices.IndexFunc(profiles, func(r *Profile) bool {
+			return r.Name == name
+		})
+		if idx == -1 {
+			continue
+		}
+
+		profile := profiles[idx]
+		merged.Default = mergeScopeConfigurations(merged.Default, profile.Default)
+		merged.ManagementGroups = mergeScopeConfigurationMaps(merged.ManagementGroups, profile.ManagementGroups)
+		merged.Subscription = mergeScopeConfigurations(merged.Subscription, profile.Subscription)
+		merged.ResourceGroups = mergeScopeConfigurationMaps(merged.ResourceGroups, profile.ResourceGroups)
+		merged.Resources = mergeScopeConfigurationMaps(merged.Resources, profile.Resources)
+	}
+
+	return &merged
+}
+
+// GetScopeConfigurationsByPath returns the scope configurations of the profile keyed by their
+// path in config, e.g. "resourceGroups[rg-dev]".
+func (p *Profile) GetScopeConfigurationsByPath() map[string]*ScopeConfiguration {
+	return getScopeConfigurationsByPath(p.Default, p.ManagementGroups, p.Subscription, p.ResourceGroups, p.Resources)
+}
+
+func getScopeConfigurationsByPath(
+	defaultScopeConfiguration *ScopeConfiguration,
+	managementGroups map[string]*ScopeConfiguration,
+	subscription *ScopeConfiguration,
+	resourceGroups map[string]*ScopeConfiguration,
+	resources map[string]*ScopeConfiguration,
+) map[string]*ScopeConfiguration {
+	scopeConfigurations := map[string]*ScopeConfiguration{}
+
+	if defaultScopeConfiguration != nil {
+		scopeConfigurations["default"] = defaultScopeConfiguration
+	}
+
+	if subscription != nil {
+		scopeConfigurations["subscription"] = subscription
+	}
+
+	for k, v := range managementGroups {
+		if v != nil {
+			scopeConfigurations[fmt.Sprintf("managementGroups[%s]", k)] = v
+		}
+	}
+
+	for k, v := range resourceGroups {
+		if v != nil {
+			scopeConfigurations[fmt.Sprintf("resourceGroups[%s]", k)] = v
+		}
+	}
+
+	for k, v := range resources {
+		if v != nil {
+			scopeConfigurations[fmt.Sprintf("resources[%s]", k)] = v
+		}
+	}
+
+	return scopeConfigurations
+}
+
+// mergeScopeConfigurations returns a scope configuration containing the schedules of both scope
+// configurations. Where both contain a schedule for the same role, the schedule in a is kept.
+func mergeScopeConfigurations(a *ScopeConfiguration, b *ScopeConfiguration) *ScopeConfiguration {
+	if b == nil {
+		return a
+	}
+
+	if a == nil {
+		a = &ScopeConfiguration{}
+	}
+
+	mergeSchedules := func(a []*Schedule, b []*Schedule) []*Schedule {
+		schedules := slices.Clone(a)
+		for _, s := range b {
+			if !slices.ContainsFunc(a, func(t *Schedule) bool { return t.RoleName == s.RoleName }) {
+				schedules = append(schedules, s)
+			}
+		}
+
+		return schedules
+	}
+
+	return &ScopeConfiguration{
+		Active:   mergeSchedules(a.Active, b.Active),
+		Eligible: mergeSchedules(a.Eligible, b.Eligible),
+	}
+}
+
+func mergeScopeConfigurationMaps(a map[string]*ScopeConfiguration, b map[string]*ScopeConfiguration) map[string]*ScopeConfiguration {
+	if len(b) == 0 {
+		return a
+	}
+
+	merged := map[string]*ScopeConfiguration{}
+	for k, v := range a {
+		merged[k] = v
+	}
+
+	for k, v := range b {
+		merged[k] = mergeScopeConfigurations(merged[k], v)
+	}
+
+	return merged
+}
@@ -0,0 +1,24 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+func (e PrincipalErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, p := range e {
+		messages[i] = fmt.Sprintf("- %s", p.Error())
+	}
+
+	return fmt.Sprintf("%d principal(s) could not be resolved:\n%s", len(e), strings.Join(messages, "\n"))
+}
+
+// SortPrincipalErrors sorts principal errors by message, so that they are reported in the same order
+// however the lookups were batched.
+func SortPrincipalErrors(principalErrors PrincipalErrors) {
+	slices.SortStableFunc(principalErrors, func(a, b error) int {
+		return strings.Compare(a.Error(), b.Error())
+	})
+}
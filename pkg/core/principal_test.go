@@ -0,0 +1,139 @@
+package core
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// getRoleNames returns the role names of the schedules, sorted.
+func getRoleNames(schedules []*Schedule) []string {
+	var roleNames []string
+	for _, s := range schedules {
+		roleNames = append(roleNames, s.RoleName)
+	}
+	slices.Sort(roleNames)
+
+	return roleNames
+}
+
+func TestPrincipalWithProfiles(t *testing.T) {
+	endDateTime := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	profiles := []*Profile{
+		{
+			Name: "readers",
+			Subscription: &ScopeConfiguration{
+				Active:   []*Schedule{{RoleName: "Reader"}},
+				Eligible: []*Schedule{{RoleName: "Contributor"}},
+			},
+		},
+		{
+			Name: "operators",
+			ResourceGroups: map[string]*ScopeConfiguration{
+				"rg-app": {Eligible: []*Schedule{{RoleName: "Website Contributor"}}},
+			},
+			Subscription: &ScopeConfiguration{
+				Eligible: []*Schedule{{RoleName: "Monitoring Contributor"}},
+			},
+		},
+	}
+
+	t.Run("principal overrides profile", func(t *testing.T) {
+		principal := &Principal{
+			Name:     "Engineers",
+			Profiles: []string{"readers"},
+			Subscription: &ScopeConfiguration{
+				Eligible: []*Schedule{{EndDateTime: &endDateTime, RoleName: "Contributor"}},
+			},
+		}
+
+		merged := principal.WithProfiles(profiles)
+
+		if roleNames := getRoleNames(merged.Subscription.Active); !slices.Equal(roleNames, []string{"Reader"}) {
+			t.Errorf("unexpected active roles: %v", roleNames)
+		}
+		if len(merged.Subscription.Eligible) != 1 || merged.Subscription.Eligible[0].EndDateTime != &endDateTime {
+			t.Errorf("expected the principal's own Contributor schedule, got %+v", merged.Subscription.Eligible)
+		}
+		if len(principal.Subscription.Active) != 0 {
+			t.Errorf("expected the principal not to be modified, got %+v", principal.Subscription.Active)
+		}
+	})
+
+	t.Run("two profiles are merged", func(t *testing.T) {
+		principal := &Principal{
+			Name:     "Engineers",
+			Profiles: []string{"readers", "operators"},
+		}
+
+		merged := principal.WithProfiles(profiles)
+
+		if roleNames := getRoleNames(merged.Subscription.Active); !slices.Equal(roleNames, []string{"Reader"}) {
+			t.Errorf("unexpected active roles: %v", roleNames)
+		}
+		if roleNames := getRoleNames(merged.Subscription.Eligible); !slices.Equal(roleNames, []string{"Contributor", "Monitoring Contributor"}) {
+			t.Errorf("unexpected eligible roles: %v", roleNames)
+		}
+		if roleNames := getRoleNames(merged.ResourceGroups["rg-app"].Eligible); !slices.Equal(roleNames, []string{"Website Contributor"}) {
+			t.Errorf("unexpected eligible roles at rg-app: %v", roleNames)
+		}
+	})
+
+	t.Run("unknown profile is skipped", func(t *testing.T) {
+		principal := &Principal{
+			Name:     "Engineers",
+			Profiles: []string{"missing", "readers"},
+		}
+
+		merged := principal.WithProfiles(profiles)
+
+		if roleNames := getRoleNames(merged.Subscription.Active); !slices.Equal(roleNames, []string{"Reader"}) {
+			t.Errorf("unexpected active roles: %v", roleNames)
+		}
+	})
+}
+
+func TestAzureRmConfigValidatePrincipalProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []string
+		expected []string
+	}{
+		{
+			name:     "known profiles",
+			profiles: []string{"readers", "operators"},
+		},
+		{
+			name:     "unknown profile",
+			profiles: []string{"missing"},
+			expected: []string{"profile missing not found"},
+		},
+		{
+			name:     "role assigned by two profiles",
+			profiles: []string{"readers", "contributors"},
+			expected: []string{"duplicate eligible role name Contributor at subscription from profiles readers and contributors"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &AzureRmConfig{
+				Groups: []*Principal{
+					{Name: "Engineers", Profiles: tt.profiles},
+				},
+				Profiles: []*Profile{
+					{Name: "readers", Subscription: &ScopeConfiguration{Eligible: []*Schedule{{RoleName: "Contributor"}}}},
+					{Name: "operators", Subscription: &ScopeConfiguration{Eligible: []*Schedule{{RoleName: "Monitoring Contributor"}}}},
+					{Name: "contributors", Subscription: &ScopeConfiguration{Eligible: []*Schedule{{RoleName: "Contributor"}}}},
+				},
+			}
+
+			messages := getValidationMessages(t, config)
+			if strings.Join(messages, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected %v, got %v", tt.expected, messages)
+			}
+		})
+	}
+}
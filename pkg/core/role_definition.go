@@ -0,0 +1,28 @@
+package core
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+)
+
+// GetRoleDefinitionProperties returns the properties of the custom role described by the role
+// definition.
+func (d *RoleDefinition) GetRoleDefinitionProperties() *armauthorization.RoleDefinitionProperties {
+	permissions := []*armauthorization.Permission{}
+	for _, p := range d.Permissions {
+		permissions = append(permissions, &armauthorization.Permission{
+			Actions:        to.SliceOfPtrs(p.Actions...),
+			DataActions:    to.SliceOfPtrs(p.DataActions...),
+			NotActions:     to.SliceOfPtrs(p.NotActions...),
+			NotDataActions: to.SliceOfPtrs(p.NotDataActions...),
+		})
+	}
+
+	return &armauthorization.RoleDefinitionProperties{
+		AssignableScopes: to.SliceOfPtrs(d.AssignableScopes...),
+		Description:      to.Ptr(d.Description),
+		Permissions:      permissions,
+		RoleName:         to.Ptr(d.Name),
+		RoleType:         to.Ptr("CustomRole"),
+	}
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAzureRmConfigValidateRequiresAssignableScopes(t *testing.T) {
+	config := &AzureRmConfig{
+		RoleDefinitions: []*RoleDefinition{
+			{
+				Name:        "App Operator",
+				Permissions: []*RoleDefinitionPermission{{Actions: []string{"Microsoft.Web/sites/restart/action"}}},
+			},
+		},
+	}
+
+	messages := getValidationMessages(t, config)
+	if expected := []string{"assignableScopes is required"}; !slices.Equal(messages, expected) {
+		t.Errorf("expected %v, got %v", expected, messages)
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"slices"
+	"strings"
+)
+
+// GetPatchedFields returns the paths of the fields set by the rule's patch, e.g.
+// "setting.isApprovalRequired". Arrays are replaced in their entirety by a merge patch, and so are
+// treated as a single field.
+func (r *RoleManagementPolicyRule) GetPatchedFields() []string {
+	fields := getPatchedFields(r.Patch, "")
+	slices.Sort(fields)
+
+	return fields
+}
+
+func getPatchedFields(patch interface{}, prefix string) []string {
+	var fields []string
+
+	switch p := patch.(type) {
+	case map[string]interface{}:
+		if len(p) == 0 {
+			break
+		}
+
+		for k, v := range p {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+
+			fields = append(fields, getPatchedFields(v, path)...)
+		}
+
+		return fields
+	}
+
+	if prefix != "" {
+		fields = append(fields, prefix)
+	}
+
+	return fields
+}
+
+// patchedFieldsOverlap returns true if a patch to one of the fields would affect the other, i.e.
+// the fields are the same or one contains the other.
+func patchedFieldsOverlap(a string, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
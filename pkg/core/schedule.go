@@ -0,0 +1,89 @@
+package core
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+)
+
+// GetCondition returns the ABAC condition of the schedule, or nil if it does not have one.
+func (s *Schedule) GetCondition() *string {
+	if s.Condition == "" {
+		return nil
+	}
+
+	return &s.Condition
+}
+
+// GetConditionVersion returns the version of the ABAC condition of the schedule, which defaults to
+// "2.0", or nil if it does not have a condition.
+func (s *Schedule) GetConditionVersion() *string {
+	if s.Condition == "" {
+		return nil
+	}
+
+	if s.ConditionVersion == "" {
+		return to.Ptr("2.0")
+	}
+
+	return &s.ConditionVersion
+}
+
+// GetDuration returns the duration of the schedule, or nil if it does not have one.
+func (s *Schedule) GetDuration() *Duration {
+	if s.Duration == "" {
+		return nil
+	}
+
+	duration, err := ParseDuration(s.Duration)
+	if err != nil {
+		return nil
+	}
+
+	return duration
+}
+
+// GetEndDateTime returns the end time of the schedule if it starts at the given time. This is the
+// configured end time or, if the schedule has a duration, the start time plus the duration.
+func (s *Schedule) GetEndDateTime(startDateTime time.Time) *time.Time {
+	if duration := s.GetDuration(); duration != nil {
+		endDateTime := duration.AddTo(startDateTime)
+		return &endDateTime
+	}
+
+	return s.EndDateTime
+}
+
+// IsDueForRenewal returns true if the schedule has a duration and renewal window, and the given
+// end time of the existing schedule is within the renewal window.
+func (s *Schedule) IsDueForRenewal(endDateTime *time.Time) bool {
+	if s.GetDuration() == nil || s.RenewalWindow == "" || endDateTime == nil {
+		return false
+	}
+
+	renewalWindow, err := ParseDuration(s.RenewalWindow)
+	if err != nil {
+		return false
+	}
+
+	return !time.Now().Before(renewalWindow.SubtractFrom(*endDateTime))
+}
+
+// GetJustification returns the justification for requests for the schedule, falling back to the
+// default justification.
+func (s *Schedule) GetJustification(defaults *ScheduleRequestDefaults) string {
+	if s.Justification != "" {
+		return s.Justification
+	}
+
+	return defaults.Justification
+}
+
+// GetTicket returns the ticket for requests for the schedule, falling back to the default ticket.
+func (s *Schedule) GetTicket(defaults *ScheduleRequestDefaults) *Ticket {
+	if s.Ticket != nil {
+		return s.Ticket
+	}
+
+	return defaults.Ticket
+}
@@ -0,0 +1,17 @@
+package core
+
+import (
+	"path"
+	"strings"
+)
+
+// NewScheduleKey creates the key that matches a schedule in config with a schedule in Azure. Azure compares
+// Ids without regard to case, and the same role definition can be referenced by Ids of different scopes, so
+// the role definition is keyed by its name, the last segment of its Id.
+func NewScheduleKey(scope string, roleDefinitionId string, principalId string) ScheduleKey {
+	return ScheduleKey{
+		PrincipalId:      strings.ToLower(principalId),
+		RoleDefinitionId: strings.ToLower(path.Base(roleDefinitionId)),
+		Scope:            strings.ToLower(strings.TrimSuffix(scope, "/")),
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+)
+
+func TestScheduleGetEndDateTime(t *testing.T) {
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	configuredEnd := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		expected *time.Time
+	}{
+		{
+			name:     "no duration or end date time",
+			schedule: &Schedule{},
+		},
+		{
+			name:     "end date time",
+			schedule: &Schedule{EndDateTime: &configuredEnd},
+			expected: &configuredEnd,
+		},
+		{
+			name:     "duration",
+			schedule: &Schedule{Duration: "P180D"},
+			expected: to.Ptr(time.Date(2024, 7, 13, 9, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:     "duration takes precedence over end date time",
+			schedule: &Schedule{Duration: "PT8H", EndDateTime: &configuredEnd},
+			expected: to.Ptr(time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:     "invalid duration falls back to end date time",
+			schedule: &Schedule{Duration: "180D", EndDateTime: &configuredEnd},
+			expected: &configuredEnd,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end := tt.schedule.GetEndDateTime(start)
+			if tt.expected == nil {
+				if end != nil {
+					t.Errorf("expected no end date time, got %s", end)
+				}
+				return
+			}
+
+			if end == nil || !end.Equal(*tt.expected) {
+				t.Errorf("expected %s, got %v", tt.expected, end)
+			}
+		})
+	}
+}
+
+func TestScheduleIsDueForRenewal(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		schedule    *Schedule
+		endDateTime *time.Time
+		expected    bool
+	}{
+		{
+			name:        "outside the renewal window",
+			schedule:    &Schedule{Duration: "P180D", RenewalWindow: "P7D"},
+			endDateTime: to.Ptr(now.Add(7*24*time.Hour + time.Minute)),
+		},
+		{
+			name:        "at the start of the renewal window",
+			schedule:    &Schedule{Duration: "P180D", RenewalWindow: "P7D"},
+			endDateTime: to.Ptr(now.Add(7*24*time.Hour - time.Minute)),
+			expected:    true,
+		},
+		{
+			name:        "expired",
+			schedule:    &Schedule{Duration: "P180D", RenewalWindow: "P7D"},
+			endDateTime: to.Ptr(now.Add(-time.Hour)),
+			expected:    true,
+		},
+		{
+			name:        "no renewal window",
+			schedule:    &Schedule{Duration: "P180D"},
+			endDateTime: to.Ptr(now.Add(time.Hour)),
+		},
+		{
+			name:        "no duration",
+			schedule:    &Schedule{RenewalWindow: "P7D"},
+			endDateTime: to.Ptr(now.Add(time.Hour)),
+		},
+		{
+			name:     "no end date time",
+			schedule: &Schedule{Duration: "P180D", RenewalWindow: "P7D"},
+		},
+		{
+			name:        "invalid renewal window",
+			schedule:    &Schedule{Duration: "P180D", RenewalWindow: "7D"},
+			endDateTime: to.Ptr(now.Add(time.Hour)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.schedule.IsDueForRenewal(tt.endDateTime); actual != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package core
+
+import "strings"
+
+// GetPosition returns the position of the value at the given path within the source file, e.g.
+// ".resourceGroups[rg-dev].active[0].roleName". If there is no value at the path, e.g. because a
+// required field is missing, then the position of the closest parent is returned instead.
+func (s *Source) GetPosition(path string) *SourcePosition {
+	for {
+		if p, ok := s.Positions[path]; ok {
+			return p
+		}
+
+		if path == "" {
+			return &SourcePosition{}
+		}
+
+		path = getParentPath(path)
+	}
+}
+
+func getParentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		return path[:strings.LastIndex(path, "[")]
+	}
+
+	idx := strings.LastIndex(path, ".")
+	if idx == -1 {
+		return ""
+	}
+
+	return path[:idx]
+}
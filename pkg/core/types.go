@@ -7,42 +7,88 @@ import (
 )
 
 type AzureRmConfig struct {
-	Groups   []*Principal                   `validate:"dive"`
-	Policies []*Policy                      `validate:"dive"`
-	Rulesets []*RoleManagementPolicyRuleset `validate:"dive"`
-	Users    []*Principal                   `validate:"dive"`
+	Groups            []*Principal                   `validate:"dive"`
+	Policies          []*Policy                      `validate:"dive"`
+	Profiles          []*Profile                     `validate:"dive"`
+	RoleDefinitions   []*RoleDefinition              `validate:"dive"`
+	Rulesets          []*RoleManagementPolicyRuleset `validate:"dive"`
+	ServicePrincipals []*Principal                   `validate:"dive"`
+	Users             []*Principal                   `validate:"dive"`
 }
 
 type Principal struct {
-	Name           string
-	Subscription   *ScopeConfiguration            `yaml:"subscription"`
-	ResourceGroups map[string]*ScopeConfiguration `yaml:"resourceGroups"`
-	Resources      map[string]*ScopeConfiguration `yaml:"resources"`
+	Default          *ScopeConfiguration            `yaml:"default" json:"default"`
+	Id               string                         `yaml:"-" json:"-" validate:"-"`
+	ManagementGroups map[string]*ScopeConfiguration `yaml:"managementGroups" json:"managementGroups" validate:"dive"`
+	Name             string
+	Profiles         []string                       `yaml:"profiles" json:"profiles"`
+	Source           *Source                        `yaml:"-" json:"-" validate:"-"`
+	Subscription     *ScopeConfiguration            `yaml:"subscription" json:"subscription"`
+	ResourceGroups   map[string]*ScopeConfiguration `yaml:"resourceGroups" json:"resourceGroups" validate:"dive"`
+	Resources        map[string]*ScopeConfiguration `yaml:"resources" json:"resources" validate:"dive"`
+}
+
+type Profile struct {
+	Default          *ScopeConfiguration            `yaml:"default" json:"default"`
+	ManagementGroups map[string]*ScopeConfiguration `yaml:"managementGroups" json:"managementGroups" validate:"dive"`
+	Name             string
+	Source           *Source                        `yaml:"-" json:"-" validate:"-"`
+	Subscription     *ScopeConfiguration            `yaml:"subscription" json:"subscription"`
+	ResourceGroups   map[string]*ScopeConfiguration `yaml:"resourceGroups" json:"resourceGroups" validate:"dive"`
+	Resources        map[string]*ScopeConfiguration `yaml:"resources" json:"resources" validate:"dive"`
 }
 
 type ScopeConfiguration struct {
-	Active   []*Schedule `yaml:"active"`
-	Eligible []*Schedule `yaml:"eligible"`
+	Active   []*Schedule `yaml:"active" json:"active" validate:"dive"`
+	Eligible []*Schedule `yaml:"eligible" json:"eligible" validate:"dive"`
 }
 
 type Schedule struct {
-	EndDateTime   *time.Time `yaml:"endDateTime"`
-	PrincipalName string
-	RoleName      string `yaml:"roleName" validate:"required"`
-	Scope         string
-	StartDateTime *time.Time `yaml:"startDateTime"`
+	Condition        string     `yaml:"condition" json:"condition"`
+	ConditionVersion string     `yaml:"conditionVersion" json:"conditionVersion" validate:"omitempty,oneof=2.0"`
+	Duration         string     `yaml:"duration" json:"duration" validate:"omitempty,iso8601_duration"`
+	EndDateTime      *time.Time `yaml:"endDateTime" json:"endDateTime"`
+	Justification    string     `yaml:"justification" json:"justification"`
+	PrincipalId      string
+	PrincipalName    string
+	RenewalWindow    string `yaml:"renewalWindow" json:"renewalWindow" validate:"omitempty,iso8601_duration"`
+	RoleName         string `yaml:"roleName" json:"roleName" validate:"required"`
+	Scope            string
+	StartDateTime    *time.Time `yaml:"startDateTime" json:"startDateTime"`
+	Ticket           *Ticket    `yaml:"ticket" json:"ticket"`
+}
+
+type Ticket struct {
+	Number string `yaml:"number" json:"number"`
+	System string `yaml:"system" json:"system"`
+}
+
+// ScheduleRequestDefaults are the values used for schedule requests where they are not set by the
+// schedule, including requests to delete schedules that are no longer in config.
+type ScheduleRequestDefaults struct {
+	Justification string
+	Ticket        *Ticket
+}
+
+type Duration struct {
+	Days   int
+	Months int
+	Time   time.Duration
+	Years  int
 }
 
 type RulesetReference struct {
-	RulesetName string `yaml:"rulesetName" validate:"required"`
+	RulesetName string `yaml:"rulesetName" json:"rulesetName" validate:"required"`
 }
 
 type Policy struct {
-	Default        []*RulesetReference `yaml:"default"`
-	Name           string
-	Subscription   []*RulesetReference            `yaml:"subscription"`
-	ResourceGroups map[string][]*RulesetReference `yaml:"resourceGroups"`
-	Resources      map[string][]*RulesetReference `yaml:"resources"`
+	Default          []*RulesetReference            `yaml:"default" json:"default"`
+	ManagementGroups map[string][]*RulesetReference `yaml:"managementGroups" json:"managementGroups"`
+	Name             string
+	Source           *Source                        `yaml:"-" json:"-" validate:"-"`
+	Subscription     []*RulesetReference            `yaml:"subscription" json:"subscription"`
+	ResourceGroups   map[string][]*RulesetReference `yaml:"resourceGroups" json:"resourceGroups"`
+	Resources        map[string][]*RulesetReference `yaml:"resources" json:"resources"`
 }
 
 type ScopeRoleNameCombination struct {
@@ -51,87 +97,235 @@ type ScopeRoleNameCombination struct {
 }
 
 type RoleAssignmentScheduleCreate struct {
-	EndDateTime                       *time.Time
-	PrincipalName                     string
-	PrincipalType                     armauthorization.PrincipalType
-	RoleAssignmentScheduleRequest     *armauthorization.RoleAssignmentScheduleRequest
-	RoleAssignmentScheduleRequestName string
-	RoleName                          string
-	Scope                             string
-	StartDateTime                     *time.Time
+	Condition                         string                                          `json:"condition"`
+	EndDateTime                       *time.Time                                      `json:"endDateTime,omitempty"`
+	Justification                     string                                          `json:"justification"`
+	PrincipalId                       string                                          `json:"principalId"`
+	PrincipalName                     string                                          `json:"principalName"`
+	PrincipalType                     armauthorization.PrincipalType                  `json:"principalType"`
+	RoleAssignmentScheduleRequest     *armauthorization.RoleAssignmentScheduleRequest `json:"roleAssignmentScheduleRequest,omitempty"`
+	RoleAssignmentScheduleRequestName string                                          `json:"roleAssignmentScheduleRequestName"`
+	RoleName                          string                                          `json:"roleName"`
+	Scope                             string                                          `json:"scope"`
+	StartDateTime                     *time.Time                                      `json:"startDateTime,omitempty"`
+	Ticket                            *Ticket                                         `json:"ticket,omitempty"`
 }
 
 type RoleAssignmentScheduleDelete struct {
-	Cancel                            bool
-	EndDateTime                       *time.Time
-	PrincipalName                     string
-	PrincipalType                     armauthorization.PrincipalType
-	RoleAssignmentScheduleRequest     *armauthorization.RoleAssignmentScheduleRequest
-	RoleAssignmentScheduleRequestName string
-	RoleName                          string
-	Scope                             string
-	StartDateTime                     *time.Time
+	Cancel                            bool                                            `json:"cancel"`
+	EndDateTime                       *time.Time                                      `json:"endDateTime,omitempty"`
+	Justification                     string                                          `json:"justification"`
+	PrincipalId                       string                                          `json:"principalId"`
+	PrincipalName                     string                                          `json:"principalName"`
+	PrincipalType                     armauthorization.PrincipalType                  `json:"principalType"`
+	RoleAssignmentScheduleRequest     *armauthorization.RoleAssignmentScheduleRequest `json:"roleAssignmentScheduleRequest,omitempty"`
+	RoleAssignmentScheduleRequestName string                                          `json:"roleAssignmentScheduleRequestName"`
+	RoleName                          string                                          `json:"roleName"`
+	Scope                             string                                          `json:"scope"`
+	StartDateTime                     *time.Time                                      `json:"startDateTime,omitempty"`
+	Ticket                            *Ticket                                         `json:"ticket,omitempty"`
 }
 
 type RoleAssignmentScheduleUpdate struct {
-	EndDateTime                       *time.Time
-	PrincipalName                     string
-	PrincipalType                     armauthorization.PrincipalType
-	RoleAssignmentScheduleRequest     *armauthorization.RoleAssignmentScheduleRequest
-	RoleAssignmentScheduleRequestName string
-	RoleName                          string
-	Scope                             string
-	StartDateTime                     *time.Time
+	Condition                         string                                          `json:"condition"`
+	EndDateTime                       *time.Time                                      `json:"endDateTime,omitempty"`
+	Justification                     string                                          `json:"justification"`
+	PrincipalId                       string                                          `json:"principalId"`
+	PrincipalName                     string                                          `json:"principalName"`
+	PrincipalType                     armauthorization.PrincipalType                  `json:"principalType"`
+	RoleAssignmentScheduleRequest     *armauthorization.RoleAssignmentScheduleRequest `json:"roleAssignmentScheduleRequest,omitempty"`
+	RoleAssignmentScheduleRequestName string                                          `json:"roleAssignmentScheduleRequestName"`
+	RoleName                          string                                          `json:"roleName"`
+	Scope                             string                                          `json:"scope"`
+	StartDateTime                     *time.Time                                      `json:"startDateTime,omitempty"`
+	Ticket                            *Ticket                                         `json:"ticket,omitempty"`
 }
 
 type RoleEligibilityScheduleCreate struct {
-	EndDateTime                        *time.Time
-	PrincipalName                      string
-	PrincipalType                      armauthorization.PrincipalType
-	RoleEligibilityScheduleRequest     *armauthorization.RoleEligibilityScheduleRequest
-	RoleEligibilityScheduleRequestName string
-	RoleName                           string
-	Scope                              string
-	StartDateTime                      *time.Time
+	Condition                          string                                           `json:"condition"`
+	EndDateTime                        *time.Time                                       `json:"endDateTime,omitempty"`
+	Justification                      string                                           `json:"justification"`
+	PrincipalId                        string                                           `json:"principalId"`
+	PrincipalName                      string                                           `json:"principalName"`
+	PrincipalType                      armauthorization.PrincipalType                   `json:"principalType"`
+	RoleEligibilityScheduleRequest     *armauthorization.RoleEligibilityScheduleRequest `json:"roleEligibilityScheduleRequest,omitempty"`
+	RoleEligibilityScheduleRequestName string                                           `json:"roleEligibilityScheduleRequestName"`
+	RoleName                           string                                           `json:"roleName"`
+	Scope                              string                                           `json:"scope"`
+	StartDateTime                      *time.Time                                       `json:"startDateTime,omitempty"`
+	Ticket                             *Ticket                                          `json:"ticket,omitempty"`
 }
 
 type RoleEligibilityScheduleDelete struct {
-	Cancel                             bool
-	EndDateTime                        *time.Time
-	PrincipalName                      string
-	PrincipalType                      armauthorization.PrincipalType
-	RoleEligibilityScheduleRequest     *armauthorization.RoleEligibilityScheduleRequest
-	RoleEligibilityScheduleRequestName string
-	RoleName                           string
-	Scope                              string
-	StartDateTime                      *time.Time
+	Cancel                             bool                                             `json:"cancel"`
+	EndDateTime                        *time.Time                                       `json:"endDateTime,omitempty"`
+	Justification                      string                                           `json:"justification"`
+	PrincipalId                        string                                           `json:"principalId"`
+	PrincipalName                      string                                           `json:"principalName"`
+	PrincipalType                      armauthorization.PrincipalType                   `json:"principalType"`
+	RoleEligibilityScheduleRequest     *armauthorization.RoleEligibilityScheduleRequest `json:"roleEligibilityScheduleRequest,omitempty"`
+	RoleEligibilityScheduleRequestName string                                           `json:"roleEligibilityScheduleRequestName"`
+	RoleName                           string                                           `json:"roleName"`
+	Scope                              string                                           `json:"scope"`
+	StartDateTime                      *time.Time                                       `json:"startDateTime,omitempty"`
+	Ticket                             *Ticket                                          `json:"ticket,omitempty"`
 }
 
 type RoleEligibilityScheduleUpdate struct {
-	EndDateTime                        *time.Time
-	PrincipalName                      string
-	PrincipalType                      armauthorization.PrincipalType
-	RoleEligibilityScheduleRequest     *armauthorization.RoleEligibilityScheduleRequest
-	RoleEligibilityScheduleRequestName string
-	RoleName                           string
-	Scope                              string
-	StartDateTime                      *time.Time
+	Condition                          string                                           `json:"condition"`
+	EndDateTime                        *time.Time                                       `json:"endDateTime,omitempty"`
+	Justification                      string                                           `json:"justification"`
+	PrincipalId                        string                                           `json:"principalId"`
+	PrincipalName                      string                                           `json:"principalName"`
+	PrincipalType                      armauthorization.PrincipalType                   `json:"principalType"`
+	RoleEligibilityScheduleRequest     *armauthorization.RoleEligibilityScheduleRequest `json:"roleEligibilityScheduleRequest,omitempty"`
+	RoleEligibilityScheduleRequestName string                                           `json:"roleEligibilityScheduleRequestName"`
+	RoleName                           string                                           `json:"roleName"`
+	Scope                              string                                           `json:"scope"`
+	StartDateTime                      *time.Time                                       `json:"startDateTime,omitempty"`
+	Ticket                             *Ticket                                          `json:"ticket,omitempty"`
+}
+
+type RoleDefinition struct {
+	AssignableScopes []string `yaml:"assignableScopes" json:"assignableScopes" validate:"required,min=1"`
+	Description      string   `yaml:"description" json:"description"`
+	Name             string
+	Permissions      []*RoleDefinitionPermission `yaml:"permissions" json:"permissions" validate:"required,min=1,dive"`
+	Source           *Source                     `yaml:"-" json:"-" validate:"-"`
+}
+
+type RoleDefinitionPermission struct {
+	Actions        []string `yaml:"actions" json:"actions"`
+	DataActions    []string `yaml:"dataActions" json:"dataActions"`
+	NotActions     []string `yaml:"notActions" json:"notActions"`
+	NotDataActions []string `yaml:"notDataActions" json:"notDataActions"`
+}
+
+type RoleDefinitionCreate struct {
+	RoleDefinition     *armauthorization.RoleDefinition `json:"roleDefinition,omitempty"`
+	RoleDefinitionName string                           `json:"roleDefinitionName"`
+	RoleName           string                           `json:"roleName"`
+	Scope              string                           `json:"scope"`
+}
+
+type RoleDefinitionUpdate struct {
+	RoleDefinition     *armauthorization.RoleDefinition `json:"roleDefinition,omitempty"`
+	RoleDefinitionName string                           `json:"roleDefinitionName"`
+	RoleName           string                           `json:"roleName"`
+	Scope              string                           `json:"scope"`
 }
 
 type RoleManagementPolicyRule struct {
-	ID    string      `yaml:"id" validate:"required"`
-	Patch interface{} `yaml:"patch" validate:"required"`
+	ID    string      `yaml:"id" json:"id" validate:"required"`
+	Patch interface{} `yaml:"patch" json:"patch" validate:"required"`
 }
 
 type RoleManagementPolicyRuleset struct {
-	Name  string
-	Rules []*RoleManagementPolicyRule `yaml:"rules"`
+	Name   string
+	Source *Source                     `yaml:"-" json:"-" validate:"-"`
+	Rules  []*RoleManagementPolicyRule `yaml:"rules" json:"rules"`
 }
 
 type RoleManagementPolicyUpdate struct {
-	RoleManagementPolicy *armauthorization.RoleManagementPolicy
-	RoleName             string
-	Scope                string
+	ChangedRuleIds       []string                               `json:"changedRuleIds,omitempty"`
+	RoleManagementPolicy *armauthorization.RoleManagementPolicy `json:"roleManagementPolicy,omitempty"`
+	RoleName             string                                 `json:"roleName"`
+	Scope                string                                 `json:"scope"`
 }
 
+type Plan struct {
+	RoleDefinitionCreates          []*RoleDefinitionCreate          `json:"roleDefinitionCreates,omitempty"`
+	RoleDefinitionUpdates          []*RoleDefinitionUpdate          `json:"roleDefinitionUpdates,omitempty"`
+	RoleAssignmentScheduleCreates  []*RoleAssignmentScheduleCreate  `json:"roleAssignmentScheduleCreates,omitempty"`
+	RoleAssignmentScheduleDeletes  []*RoleAssignmentScheduleDelete  `json:"roleAssignmentScheduleDeletes,omitempty"`
+	RoleAssignmentScheduleUpdates  []*RoleAssignmentScheduleUpdate  `json:"roleAssignmentScheduleUpdates,omitempty"`
+	RoleEligibilityScheduleCreates []*RoleEligibilityScheduleCreate `json:"roleEligibilityScheduleCreates,omitempty"`
+	RoleEligibilityScheduleDeletes []*RoleEligibilityScheduleDelete `json:"roleEligibilityScheduleDeletes,omitempty"`
+	RoleEligibilityScheduleUpdates []*RoleEligibilityScheduleUpdate `json:"roleEligibilityScheduleUpdates,omitempty"`
+	RoleManagementPolicyUpdates    []*RoleManagementPolicyUpdate    `json:"roleManagementPolicyUpdates,omitempty"`
+	Scope                          string                           `json:"scope,omitempty"`
+}
+
+type PlanDocument struct {
+	Changes []*PlanDocumentChange `json:"changes" yaml:"changes"`
+	Summary *PlanDocumentSummary  `json:"summary" yaml:"summary"`
+	Version int                   `json:"version" yaml:"version"`
+}
+
+type PlanDocumentChange struct {
+	Action         string     `json:"action" yaml:"action"`
+	ChangedRuleIds []string   `json:"changedRuleIds,omitempty" yaml:"changedRuleIds,omitempty"`
+	EndDateTime    *time.Time `json:"endDateTime,omitempty" yaml:"endDateTime,omitempty"`
+	PrincipalId    string     `json:"principalId,omitempty" yaml:"principalId,omitempty"`
+	PrincipalName  string     `json:"principalName,omitempty" yaml:"principalName,omitempty"`
+	PrincipalType  string     `json:"principalType,omitempty" yaml:"principalType,omitempty"`
+	ResourceType   string     `json:"resourceType" yaml:"resourceType"`
+	RoleName       string     `json:"roleName" yaml:"roleName"`
+	Scope          string     `json:"scope" yaml:"scope"`
+	StartDateTime  *time.Time `json:"startDateTime,omitempty" yaml:"startDateTime,omitempty"`
+}
+
+type PlanDocumentSummary struct {
+	Add    int `json:"add" yaml:"add"`
+	Change int `json:"change" yaml:"change"`
+	Delete int `json:"delete" yaml:"delete"`
+}
+
+type SavedPlan struct {
+	ConfigHash string   `json:"configHash"`
+	Plan       *Plan    `json:"plan"`
+	Scopes     []string `json:"scopes"`
+	StateHash  string   `json:"stateHash"`
+	Version    int      `json:"version"`
+}
+
+type ChangesPresentError struct{}
+
 type ConfigurationEmptyError struct{}
+
+type ConfigError struct {
+	Column   int
+	FilePath string
+	Line     int
+	Message  string
+}
+
+type ConfigErrors []*ConfigError
+
+type PrincipalErrors []error
+
+// KeyedRoleAssignmentSchedules are the schedules in config for a type of principal at a scope and the role
+// assignment schedules in Azure for that type of principal, keyed once so that every type of change can be
+// planned from them.
+type KeyedRoleAssignmentSchedules struct {
+	Existing      []*armauthorization.RoleAssignmentSchedule
+	ExistingIndex map[ScheduleKey]*armauthorization.RoleAssignmentSchedule
+	Keys          map[*Schedule]ScheduleKey
+	Schedules     []*Schedule
+}
+
+// KeyedRoleEligibilitySchedules are the schedules in config for a type of principal at a scope and the role
+// eligibility schedules in Azure for that type of principal, keyed once so that every type of change can be
+// planned from them.
+type KeyedRoleEligibilitySchedules struct {
+	Existing      []*armauthorization.RoleEligibilitySchedule
+	ExistingIndex map[ScheduleKey]*armauthorization.RoleEligibilitySchedule
+	Keys          map[*Schedule]ScheduleKey
+	Schedules     []*Schedule
+}
+
+type ScheduleKey struct {
+	PrincipalId      string
+	RoleDefinitionId string
+	Scope            string
+}
+
+type Source struct {
+	FilePath  string
+	Positions map[string]*SourcePosition
+}
+
+type SourcePosition struct {
+	Column int
+	Line   int
+}
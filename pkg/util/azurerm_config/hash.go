@@ -0,0 +1,56 @@
+package azurerm_config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// GetHash gets a hash of the config files in the config dir and the variables that they are interpolated
+// with, which identifies the config that a plan was generated from.
+func GetHash(configDirPath string, variables map[string]string) (string, error) {
+	hash := sha256.New()
+
+	err := filepath.WalkDir(configDirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !isConfigFile(d.Name()) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(configDirPath, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(relPath), len(data))
+		hash.Write(data)
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for name := range variables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, variables[name])
+	}
+
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
@@ -0,0 +1,61 @@
+package azurerm_config
+
+import "testing"
+
+func TestGetHash(t *testing.T) {
+	files := map[string]string{
+		"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+	}
+	variables := map[string]string{"environment": "prod"}
+
+	hash, err := GetHash(writeFiles(t, files), variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		variables map[string]string
+		same      bool
+	}{
+		{
+			name:      "same config",
+			files:     files,
+			variables: variables,
+			same:      true,
+		},
+		{
+			name: "changed file",
+			files: map[string]string{
+				"groups/Engineers.yml": "subscription:\n  active:\n    - roleName: Contributor\n",
+			},
+			variables: variables,
+		},
+		{
+			name: "renamed file",
+			files: map[string]string{
+				"groups/Developers.yml": "subscription:\n  active:\n    - roleName: Reader\n",
+			},
+			variables: variables,
+		},
+		{
+			name:      "changed variable",
+			files:     files,
+			variables: map[string]string{"environment": "dev"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			otherHash, err := GetHash(writeFiles(t, test.files), test.variables)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (otherHash == hash) != test.same {
+				t.Errorf("expected hashes to be the same: %t, got %s and %s", test.same, hash, otherHash)
+			}
+		})
+	}
+}
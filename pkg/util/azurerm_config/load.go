@@ -4,58 +4,166 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/gofrontier-com/sheriff/pkg/core"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
-func convertPatchStruct(i interface{}) interface{} {
-	switch x := i.(type) {
-	case map[interface{}]interface{}:
-		m2 := map[string]interface{}{}
-		for k, v := range x {
-			m2[k.(string)] = convertPatchStruct(v)
+var configFileExtensions = []string{".json", ".yaml", ".yml"}
+
+func isConfigFile(fileName string) bool {
+	return slices.Contains(configFileExtensions, filepath.Ext(fileName))
+}
+
+// unmarshalConfigFile unmarshals a YAML or JSON config file after interpolating variables, returning
+// the source of the unmarshalled object. JSON is a subset of YAML, so both are parsed by the YAML parser.
+func unmarshalConfigFile(filePath string, out interface{}, variables map[string]string) (*core.Source, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	err = yaml.Unmarshal(data, &node)
+	if err != nil {
+		return nil, getConfigErrors(filePath, err)
+	}
+
+	configErrors := interpolateVariables(filePath, &node, variables)
+	if len(configErrors) > 0 {
+		return nil, configErrors
+	}
+
+	source, configErrors := getSource(filePath, &node, reflect.TypeOf(out))
+	if len(configErrors) > 0 {
+		return nil, configErrors
+	}
+
+	if node.Kind == 0 {
+		return source, nil
+	}
+
+	err = node.Decode(out)
+	if err != nil {
+		return nil, getConfigErrors(filePath, err)
+	}
+
+	return source, nil
+}
+
+func checkForDuplicateName(fileNames map[string]string, kind string, dirPath string, fileName string) error {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	if existingFileName, ok := fileNames[name]; ok {
+		return &core.ConfigError{
+			FilePath: filepath.Join(dirPath, fileName),
+			Message:  fmt.Sprintf("%s \"%s\" is already defined in %s", kind, name, filepath.Join(dirPath, existingFileName)),
+		}
+	}
+
+	fileNames[name] = fileName
+
+	return nil
+}
+
+// appendConfigError appends err to configErrors if it is a config error, i.e. one that
+// should not stop the remaining config files from being loaded, otherwise returns it.
+func appendConfigError(configErrors core.ConfigErrors, err error) (core.ConfigErrors, error) {
+	switch e := err.(type) {
+	case *core.ConfigError:
+		return append(configErrors, e), nil
+	case core.ConfigErrors:
+		return append(configErrors, e...), nil
+	default:
+		return configErrors, err
+	}
+}
+
+func loadRoleDefinitions(roleDefinitionsDirPath string, variables map[string]string) ([]*core.RoleDefinition, core.ConfigErrors, error) {
+	var roleDefinitions []*core.RoleDefinition
+	var configErrors core.ConfigErrors
+
+	if _, err := os.Stat(roleDefinitionsDirPath); err != nil {
+		if os.IsNotExist(err) {
+			return roleDefinitions, nil, nil
 		}
-		return m2
-	case []interface{}:
-		for i, v := range x {
-			x[i] = convertPatchStruct(v)
+	}
+
+	entries, err := os.ReadDir(roleDefinitionsDirPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileNames := map[string]string{}
+	for _, e := range entries {
+		if !isConfigFile(e.Name()) {
+			continue
 		}
+
+		err = checkForDuplicateName(fileNames, "role definition", roleDefinitionsDirPath, e.Name())
+		if err != nil {
+			configErrors = append(configErrors, err.(*core.ConfigError))
+			continue
+		}
+
+		var roleDefinition core.RoleDefinition
+
+		source, err := unmarshalConfigFile(filepath.Join(roleDefinitionsDirPath, e.Name()), &roleDefinition, variables)
+		if err != nil {
+			configErrors, err = appendConfigError(configErrors, err)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		roleDefinition.Name = strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		roleDefinition.Source = source
+
+		roleDefinitions = append(roleDefinitions, &roleDefinition)
 	}
-	return i
+
+	return roleDefinitions, configErrors, nil
 }
 
-func loadRoleManagementPolicyRulesets(patchesDirPath string) ([]*core.RoleManagementPolicyRuleset, error) {
+func loadRoleManagementPolicyRulesets(patchesDirPath string, variables map[string]string) ([]*core.RoleManagementPolicyRuleset, core.ConfigErrors, error) {
 	var roleManagementPolicyRulesets []*core.RoleManagementPolicyRuleset
+	var configErrors core.ConfigErrors
 
 	if _, err := os.Stat(patchesDirPath); err != nil {
 		if os.IsNotExist(err) {
-			return roleManagementPolicyRulesets, nil
+			return roleManagementPolicyRulesets, nil, nil
 		}
 	}
 
 	entries, err := os.ReadDir(patchesDirPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	fileNames := map[string]string{}
 	for _, e := range entries {
-		if filepath.Ext(e.Name()) != ".yml" && filepath.Ext(e.Name()) != ".yaml" {
+		if !isConfigFile(e.Name()) {
 			continue
 		}
 
-		filePath := filepath.Join(patchesDirPath, e.Name())
-		yamlFile, err := os.ReadFile(filePath)
+		err = checkForDuplicateName(fileNames, "ruleset", patchesDirPath, e.Name())
 		if err != nil {
-			return nil, err
+			configErrors = append(configErrors, err.(*core.ConfigError))
+			continue
 		}
 
 		var roleManagementPolicyRuleset core.RoleManagementPolicyRuleset
 
-		err = yaml.Unmarshal(yamlFile, &roleManagementPolicyRuleset)
+		source, err := unmarshalConfigFile(filepath.Join(patchesDirPath, e.Name()), &roleManagementPolicyRuleset, variables)
 		if err != nil {
-			return nil, err
+			configErrors, err = appendConfigError(configErrors, err)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
 		}
 
 		if roleManagementPolicyRuleset.Rules == nil {
@@ -63,53 +171,58 @@ func loadRoleManagementPolicyRulesets(patchesDirPath string) ([]*core.RoleManage
 		}
 
 		roleManagementPolicyRuleset.Name = strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
-
-		for _, r := range roleManagementPolicyRuleset.Rules {
-			r.Patch = convertPatchStruct(r.Patch)
-		}
+		roleManagementPolicyRuleset.Source = source
 
 		roleManagementPolicyRulesets = append(roleManagementPolicyRulesets, &roleManagementPolicyRuleset)
 	}
 
-	return roleManagementPolicyRulesets, err
+	return roleManagementPolicyRulesets, configErrors, nil
 }
 
-func loadPolicies(policiesDirPath string) ([]*core.Policy, error) {
+func loadPolicies(policiesDirPath string, variables map[string]string) ([]*core.Policy, core.ConfigErrors, error) {
 	var policies []*core.Policy
+	var configErrors core.ConfigErrors
 
 	if _, err := os.Stat(policiesDirPath); err != nil {
 		if os.IsNotExist(err) {
-			return policies, nil
+			return policies, nil, nil
 		}
 	}
 
 	entries, err := os.ReadDir(policiesDirPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+
+	fileNames := map[string]string{}
 	for _, e := range entries {
 		if e.IsDir() {
 			continue
 		}
 
-		if filepath.Ext(e.Name()) != ".yml" && filepath.Ext(e.Name()) != ".yaml" {
+		if !isConfigFile(e.Name()) {
 			continue
 		}
 
-		filePath := filepath.Join(policiesDirPath, e.Name())
-		yamlFile, err := os.ReadFile(filePath)
+		err = checkForDuplicateName(fileNames, "policy", policiesDirPath, e.Name())
 		if err != nil {
-			return nil, err
+			configErrors = append(configErrors, err.(*core.ConfigError))
+			continue
 		}
 
 		var policy core.Policy
 
-		err = yaml.Unmarshal(yamlFile, &policy)
+		source, err := unmarshalConfigFile(filepath.Join(policiesDirPath, e.Name()), &policy, variables)
 		if err != nil {
-			return nil, err
+			configErrors, err = appendConfigError(configErrors, err)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
 		}
 
 		if policy.Default == nil &&
+			policy.ManagementGroups == nil &&
 			policy.Subscription == nil &&
 			policy.ResourceGroups == nil &&
 			policy.Resources == nil {
@@ -117,57 +230,115 @@ func loadPolicies(policiesDirPath string) ([]*core.Policy, error) {
 		}
 
 		policy.Name = strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		policy.Source = source
 
 		policies = append(policies, &policy)
 	}
 
-	return policies, nil
+	return policies, configErrors, nil
 }
 
-func loadPrincipals(principalsDirPath string) ([]*core.Principal, error) {
+func loadPrincipals(principalsDirPath string, variables map[string]string) ([]*core.Principal, core.ConfigErrors, error) {
 	var principals []*core.Principal
+	var configErrors core.ConfigErrors
 
 	if _, err := os.Stat(principalsDirPath); err != nil {
 		if os.IsNotExist(err) {
-			return principals, nil
+			return principals, nil, nil
 		}
 	}
 
 	entries, err := os.ReadDir(principalsDirPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	fileNames := map[string]string{}
 	for _, e := range entries {
-		if filepath.Ext(e.Name()) != ".yml" && filepath.Ext(e.Name()) != ".yaml" {
+		if !isConfigFile(e.Name()) {
 			continue
 		}
 
-		filePath := filepath.Join(principalsDirPath, e.Name())
-		yamlFile, err := os.ReadFile(filePath)
+		err = checkForDuplicateName(fileNames, "principal", principalsDirPath, e.Name())
 		if err != nil {
-			return nil, err
+			configErrors = append(configErrors, err.(*core.ConfigError))
+			continue
 		}
 
 		var principal core.Principal
 
-		err = yaml.Unmarshal(yamlFile, &principal)
+		source, err := unmarshalConfigFile(filepath.Join(principalsDirPath, e.Name()), &principal, variables)
 		if err != nil {
-			return nil, err
+			configErrors, err = appendConfigError(configErrors, err)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
 		}
 
-		if principal.Subscription == nil &&
+		if principal.Default == nil &&
+			principal.ManagementGroups == nil &&
+			principal.Profiles == nil &&
+			principal.Subscription == nil &&
 			principal.ResourceGroups == nil &&
 			principal.Resources == nil {
 			continue
 		}
 
 		principal.Name = strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		principal.Source = source
 
 		principals = append(principals, &principal)
 	}
 
-	return principals, err
+	return principals, configErrors, nil
+}
+
+func loadProfiles(profilesDirPath string, variables map[string]string) ([]*core.Profile, core.ConfigErrors, error) {
+	var profiles []*core.Profile
+	var configErrors core.ConfigErrors
+
+	if _, err := os.Stat(profilesDirPath); err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil, nil
+		}
+	}
+
+	entries, err := os.ReadDir(profilesDirPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileNames := map[string]string{}
+	for _, e := range entries {
+		if !isConfigFile(e.Name()) {
+			continue
+		}
+
+		err = checkForDuplicateName(fileNames, "profile", profilesDirPath, e.Name())
+		if err != nil {
+			configErrors = append(configErrors, err.(*core.ConfigError))
+			continue
+		}
+
+		var profile core.Profile
+
+		source, err := unmarshalConfigFile(filepath.Join(profilesDirPath, e.Name()), &profile, variables)
+		if err != nil {
+			configErrors, err = appendConfigError(configErrors, err)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		profile.Name = strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		profile.Source = source
+
+		profiles = append(profiles, &profile)
+	}
+
+	return profiles, configErrors, nil
 }
 
 func validateDirStructure(configDirPath string) []error {
@@ -185,11 +356,13 @@ func validateDirStructure(configDirPath string) []error {
 	}
 	for _, e := range entries {
 		if !e.IsDir() {
-			errors = append(errors, fmt.Errorf("unexpected file in config dir: %s", e.Name()))
+			if !slices.Contains(variableFileNames, e.Name()) {
+				errors = append(errors, fmt.Errorf("unexpected file in config dir: %s", e.Name()))
+			}
 			continue
 		}
 
-		if e.Name() == "groups" || e.Name() == "users" {
+		if e.Name() == "groups" || e.Name() == "profiles" || e.Name() == "roleDefinitions" || e.Name() == "servicePrincipals" || e.Name() == "users" {
 			entries, err := os.ReadDir(filepath.Join(configDirPath, e.Name()))
 			if err != nil {
 				return append(errors, err)
@@ -237,40 +410,75 @@ func validateDirStructure(configDirPath string) []error {
 	return errors
 }
 
-func Load(configDirPath string) (*core.AzureRmConfig, error) {
+// Load loads the config from the given directory, interpolating the given variables. Problems with
+// individual config files do not stop the remaining files from being loaded, and are returned
+// together as core.ConfigErrors.
+func Load(configDirPath string, variables map[string]string) (*core.AzureRmConfig, error) {
 	errors := validateDirStructure(configDirPath)
 	if len(errors) > 0 {
 		return nil, fmt.Errorf("invalid config dir structure: %v", errors)
 	}
 
-	groups, err := loadPrincipals(filepath.Join(configDirPath, "groups"))
+	var configErrors core.ConfigErrors
+
+	groups, groupConfigErrors, err := loadPrincipals(filepath.Join(configDirPath, "groups"), variables)
+	if err != nil {
+		return nil, err
+	}
+	configErrors = append(configErrors, groupConfigErrors...)
+
+	servicePrincipals, servicePrincipalConfigErrors, err := loadPrincipals(filepath.Join(configDirPath, "servicePrincipals"), variables)
+	if err != nil {
+		return nil, err
+	}
+	configErrors = append(configErrors, servicePrincipalConfigErrors...)
+
+	users, userConfigErrors, err := loadPrincipals(filepath.Join(configDirPath, "users"), variables)
 	if err != nil {
 		return nil, err
 	}
+	configErrors = append(configErrors, userConfigErrors...)
 
-	users, err := loadPrincipals(filepath.Join(configDirPath, "users"))
+	profiles, profileConfigErrors, err := loadProfiles(filepath.Join(configDirPath, "profiles"), variables)
 	if err != nil {
 		return nil, err
 	}
+	configErrors = append(configErrors, profileConfigErrors...)
 
-	roleManagementPolicyRulesets, err := loadRoleManagementPolicyRulesets(filepath.Join(configDirPath, "policies", "rulesets"))
+	roleDefinitions, roleDefinitionConfigErrors, err := loadRoleDefinitions(filepath.Join(configDirPath, "roleDefinitions"), variables)
 	if err != nil {
 		return nil, err
 	}
+	configErrors = append(configErrors, roleDefinitionConfigErrors...)
 
-	policies, err := loadPolicies(filepath.Join(configDirPath, "policies"))
+	roleManagementPolicyRulesets, rulesetConfigErrors, err := loadRoleManagementPolicyRulesets(filepath.Join(configDirPath, "policies", "rulesets"), variables)
 	if err != nil {
 		return nil, err
 	}
+	configErrors = append(configErrors, rulesetConfigErrors...)
+
+	policies, policyConfigErrors, err := loadPolicies(filepath.Join(configDirPath, "policies"), variables)
+	if err != nil {
+		return nil, err
+	}
+	configErrors = append(configErrors, policyConfigErrors...)
 
 	configurationData := core.AzureRmConfig{
-		Groups:   groups,
-		Policies: policies,
-		Rulesets: roleManagementPolicyRulesets,
-		Users:    users,
+		Groups:            groups,
+		Policies:          policies,
+		Profiles:          profiles,
+		RoleDefinitions:   roleDefinitions,
+		Rulesets:          roleManagementPolicyRulesets,
+		ServicePrincipals: servicePrincipals,
+		Users:             users,
+	}
+
+	if len(configErrors) > 0 {
+		core.SortConfigErrors(configErrors)
+		return &configurationData, configErrors
 	}
 
-	if len(configurationData.Groups) == 0 && len(configurationData.Users) == 0 {
+	if len(configurationData.Groups) == 0 && len(configurationData.RoleDefinitions) == 0 && len(configurationData.ServicePrincipals) == 0 && len(configurationData.Users) == 0 {
 		return &configurationData, &core.ConfigurationEmptyError{}
 	}
 
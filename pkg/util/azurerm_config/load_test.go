@@ -1 +1,27 @@
 package azurerm_config
+
+import "testing"
+
+func TestLoadKeepsPrincipalWithOnlyDefault(t *testing.T) {
+	configDirPath := writeFiles(t, map[string]string{
+		"groups/Auditors.yml":         "default:\n  eligible:\n    - roleName: Reader\n",
+		"users/alice@example.com.yml": "default:\n  active:\n    - roleName: Reader\n",
+	})
+
+	config, err := Load(configDirPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000001"
+
+	groupSchedules := config.GetGroupEligibilitySchedules(scope)
+	if len(groupSchedules) != 1 || groupSchedules[0].PrincipalName != "Auditors" || groupSchedules[0].Scope != scope {
+		t.Errorf("expected a Reader schedule for Auditors at %s, got %+v", scope, groupSchedules)
+	}
+
+	userSchedules := config.GetUserAssignmentSchedules(scope)
+	if len(userSchedules) != 1 || userSchedules[0].PrincipalName != "alice@example.com" || userSchedules[0].Scope != scope {
+		t.Errorf("expected a Reader schedule for alice@example.com at %s, got %+v", scope, userSchedules)
+	}
+}
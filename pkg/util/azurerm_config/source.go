@@ -0,0 +1,137 @@
+package azurerm_config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	lineRegex = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.+)$`)
+)
+
+type sourceBuilder struct {
+	configErrors core.ConfigErrors
+	source       *core.Source
+}
+
+// getSource walks the YAML node tree alongside the type it is unmarshalled into, recording
+// the position of every value against its path, e.g. ".resourceGroups[rg-dev].active[0]".
+// The paths match the namespaces used by the validator, minus the top level config struct.
+// Timestamps are also checked here, as the YAML decoder does not report where invalid ones are.
+func getSource(filePath string, node *yaml.Node, t reflect.Type) (*core.Source, core.ConfigErrors) {
+	builder := &sourceBuilder{
+		source: &core.Source{
+			FilePath:  filePath,
+			Positions: map[string]*core.SourcePosition{},
+		},
+	}
+
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	builder.addPositions("", node, node, t)
+
+	return builder.source, builder.configErrors
+}
+
+func (b *sourceBuilder) addPositions(path string, keyNode *yaml.Node, node *yaml.Node, t reflect.Type) {
+	b.source.Positions[path] = &core.SourcePosition{
+		Column: keyNode.Column,
+		Line:   keyNode.Line,
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) && node.Kind == yaml.ScalarNode {
+		var v time.Time
+		if err := node.Decode(&v); err != nil {
+			b.configErrors = append(b.configErrors, &core.ConfigError{
+				Column:   node.Column,
+				FilePath: b.source.FilePath,
+				Line:     node.Line,
+				Message:  fmt.Sprintf("%s is not a valid timestamp: %s", path[strings.LastIndex(path, ".")+1:], node.Value),
+			})
+		}
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k := node.Content[i]
+			v := node.Content[i+1]
+
+			switch t.Kind() {
+			case reflect.Struct:
+				if f, ok := getField(t, k.Value); ok {
+					b.addPositions(fmt.Sprintf("%s.%s", path, k.Value), k, v, f.Type)
+				}
+			case reflect.Map:
+				b.addPositions(fmt.Sprintf("%s[%s]", path, k.Value), k, v, t.Elem())
+			case reflect.Interface:
+				// Untyped values, e.g. ruleset patches, are addressed by their keys.
+				b.addPositions(fmt.Sprintf("%s.%s", path, k.Value), k, v, t)
+			}
+		}
+	case yaml.SequenceNode:
+		switch t.Kind() {
+		case reflect.Slice:
+			for i, v := range node.Content {
+				b.addPositions(fmt.Sprintf("%s[%d]", path, i), v, v, t.Elem())
+			}
+		case reflect.Interface:
+			for i, v := range node.Content {
+				b.addPositions(fmt.Sprintf("%s[%d]", path, i), v, v, t)
+			}
+		}
+	}
+}
+
+func getField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.Split(f.Tag.Get("yaml"), ",")[0] == name {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// getConfigErrors converts a YAML error into config errors. YAML errors only report the
+// line on which they occur, so the column is reported as the start of the line.
+func getConfigErrors(filePath string, err error) core.ConfigErrors {
+	var messages []string
+	if typeError, ok := err.(*yaml.TypeError); ok {
+		messages = typeError.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	var configErrors core.ConfigErrors
+	for _, m := range messages {
+		configError := &core.ConfigError{
+			FilePath: filePath,
+			Message:  strings.TrimPrefix(m, "yaml: "),
+		}
+
+		if groups := lineRegex.FindStringSubmatch(m); groups != nil {
+			configError.Line, _ = strconv.Atoi(groups[1])
+			configError.Column = 1
+			configError.Message = groups[2]
+		}
+
+		configErrors = append(configErrors, configError)
+	}
+
+	return configErrors
+}
@@ -0,0 +1 @@
+package azurerm_config
@@ -0,0 +1,135 @@
+package azurerm_config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	variableEnvPrefix = "SHERIFF_VAR_"
+)
+
+var (
+	variableFileNames = []string{"vars.json", "vars.yaml", "vars.yml"}
+	variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	variableRegex     = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// LoadVariables loads the variables that can be referenced as "${name}" in config files. In
+// increasing order of precedence, variables are read from a vars file in the config dir, the
+// given var files, SHERIFF_VAR_<name> environment variables and the given "<name>=<value>" pairs.
+func LoadVariables(configDirPath string, varFilePaths []string, vars []string) (map[string]string, error) {
+	variables := map[string]string{}
+
+	var filePaths []string
+	for _, n := range variableFileNames {
+		filePath := filepath.Join(configDirPath, n)
+		if _, err := os.Stat(filePath); err == nil {
+			filePaths = append(filePaths, filePath)
+		}
+	}
+	if len(filePaths) > 1 {
+		return nil, fmt.Errorf("multiple vars files found in config dir: %s", strings.Join(filePaths, ", "))
+	}
+	filePaths = append(filePaths, varFilePaths...)
+
+	for _, filePath := range filePaths {
+		err := loadVariablesFile(filePath, variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range os.Environ() {
+		name, value, _ := strings.Cut(e, "=")
+		if strings.HasPrefix(name, variableEnvPrefix) {
+			variables[strings.TrimPrefix(name, variableEnvPrefix)] = value
+		}
+	}
+
+	for _, v := range vars {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || !variableNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable \"%s\", expected <name>=<value>", v)
+		}
+
+		variables[name] = value
+	}
+
+	return variables, nil
+}
+
+func loadVariablesFile(filePath string, variables map[string]string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	var fileVariables map[string]string
+	err = yaml.Unmarshal(data, &fileVariables)
+	if err != nil {
+		return getConfigErrors(filePath, err)
+	}
+
+	for name, value := range fileVariables {
+		if !variableNameRegex.MatchString(name) {
+			return &core.ConfigError{
+				FilePath: filePath,
+				Message:  fmt.Sprintf("invalid variable name \"%s\"", name),
+			}
+		}
+
+		variables[name] = value
+	}
+
+	return nil
+}
+
+// interpolateVariables replaces references to variables in the keys and values of the node, returning
+// an error for each reference to a variable that is not defined. "$${" is an escaped "${".
+func interpolateVariables(filePath string, node *yaml.Node, variables map[string]string) core.ConfigErrors {
+	var configErrors core.ConfigErrors
+
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${") {
+		value := variableRegex.ReplaceAllStringFunc(node.Value, func(m string) string {
+			if m == "$${" {
+				return "${"
+			}
+
+			name := m[2 : len(m)-1]
+			if v, ok := variables[name]; ok {
+				return v
+			}
+
+			configErrors = append(configErrors, &core.ConfigError{
+				Column:   node.Column,
+				FilePath: filePath,
+				Line:     node.Line,
+				Message:  fmt.Sprintf("variable \"%s\" is not defined", name),
+			})
+
+			return m
+		})
+
+		if value != node.Value {
+			node.Value = value
+
+			// Re-resolve the tag of unquoted values, so that e.g. timestamps are decoded as such.
+			if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				node.Tag = ""
+			}
+		}
+	}
+
+	for _, n := range node.Content {
+		configErrors = append(configErrors, interpolateVariables(filePath, n, variables)...)
+	}
+
+	return configErrors
+}
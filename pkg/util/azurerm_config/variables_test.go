@@ -0,0 +1,158 @@
+package azurerm_config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes the given files to a temporary dir and returns its path.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dirPath := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dirPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dirPath
+}
+
+func TestLoadVariablesPrecedence(t *testing.T) {
+	configDirPath := writeFiles(t, map[string]string{
+		"vars.yml": "a: vars-file\nb: vars-file\nc: vars-file\nd: vars-file\n",
+	})
+	varFilesDirPath := writeFiles(t, map[string]string{
+		"first.yml":  "b: first-var-file\nc: first-var-file\nd: first-var-file\ne: first-var-file\n",
+		"second.yml": "e: second-var-file\n",
+	})
+
+	t.Setenv("SHERIFF_VAR_c", "env")
+	t.Setenv("SHERIFF_VAR_d", "env")
+
+	variables, err := LoadVariables(
+		configDirPath,
+		[]string{filepath.Join(varFilesDirPath, "first.yml"), filepath.Join(varFilesDirPath, "second.yml")},
+		[]string{"d=var", "f=a=b"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"a": "vars-file",
+		"b": "first-var-file",
+		"c": "env",
+		"d": "var",
+		"e": "second-var-file",
+		"f": "a=b",
+	}
+	for name, value := range expected {
+		if variables[name] != value {
+			t.Errorf("expected variable %s to be %q, got %q", name, value, variables[name])
+		}
+	}
+}
+
+func TestLoadVariablesRejectsInvalidVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		vars     []string
+		expected string
+	}{
+		{
+			name:     "var without value",
+			vars:     []string{"name"},
+			expected: "invalid variable \"name\", expected <name>=<value>",
+		},
+		{
+			name:     "var with invalid name",
+			vars:     []string{"1name=value"},
+			expected: "invalid variable \"1name=value\", expected <name>=<value>",
+		},
+		{
+			name:     "vars file with invalid name",
+			files:    map[string]string{"vars.yml": "my-name: value\n"},
+			expected: "invalid variable name \"my-name\"",
+		},
+		{
+			name:     "multiple vars files",
+			files:    map[string]string{"vars.yml": "a: b\n", "vars.json": "{\"a\": \"b\"}\n"},
+			expected: "multiple vars files found in config dir",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDirPath := writeFiles(t, tt.files)
+
+			_, err := LoadVariables(configDirPath, nil, tt.vars)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadInterpolatesVariables(t *testing.T) {
+	configDirPath := writeFiles(t, map[string]string{
+		"groups/Engineers.yml":           "subscription:\n  active:\n    - roleName: ${role}\n      justification: Cost is $${cost}\n",
+		"policies/Reader.yml":            "subscription:\n  - rulesetName: ${ruleset}\n",
+		"policies/rulesets/baseline.yml": "rules:\n  - id: Expiration_Admin_Eligibility\n    patch:\n      maximumDuration: ${max_duration}\n",
+	})
+
+	config, err := Load(configDirPath, map[string]string{
+		"max_duration": "P90D",
+		"role":         "Reader",
+		"ruleset":      "baseline",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedule := config.Groups[0].Subscription.Active[0]
+	if schedule.RoleName != "Reader" {
+		t.Errorf("expected role name to be interpolated, got %q", schedule.RoleName)
+	}
+	if schedule.Justification != "Cost is ${cost}" {
+		t.Errorf("expected escaped reference to be kept, got %q", schedule.Justification)
+	}
+
+	if rulesetName := config.Policies[0].Subscription[0].RulesetName; rulesetName != "baseline" {
+		t.Errorf("expected ruleset name in policy to be interpolated, got %q", rulesetName)
+	}
+
+	patch := config.Rulesets[0].Rules[0].Patch.(map[string]interface{})
+	if patch["maximumDuration"] != "P90D" {
+		t.Errorf("expected patch in ruleset to be interpolated, got %v", patch["maximumDuration"])
+	}
+}
+
+func TestLoadReportsUndefinedVariables(t *testing.T) {
+	configDirPath := writeFiles(t, map[string]string{
+		"groups/Engineers.yml":           "subscription:\n  active:\n    - roleName: ${role}\n",
+		"policies/rulesets/baseline.yml": "rules:\n  - id: Expiration_Admin_Eligibility\n    patch:\n      maximumDuration: ${max_duration}\n",
+	})
+
+	_, err := Load(configDirPath, map[string]string{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, expected := range []string{
+		filepath.Join(configDirPath, "groups", "Engineers.yml") + ":3:17: variable \"role\" is not defined",
+		filepath.Join(configDirPath, "policies", "rulesets", "baseline.yml") + ":4:24: variable \"max_duration\" is not defined",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error containing %q, got %v", expected, err)
+		}
+	}
+}
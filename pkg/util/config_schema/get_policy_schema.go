@@ -0,0 +1,15 @@
+package config_schema
+
+import (
+	"reflect"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+func GetPolicySchema() *Schema {
+	schema := fromType(reflect.TypeOf(core.Policy{}), configFieldName)
+	schema.Schema = draft
+	schema.Title = "Sheriff policy"
+
+	return schema
+}
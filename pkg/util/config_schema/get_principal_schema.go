@@ -0,0 +1,15 @@
+package config_schema
+
+import (
+	"reflect"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+func GetPrincipalSchema() *Schema {
+	schema := fromType(reflect.TypeOf(core.Principal{}), configFieldName)
+	schema.Schema = draft
+	schema.Title = "Sheriff principal"
+
+	return schema
+}
@@ -0,0 +1,15 @@
+package config_schema
+
+import (
+	"reflect"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+func GetProfileSchema() *Schema {
+	schema := fromType(reflect.TypeOf(core.Profile{}), configFieldName)
+	schema.Schema = draft
+	schema.Title = "Sheriff profile"
+
+	return schema
+}
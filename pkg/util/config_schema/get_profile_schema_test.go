@@ -0,0 +1 @@
+package config_schema
@@ -0,0 +1,15 @@
+package config_schema
+
+import (
+	"reflect"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+func GetRoleDefinitionSchema() *Schema {
+	schema := fromType(reflect.TypeOf(core.RoleDefinition{}), configFieldName)
+	schema.Schema = draft
+	schema.Title = "Sheriff role definition"
+
+	return schema
+}
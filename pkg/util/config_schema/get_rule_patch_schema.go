@@ -0,0 +1,31 @@
+package config_schema
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+)
+
+var ruleTypes = map[armauthorization.RoleManagementPolicyRuleType]reflect.Type{
+	armauthorization.RoleManagementPolicyRuleTypeRoleManagementPolicyApprovalRule:              reflect.TypeOf(armauthorization.RoleManagementPolicyApprovalRule{}),
+	armauthorization.RoleManagementPolicyRuleTypeRoleManagementPolicyAuthenticationContextRule: reflect.TypeOf(armauthorization.RoleManagementPolicyAuthenticationContextRule{}),
+	armauthorization.RoleManagementPolicyRuleTypeRoleManagementPolicyEnablementRule:            reflect.TypeOf(armauthorization.RoleManagementPolicyEnablementRule{}),
+	armauthorization.RoleManagementPolicyRuleTypeRoleManagementPolicyExpirationRule:            reflect.TypeOf(armauthorization.RoleManagementPolicyExpirationRule{}),
+	armauthorization.RoleManagementPolicyRuleTypeRoleManagementPolicyNotificationRule:          reflect.TypeOf(armauthorization.RoleManagementPolicyNotificationRule{}),
+}
+
+// GetRulePatchSchema returns the schema of a patch for a role management policy rule of the given type.
+// The Id and rule type identify the rule and so cannot be patched.
+func GetRulePatchSchema(ruleType armauthorization.RoleManagementPolicyRuleType) (*Schema, error) {
+	t, ok := ruleTypes[ruleType]
+	if !ok {
+		return nil, fmt.Errorf("unknown rule type '%s'", ruleType)
+	}
+
+	schema := fromType(t, sdkFieldName)
+	delete(schema.Properties, "id")
+	delete(schema.Properties, "ruleType")
+
+	return schema, nil
+}
@@ -0,0 +1,58 @@
+package config_schema
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/gofrontier-com/sheriff/pkg/util/role_management_policy_classification_rule"
+)
+
+func GetRulesetSchema(defaultRoleManagementPolicyPropertiesData string) (*Schema, error) {
+	var defaultRoleManagementPolicyProperties armauthorization.RoleManagementPolicyProperties
+	err := defaultRoleManagementPolicyProperties.UnmarshalJSON([]byte(defaultRoleManagementPolicyPropertiesData))
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(
+		defaultRoleManagementPolicyProperties.Rules,
+		role_management_policy_classification_rule.SortByID,
+	)
+
+	schema := fromType(reflect.TypeOf(core.RoleManagementPolicyRuleset{}), configFieldName)
+	schema.Schema = draft
+	schema.Title = "Sheriff role management policy ruleset"
+
+	ruleSchema := schema.Properties["rules"].Items
+	ruleSchema.Properties["patch"] = &Schema{Type: "object"}
+
+	var ruleIds []interface{}
+	for _, r := range defaultRoleManagementPolicyProperties.Rules {
+		rule := r.GetRoleManagementPolicyRule()
+
+		patchSchema, err := GetRulePatchSchema(*rule.RuleType)
+		if err != nil {
+			return nil, err
+		}
+
+		ruleIds = append(ruleIds, *rule.ID)
+		ruleSchema.AllOf = append(ruleSchema.AllOf, &Schema{
+			If: &Schema{
+				Properties: map[string]*Schema{
+					"id": {Const: *rule.ID},
+				},
+			},
+			Then: &Schema{
+				Properties: map[string]*Schema{
+					"patch": patchSchema,
+				},
+			},
+		})
+	}
+
+	ruleSchema.Properties["id"] = &Schema{Type: "string", Enum: ruleIds}
+
+	return schema, nil
+}
@@ -0,0 +1,152 @@
+package config_schema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+)
+
+const (
+	draft = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// Schema is a JSON Schema document, limited to the keywords that Sheriff needs.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	If                   *Schema            `json:"if,omitempty"`
+	Then                 *Schema            `json:"then,omitempty"`
+}
+
+var enumValues = map[reflect.Type][]interface{}{
+	reflect.TypeOf(armauthorization.ApprovalMode("")):                  toInterfaces(armauthorization.PossibleApprovalModeValues()),
+	reflect.TypeOf(armauthorization.EnablementRules("")):               toInterfaces(armauthorization.PossibleEnablementRulesValues()),
+	reflect.TypeOf(armauthorization.NotificationDeliveryMechanism("")): toInterfaces(armauthorization.PossibleNotificationDeliveryMechanismValues()),
+	reflect.TypeOf(armauthorization.NotificationLevel("")):             toInterfaces(armauthorization.PossibleNotificationLevelValues()),
+	reflect.TypeOf(armauthorization.RecipientType("")):                 toInterfaces(armauthorization.PossibleRecipientTypeValues()),
+	reflect.TypeOf(armauthorization.UserType("")):                      toInterfaces(armauthorization.PossibleUserTypeValues()),
+}
+
+// formats are the formats of string fields, by field name.
+var formats = map[string]string{
+	"Duration":        "duration",
+	"MaximumDuration": "duration",
+	"RenewalWindow":   "duration",
+}
+
+func toInterfaces[T any](values []T) []interface{} {
+	var interfaces []interface{}
+	for _, v := range values {
+		interfaces = append(interfaces, v)
+	}
+	return interfaces
+}
+
+// configFieldName returns the name of a config struct field, as defined by its json tag.
+// Fields without a json tag are not read from config files and are excluded.
+func configFieldName(f reflect.StructField) (string, bool) {
+	tag, ok := f.Tag.Lookup("json")
+	if !ok {
+		return "", false
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == "" || name == "-" {
+		return "", false
+	}
+
+	return name, true
+}
+
+// sdkFieldName returns the name of an Azure SDK model struct field. The SDK models are serialised
+// with custom marshallers rather than tags, using the field name in lower camel case.
+func sdkFieldName(f reflect.StructField) (string, bool) {
+	runes := []rune(f.Name)
+	for i := range runes {
+		if i > 0 && i < len(runes)-1 && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes), true
+}
+
+func fromType(t reflect.Type, fieldName func(reflect.StructField) (string, bool)) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if values, ok := enumValues[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: fromType(t.Elem(), fieldName)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: fromType(t.Elem(), fieldName)}
+	case reflect.Struct:
+		schema := &Schema{
+			Type:                 "object",
+			Properties:           map[string]*Schema{},
+			AdditionalProperties: false,
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			name, ok := fieldName(f)
+			if !ok {
+				continue
+			}
+
+			schema.Properties[name] = fromType(f.Type, fieldName)
+			if format, ok := formats[f.Name]; ok && schema.Properties[name].Type == "string" {
+				schema.Properties[name].Format = format
+			}
+
+			if strings.Contains(f.Tag.Get("validate"), "required") {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+
+		return schema
+	}
+
+	// Anything else, e.g. interface{}, can hold any value.
+	return &Schema{}
+}
@@ -0,0 +1,153 @@
+package config_schema
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+// ValidationError is a value that does not conform to a schema, at the given path within the
+// validated value, e.g. ".setting.isApprovalRequired".
+type ValidationError struct {
+	Message string
+	Path    string
+}
+
+// Validate validates a value decoded from a config file against the schema. Only the keywords
+// generated by Sheriff are supported, and null is accepted for any value, as it removes the value
+// from the result of a merge patch.
+func (s *Schema) Validate(value interface{}) []*ValidationError {
+	return s.validate("", value)
+}
+
+func (s *Schema) validate(path string, value interface{}) []*ValidationError {
+	if value == nil {
+		return nil
+	}
+
+	name := path[strings.LastIndexAny(path, ".[")+1:]
+	name = strings.TrimSuffix(name, "]")
+	if name == "" {
+		name = "value"
+	}
+
+	if s.Type != "" && !isType(value, s.Type) {
+		return []*ValidationError{{
+			Message: fmt.Sprintf("%s must be of type %s", name, s.Type),
+			Path:    path,
+		}}
+	}
+
+	if len(s.Enum) > 0 {
+		matches := slices.ContainsFunc(s.Enum, func(e interface{}) bool {
+			return fmt.Sprint(e) == fmt.Sprint(value)
+		})
+		if !matches {
+			values := make([]string, len(s.Enum))
+			for i, e := range s.Enum {
+				values[i] = fmt.Sprint(e)
+			}
+
+			return []*ValidationError{{
+				Message: fmt.Sprintf("%s must be one of %s", name, strings.Join(values, ", ")),
+				Path:    path,
+			}}
+		}
+	}
+
+	switch s.Format {
+	case "duration":
+		if _, err := core.ParseDuration(fmt.Sprint(value)); err != nil {
+			return []*ValidationError{{
+				Message: fmt.Sprintf("%s is not a valid ISO 8601 duration: %v", name, value),
+				Path:    path,
+			}}
+		}
+	case "date-time":
+		if _, ok := value.(time.Time); !ok {
+			if _, err := time.Parse(time.RFC3339, fmt.Sprint(value)); err != nil {
+				return []*ValidationError{{
+					Message: fmt.Sprintf("%s is not a valid timestamp: %v", name, value),
+					Path:    path,
+				}}
+			}
+		}
+	}
+
+	var validationErrors []*ValidationError
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			propertyPath := fmt.Sprintf("%s.%s", path, k)
+
+			if p, ok := s.Properties[k]; ok {
+				validationErrors = append(validationErrors, p.validate(propertyPath, v[k])...)
+				continue
+			}
+
+			switch a := s.AdditionalProperties.(type) {
+			case bool:
+				if !a {
+					validationErrors = append(validationErrors, &ValidationError{
+						Message: fmt.Sprintf("unknown field %s", k),
+						Path:    propertyPath,
+					})
+				}
+			case *Schema:
+				validationErrors = append(validationErrors, a.validate(fmt.Sprintf("%s[%s]", path, k), v[k])...)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				validationErrors = append(validationErrors, s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+			}
+		}
+	}
+
+	return validationErrors
+}
+
+func isType(value interface{}, t string) bool {
+	switch t {
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch v := value.(type) {
+		case int, int64, uint64:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return false
+	case "number":
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			return true
+		}
+		return false
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	}
+
+	return true
+}
@@ -0,0 +1,78 @@
+package config_schema
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+// ValidateRulesets checks that every rule in the given rulesets exists in the default role
+// management policy, and that every patch conforms to the schema of the rule's type. No requests
+// are made to Azure.
+func ValidateRulesets(rulesets []*core.RoleManagementPolicyRuleset, defaultRoleManagementPolicyPropertiesData string) (core.ConfigErrors, error) {
+	var defaultRoleManagementPolicyProperties armauthorization.RoleManagementPolicyProperties
+	err := defaultRoleManagementPolicyProperties.UnmarshalJSON([]byte(defaultRoleManagementPolicyPropertiesData))
+	if err != nil {
+		return nil, err
+	}
+
+	ruleTypes := map[string]armauthorization.RoleManagementPolicyRuleType{}
+	for _, r := range defaultRoleManagementPolicyProperties.Rules {
+		rule := r.GetRoleManagementPolicyRule()
+		ruleTypes[*rule.ID] = *rule.RuleType
+	}
+
+	var configErrors core.ConfigErrors
+
+	newConfigError := func(source *core.Source, path string, message string) *core.ConfigError {
+		configError := &core.ConfigError{
+			Message: message,
+		}
+
+		if source != nil {
+			position := source.GetPosition(path)
+			configError.FilePath = source.FilePath
+			configError.Line = position.Line
+			configError.Column = position.Column
+		}
+
+		return configError
+	}
+
+	for _, s := range rulesets {
+		for i, r := range s.Rules {
+			if r.ID == "" {
+				continue
+			}
+
+			ruleType, ok := ruleTypes[r.ID]
+			if !ok {
+				configErrors = append(configErrors, newConfigError(s.Source, fmt.Sprintf(".rules[%d].id", i), fmt.Sprintf("rule %s not found in the default role management policy", r.ID)))
+				continue
+			}
+
+			if r.Patch == nil {
+				continue
+			}
+
+			if _, ok := r.Patch.(map[string]interface{}); !ok {
+				configErrors = append(configErrors, newConfigError(s.Source, fmt.Sprintf(".rules[%d].patch", i), "patch must be of type object"))
+				continue
+			}
+
+			patchSchema, err := GetRulePatchSchema(ruleType)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, e := range patchSchema.Validate(r.Patch) {
+				configErrors = append(configErrors, newConfigError(s.Source, fmt.Sprintf(".rules[%d].patch%s", i, e.Path), e.Message))
+			}
+		}
+	}
+
+	core.SortConfigErrors(configErrors)
+
+	return configErrors, nil
+}
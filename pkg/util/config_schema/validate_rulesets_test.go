@@ -0,0 +1,126 @@
+package config_schema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+const testDefaultRoleManagementPolicyPropertiesData = `{
+  "rules": [
+    {
+      "id": "Expiration_Admin_Eligibility",
+      "isExpirationRequired": true,
+      "maximumDuration": "P365D",
+      "ruleType": "RoleManagementPolicyExpirationRule"
+    },
+    {
+      "enabledRules": [],
+      "id": "Enablement_EndUser_Assignment",
+      "ruleType": "RoleManagementPolicyEnablementRule"
+    }
+  ]
+}`
+
+func TestValidateRulesets(t *testing.T) {
+	source := &core.Source{
+		FilePath: "rulesets/default.yml",
+		Positions: map[string]*core.SourcePosition{
+			"":                                     {Line: 1, Column: 1},
+			".rules[0].id":                         {Line: 3, Column: 9},
+			".rules[0].patch":                      {Line: 5, Column: 7},
+			".rules[0].patch.maximumDuration":      {Line: 5, Column: 24},
+			".rules[1].id":                         {Line: 6, Column: 9},
+			".rules[1].patch":                      {Line: 8, Column: 7},
+			".rules[1].patch.isExpirationRequired": {Line: 8, Column: 29},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		rules    []*core.RoleManagementPolicyRule
+		expected core.ConfigErrors
+	}{
+		{
+			name: "valid ruleset",
+			rules: []*core.RoleManagementPolicyRule{
+				{ID: "Expiration_Admin_Eligibility", Patch: map[string]interface{}{"maximumDuration": "P90D"}},
+				{ID: "Enablement_EndUser_Assignment", Patch: map[string]interface{}{"enabledRules": []interface{}{"Justification"}}},
+			},
+		},
+		{
+			name: "unknown rule Id",
+			rules: []*core.RoleManagementPolicyRule{
+				{ID: "Expiration_Admin_Eligibility", Patch: map[string]interface{}{"maximumDuration": "P90D"}},
+				{ID: "Expiration_Unknown", Patch: map[string]interface{}{"isExpirationRequired": false}},
+			},
+			expected: core.ConfigErrors{
+				{Column: 9, FilePath: "rulesets/default.yml", Line: 6, Message: "rule Expiration_Unknown not found in the default role management policy"},
+			},
+		},
+		{
+			name: "unknown patch key on known rule",
+			rules: []*core.RoleManagementPolicyRule{
+				{ID: "Expiration_Admin_Eligibility", Patch: map[string]interface{}{"maximumDuration": "P90D"}},
+				{ID: "Enablement_EndUser_Assignment", Patch: map[string]interface{}{"isExpirationRequired": false}},
+			},
+			expected: core.ConfigErrors{
+				{Column: 29, FilePath: "rulesets/default.yml", Line: 8, Message: "unknown field isExpirationRequired"},
+			},
+		},
+		{
+			name: "invalid patch value",
+			rules: []*core.RoleManagementPolicyRule{
+				{ID: "Expiration_Admin_Eligibility", Patch: map[string]interface{}{"maximumDuration": "90 days"}},
+			},
+			expected: core.ConfigErrors{
+				{Column: 24, FilePath: "rulesets/default.yml", Line: 5, Message: "maximumDuration is not a valid ISO 8601 duration: 90 days"},
+			},
+		},
+		{
+			name: "patch is not an object",
+			rules: []*core.RoleManagementPolicyRule{
+				{ID: "Expiration_Admin_Eligibility", Patch: "P90D"},
+			},
+			expected: core.ConfigErrors{
+				{Column: 7, FilePath: "rulesets/default.yml", Line: 5, Message: "patch must be of type object"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rulesets := []*core.RoleManagementPolicyRuleset{
+				{Name: "default", Rules: tt.rules, Source: source},
+			}
+
+			configErrors, err := ValidateRulesets(rulesets, testDefaultRoleManagementPolicyPropertiesData)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(configErrors, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, configErrors)
+			}
+		})
+	}
+}
+
+func TestValidateRulesetsWithoutSource(t *testing.T) {
+	rulesets := []*core.RoleManagementPolicyRuleset{
+		{Name: "default", Rules: []*core.RoleManagementPolicyRule{{ID: "Expiration_Unknown"}}},
+	}
+
+	configErrors, err := ValidateRulesets(rulesets, testDefaultRoleManagementPolicyPropertiesData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := core.ConfigErrors{
+		{Message: "rule Expiration_Unknown not found in the default role management policy"},
+	}
+	if !reflect.DeepEqual(configErrors, expected) {
+		t.Errorf("expected %v, got %v", expected, configErrors)
+	}
+}
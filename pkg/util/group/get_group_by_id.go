@@ -2,24 +2,24 @@ package group
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
 
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
 	gocache "github.com/patrickmn/go-cache"
 )
 
-func GetGroupById(graphServiceClient *msgraphsdkgo.GraphServiceClient, groupId string) (models.Groupable, error) {
+func GetGroupById(ctx context.Context, graphClient backend.GraphClient, groupId string) (models.Groupable, error) {
 	var group models.Groupable
 	cacheKey := fmt.Sprintf("id::%s", groupId)
 
 	if g, found := cache.Get(cacheKey); found {
 		group = g.(models.Groupable)
 	} else {
-		result, err := graphServiceClient.Groups().ByGroupId(groupId).Get(context.Background(), nil)
+		result, err := graphClient.GetGroupById(ctx, groupId)
 		if err != nil {
-			if strings.HasPrefix(err.Error(), fmt.Sprintf("Resource '%s' does not exist", groupId)) {
+			if errors.Is(err, backend.ErrNotFound) {
 				return nil, fmt.Errorf("group with Id \"%s\" not found", groupId)
 			} else {
 				return nil, err
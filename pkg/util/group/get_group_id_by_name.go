@@ -0,0 +1,15 @@
+package group
+
+import (
+	"context"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+)
+
+func GetGroupIdByName(ctx context.Context, graphClient backend.GraphClient, groupName string) (*string, error) {
+	group, err := GetGroupByName(ctx, graphClient, groupName)
+	if err != nil {
+		return nil, err
+	}
+	return group.GetId(), nil
+}
@@ -0,0 +1,55 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// PrefetchGroupsByIds looks up, in bulk, the groups with the given object Ids that are not already cached,
+// and caches them. Groups that are not found are returned as principal errors.
+func PrefetchGroupsByIds(ctx context.Context, graphClient backend.GraphClient, groupIds []string) (core.PrincipalErrors, error) {
+	var uncachedGroupIds []string
+	for _, id := range groupIds {
+		if _, found := cache.Get(fmt.Sprintf("id::%s", id)); !found && !slices.Contains(uncachedGroupIds, id) {
+			uncachedGroupIds = append(uncachedGroupIds, id)
+		}
+	}
+
+	if len(uncachedGroupIds) == 0 {
+		return nil, nil
+	}
+
+	groups, err := graphClient.ListGroupsByIds(ctx, uncachedGroupIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var principalErrors core.PrincipalErrors
+	for _, id := range uncachedGroupIds {
+		index := slices.IndexFunc(groups, func(g models.Groupable) bool {
+			return strings.EqualFold(*g.GetId(), id)
+		})
+		if index == -1 {
+			principalErrors = append(principalErrors, fmt.Errorf("group with Id \"%s\" not found", id))
+			continue
+		}
+
+		group := groups[index]
+		cacheKeys := []string{
+			fmt.Sprintf("id::%s", id),
+			fmt.Sprintf("name::%s", *group.GetDisplayName()),
+		}
+		for _, cacheKey := range cacheKeys {
+			cache.Set(cacheKey, group, gocache.NoExpiration)
+		}
+	}
+
+	return principalErrors, nil
+}
@@ -0,0 +1,65 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// PrefetchGroupsByNames looks up, in bulk, the groups with the given display names that are not already
+// cached, and caches them. Names that are not found, or that match more than one group, are returned as
+// principal errors.
+func PrefetchGroupsByNames(ctx context.Context, graphClient backend.GraphClient, groupNames []string) (core.PrincipalErrors, error) {
+	var uncachedGroupNames []string
+	for _, n := range groupNames {
+		if _, found := cache.Get(fmt.Sprintf("name::%s", n)); !found && !slices.Contains(uncachedGroupNames, n) {
+			uncachedGroupNames = append(uncachedGroupNames, n)
+		}
+	}
+
+	if len(uncachedGroupNames) == 0 {
+		return nil, nil
+	}
+
+	groups, err := graphClient.ListGroupsByNames(ctx, uncachedGroupNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var principalErrors core.PrincipalErrors
+	for _, n := range uncachedGroupNames {
+		var matches []models.Groupable
+		for _, g := range groups {
+			if strings.EqualFold(*g.GetDisplayName(), n) {
+				matches = append(matches, g)
+			}
+		}
+
+		if len(matches) == 0 {
+			principalErrors = append(principalErrors, fmt.Errorf("group with display name \"%s\" not found", n))
+			continue
+		}
+
+		if len(matches) > 1 {
+			principalErrors = append(principalErrors, fmt.Errorf("multiple groups with display name \"%s\" found", n))
+			continue
+		}
+
+		group := matches[0]
+		cacheKeys := []string{
+			fmt.Sprintf("name::%s", n),
+			fmt.Sprintf("id::%s", *group.GetId()),
+		}
+		for _, cacheKey := range cacheKeys {
+			cache.Set(cacheKey, group, gocache.NoExpiration)
+		}
+	}
+
+	return principalErrors, nil
+}
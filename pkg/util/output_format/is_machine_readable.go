@@ -0,0 +1,7 @@
+package output_format
+
+// IsMachineReadable determines whether an output format is a machine-readable one, in which case only the
+// document is written to stdout.
+func IsMachineReadable(outputFormat string) bool {
+	return outputFormat == Json || outputFormat == Yaml
+}
@@ -0,0 +1,25 @@
+package output_format
+
+import (
+	"io"
+	"os"
+)
+
+const (
+	// Text is the human-readable output format.
+	Text = "text"
+
+	// Json is the machine-readable JSON output format.
+	Json = "json"
+
+	// Yaml is the machine-readable YAML output format.
+	Yaml = "yaml"
+)
+
+// Annotation marks a command whose --output flag sets its output format, so that human-readable output,
+// including the banner, is redirected to stderr before the command runs when a machine-readable format is set.
+const Annotation = "sheriff/output-format"
+
+// documentOutput is where machine-readable documents are written. It is stdout as it was at startup, so that
+// documents are still written to stdout once human-readable output has been redirected to stderr.
+var documentOutput io.Writer = os.Stdout
@@ -0,0 +1 @@
+package output_format
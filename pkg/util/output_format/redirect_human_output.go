@@ -0,0 +1,13 @@
+package output_format
+
+import (
+	"os"
+
+	"github.com/fatih/color"
+)
+
+// RedirectHumanOutput redirects human-readable output, which the output package writes to stdout, to stderr.
+func RedirectHumanOutput() {
+	os.Stdout = os.Stderr
+	color.Output = os.Stderr
+}
@@ -0,0 +1,13 @@
+package output_format
+
+import "fmt"
+
+// Validate checks that an output format is one that Sheriff supports.
+func Validate(outputFormat string) error {
+	switch outputFormat {
+	case Text, Json, Yaml:
+		return nil
+	default:
+		return fmt.Errorf("--output must be one of %s, %s or %s", Text, Json, Yaml)
+	}
+}
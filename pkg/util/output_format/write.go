@@ -0,0 +1,36 @@
+package output_format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Write writes a document to stdout in the given machine-readable output format.
+func Write(outputFormat string, document any) error {
+	return write(documentOutput, outputFormat, document)
+}
+
+func write(w io.Writer, outputFormat string, document any) error {
+	var data []byte
+	var err error
+	switch outputFormat {
+	case Json:
+		data, err = json.MarshalIndent(document, "", "  ")
+		if err == nil {
+			data = append(data, '\n')
+		}
+	case Yaml:
+		data, err = yaml.Marshal(document)
+	default:
+		return fmt.Errorf("output format '%s' is not machine-readable", outputFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
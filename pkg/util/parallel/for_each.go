@@ -0,0 +1,48 @@
+package parallel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// ForEach calls fn for each index in [0, count), running at most GetLimit(ctx) calls at a time. Indexes are
+// started in order, and no more are started once ctx is cancelled or a call fails, though calls that have
+// already started are left to complete. The error of the failed call with the lowest index is returned, so
+// the result does not depend on how the calls were scheduled.
+func ForEach(ctx context.Context, count int, fn func(ctx context.Context, i int) error) error {
+	errs := make([]error, count)
+	semaphore := make(chan struct{}, GetLimit(ctx))
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+
+	for i := 0; i < count; i++ {
+		semaphore <- struct{}{}
+
+		if failed.Load() || ctx.Err() != nil {
+			<-semaphore
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if err := fn(ctx, i); err != nil {
+				errs[i] = err
+				failed.Store(true)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,91 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachRunsEveryIndex(t *testing.T) {
+	results := make([]int, 100)
+
+	err := ForEach(WithLimit(context.Background(), 8), len(results), func(ctx context.Context, i int) error {
+		results[i] = i * i
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, r := range results {
+		if r != i*i {
+			t.Fatalf("expected result %d to be %d, got %d", i, i*i, r)
+		}
+	}
+}
+
+func TestForEachRespectsLimit(t *testing.T) {
+	var running, maxRunning atomic.Int32
+
+	err := ForEach(WithLimit(context.Background(), 3), 20, func(ctx context.Context, i int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m := maxRunning.Load(); m > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", m)
+	}
+}
+
+func TestForEachReturnsErrorWithLowestIndex(t *testing.T) {
+	err := ForEach(WithLimit(context.Background(), 4), 4, func(ctx context.Context, i int) error {
+		// Later indexes fail first.
+		time.Sleep(time.Duration(4-i) * time.Millisecond)
+		if i >= 1 {
+			return fmt.Errorf("error %d", i)
+		}
+
+		return nil
+	})
+	if err == nil || err.Error() != "error 1" {
+		t.Errorf("expected error 1, got %v", err)
+	}
+}
+
+func TestForEachStopsStartingCallsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+
+	err := ForEach(ctx, 10, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		if i == 2 {
+			cancel()
+		}
+
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if c := calls.Load(); c != 3 {
+		t.Errorf("expected 3 calls, got %d", c)
+	}
+}
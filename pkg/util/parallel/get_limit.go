@@ -0,0 +1,12 @@
+package parallel
+
+import "context"
+
+// GetLimit gets the maximum number of calls that ForEach runs at a time, which is 1 unless set with WithLimit.
+func GetLimit(ctx context.Context) int {
+	if limit, ok := ctx.Value(limitKey{}).(int); ok && limit > 0 {
+		return limit
+	}
+
+	return 1
+}
@@ -0,0 +1,4 @@
+package parallel
+
+// limitKey is the context key for the maximum number of calls that ForEach runs at a time.
+type limitKey struct{}
@@ -0,0 +1,8 @@
+package parallel
+
+import "context"
+
+// WithLimit returns a copy of ctx in which ForEach runs at most limit calls at a time.
+func WithLimit(ctx context.Context, limit int) context.Context {
+	return context.WithValue(ctx, limitKey{}, limit)
+}
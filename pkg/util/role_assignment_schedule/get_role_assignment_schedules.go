@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	gocache "github.com/patrickmn/go-cache"
 )
 
@@ -15,26 +16,21 @@ func init() {
 	cache = *gocache.New(gocache.NoExpiration, gocache.NoExpiration)
 }
 
-func GetRoleAssignmentSchedules(clientFactory *armauthorization.ClientFactory, scope string, filter func(*armauthorization.RoleAssignmentSchedule) bool) ([]*armauthorization.RoleAssignmentSchedule, error) {
+func GetRoleAssignmentSchedules(ctx context.Context, authorizationClient backend.AuthorizationClient, scope string, filter func(*armauthorization.RoleAssignmentSchedule) bool) ([]*armauthorization.RoleAssignmentSchedule, error) {
 	var roleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule
 	cacheKey := fmt.Sprintf("roleAssignmentSchedules_%s", scope)
 
 	if s, found := cache.Get(cacheKey); found {
 		roleAssignmentSchedules = s.([]*armauthorization.RoleAssignmentSchedule)
 	} else {
-		roleAssignmentSchedulesClient := clientFactory.NewRoleAssignmentSchedulesClient()
-
-		pager := roleAssignmentSchedulesClient.NewListForScopePager(scope, nil)
-		for pager.More() {
-			page, err := pager.NextPage(context.Background())
-			if err != nil {
-				return nil, err
-			}
+		result, err := authorizationClient.ListRoleAssignmentSchedules(ctx, scope)
+		if err != nil {
+			return nil, err
+		}
 
-			for _, s := range page.Value {
-				if strings.HasPrefix(*s.Properties.Scope, scope) {
-					roleAssignmentSchedules = append(roleAssignmentSchedules, s)
-				}
+		for _, s := range result {
+			if *s.Properties.Scope == scope || strings.HasPrefix(*s.Properties.Scope, scope+"/") {
+				roleAssignmentSchedules = append(roleAssignmentSchedules, s)
 			}
 		}
 
@@ -1,114 +1,153 @@
 package role_assignment_schedule_create
 
 import (
+	"context"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/gofrontier-com/sheriff/pkg/core"
 	"github.com/gofrontier-com/sheriff/pkg/util/group"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
 	"github.com/gofrontier-com/sheriff/pkg/util/schedule"
 	"github.com/gofrontier-com/sheriff/pkg/util/schedule_info"
+	"github.com/gofrontier-com/sheriff/pkg/util/service_principal"
 	"github.com/gofrontier-com/sheriff/pkg/util/user"
 	"github.com/google/uuid"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
 func GetRoleAssignmentScheduleCreates(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
 	scope string,
-	groupAssignmentSchedules []*core.Schedule,
-	existingGroupRoleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule,
-	userAssignmentSchedules []*core.Schedule,
-	existingUserRoleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule,
+	groupAssignmentSchedules *core.KeyedRoleAssignmentSchedules,
+	userAssignmentSchedules *core.KeyedRoleAssignmentSchedules,
+	servicePrincipalAssignmentSchedules *core.KeyedRoleAssignmentSchedules,
+	defaults *core.ScheduleRequestDefaults,
 ) ([]*core.RoleAssignmentScheduleCreate, error) {
 	var roleAssignmentScheduleCreates []*core.RoleAssignmentScheduleCreate
 
-	groupAssignmentSchedulesToCreate, err := schedule.FilterForAssignmentSchedulesToCreate(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		groupAssignmentSchedules,
-		existingGroupRoleAssignmentSchedules,
-		group.GetGroupDisplayNameById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	groupAssignmentSchedulesToCreate := schedule.FilterForAssignmentSchedulesToCreate(groupAssignmentSchedules)
 
 	for _, a := range groupAssignmentSchedulesToCreate {
-		roleDefinition, err := role_definition.GetRoleDefinitionByName(clientFactory, scope, a.RoleName)
+		roleDefinition, err := role_definition.GetRoleDefinitionByName(ctx, authorizationClient, scope, a.RoleName)
 		if err != nil {
 			return nil, err
 		}
 
-		group, err := group.GetGroupByName(graphServiceClient, a.PrincipalName)
+		group, err := group.GetGroupByName(ctx, graphClient, a.PrincipalName)
 		if err != nil {
 			return nil, err
 		}
 
-		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(a.StartDateTime, a.EndDateTime)
+		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(a.StartDateTime, a.EndDateTime, a.GetDuration())
 		roleAssignmentScheduleCreates = append(roleAssignmentScheduleCreates, &core.RoleAssignmentScheduleCreate{
+			Condition:     a.Condition,
 			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *group.GetId(),
 			PrincipalName: *group.GetDisplayName(),
 			PrincipalType: armauthorization.PrincipalTypeGroup,
 			RoleAssignmentScheduleRequest: &armauthorization.RoleAssignmentScheduleRequest{
 				Properties: &armauthorization.RoleAssignmentScheduleRequestProperties{
-					Justification:    to.Ptr("Managed by Sheriff"),
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
 					PrincipalID:      group.GetId(),
 					RequestType:      to.Ptr(armauthorization.RequestTypeAdminAssign),
 					RoleDefinitionID: roleDefinition.ID,
 					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleAssignmentTicketInfo(a.GetTicket(defaults)),
 				},
 			},
 			RoleAssignmentScheduleRequestName: uuid.New().String(),
 			RoleName:                          *roleDefinition.Properties.RoleName,
 			Scope:                             a.Scope,
 			StartDateTime:                     scheduleInfo.StartDateTime,
+			Ticket:                            a.GetTicket(defaults),
 		})
 	}
 
-	userAssignmentSchedulesToCreate, err := schedule.FilterForAssignmentSchedulesToCreate(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		userAssignmentSchedules,
-		existingUserRoleAssignmentSchedules,
-		user.GetUserUpnById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	userAssignmentSchedulesToCreate := schedule.FilterForAssignmentSchedulesToCreate(userAssignmentSchedules)
 
 	for _, a := range userAssignmentSchedulesToCreate {
-		roleDefinition, err := role_definition.GetRoleDefinitionByName(clientFactory, scope, a.RoleName)
+		roleDefinition, err := role_definition.GetRoleDefinitionByName(ctx, authorizationClient, scope, a.RoleName)
 		if err != nil {
 			return nil, err
 		}
 
-		user, err := user.GetUserByUpn(graphServiceClient, a.PrincipalName)
+		user, err := user.GetUserByUpn(ctx, graphClient, a.PrincipalName)
 		if err != nil {
 			return nil, err
 		}
 
-		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(a.StartDateTime, a.EndDateTime)
+		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(a.StartDateTime, a.EndDateTime, a.GetDuration())
 		roleAssignmentScheduleCreates = append(roleAssignmentScheduleCreates, &core.RoleAssignmentScheduleCreate{
+			Condition:     a.Condition,
 			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *user.GetId(),
 			PrincipalName: *user.GetUserPrincipalName(),
 			PrincipalType: armauthorization.PrincipalTypeUser,
 			RoleAssignmentScheduleRequest: &armauthorization.RoleAssignmentScheduleRequest{
 				Properties: &armauthorization.RoleAssignmentScheduleRequestProperties{
-					Justification:    to.Ptr("Managed by Sheriff"),
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
 					PrincipalID:      user.GetId(),
 					RequestType:      to.Ptr(armauthorization.RequestTypeAdminAssign),
 					RoleDefinitionID: roleDefinition.ID,
 					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleAssignmentTicketInfo(a.GetTicket(defaults)),
+				},
+			},
+			RoleAssignmentScheduleRequestName: uuid.New().String(),
+			RoleName:                          *roleDefinition.Properties.RoleName,
+			Scope:                             a.Scope,
+			StartDateTime:                     scheduleInfo.StartDateTime,
+			Ticket:                            a.GetTicket(defaults),
+		})
+	}
+
+	servicePrincipalAssignmentSchedulesToCreate := schedule.FilterForAssignmentSchedulesToCreate(servicePrincipalAssignmentSchedules)
+
+	for _, a := range servicePrincipalAssignmentSchedulesToCreate {
+		roleDefinition, err := role_definition.GetRoleDefinitionByName(ctx, authorizationClient, scope, a.RoleName)
+		if err != nil {
+			return nil, err
+		}
+
+		servicePrincipal, err := service_principal.GetServicePrincipalById(ctx, graphClient, a.PrincipalId)
+		if err != nil {
+			return nil, err
+		}
+
+		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(a.StartDateTime, a.EndDateTime, a.GetDuration())
+		roleAssignmentScheduleCreates = append(roleAssignmentScheduleCreates, &core.RoleAssignmentScheduleCreate{
+			Condition:     a.Condition,
+			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *servicePrincipal.GetId(),
+			PrincipalName: *servicePrincipal.GetDisplayName(),
+			PrincipalType: armauthorization.PrincipalTypeServicePrincipal,
+			RoleAssignmentScheduleRequest: &armauthorization.RoleAssignmentScheduleRequest{
+				Properties: &armauthorization.RoleAssignmentScheduleRequestProperties{
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
+					PrincipalID:      servicePrincipal.GetId(),
+					RequestType:      to.Ptr(armauthorization.RequestTypeAdminAssign),
+					RoleDefinitionID: roleDefinition.ID,
+					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleAssignmentTicketInfo(a.GetTicket(defaults)),
 				},
 			},
 			RoleAssignmentScheduleRequestName: uuid.New().String(),
 			RoleName:                          *roleDefinition.Properties.RoleName,
 			Scope:                             a.Scope,
 			StartDateTime:                     scheduleInfo.StartDateTime,
+			Ticket:                            a.GetTicket(defaults),
 		})
 	}
 
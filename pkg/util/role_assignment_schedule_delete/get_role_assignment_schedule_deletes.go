@@ -1,50 +1,46 @@
 package role_assignment_schedule_delete
 
 import (
+	"context"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/gofrontier-com/sheriff/pkg/core"
 	"github.com/gofrontier-com/sheriff/pkg/util/group"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_assignment_schedule"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
+	"github.com/gofrontier-com/sheriff/pkg/util/schedule_info"
+	"github.com/gofrontier-com/sheriff/pkg/util/service_principal"
 	"github.com/gofrontier-com/sheriff/pkg/util/user"
 	"github.com/google/uuid"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
 func GetRoleAssignmentScheduleDeletes(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
 	scope string,
-	groupAssignmentSchedules []*core.Schedule,
-	existingGroupRoleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule,
-	userAssignmentSchedules []*core.Schedule,
-	existingUserRoleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule,
+	groupAssignmentSchedules *core.KeyedRoleAssignmentSchedules,
+	userAssignmentSchedules *core.KeyedRoleAssignmentSchedules,
+	servicePrincipalAssignmentSchedules *core.KeyedRoleAssignmentSchedules,
+	defaults *core.ScheduleRequestDefaults,
 ) ([]*core.RoleAssignmentScheduleDelete, error) {
 	var roleAssignmentScheduleDeletes []*core.RoleAssignmentScheduleDelete
 
-	groupAssignmentSchedulesToDelete, err := role_assignment_schedule.FilterForRoleAssignmentSchedulesToDelete(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		existingGroupRoleAssignmentSchedules,
-		groupAssignmentSchedules,
-		group.GetGroupDisplayNameById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	groupAssignmentSchedulesToDelete := role_assignment_schedule.FilterForRoleAssignmentSchedulesToDelete(groupAssignmentSchedules)
 
 	for _, s := range groupAssignmentSchedulesToDelete {
 		roleDefinition, err := role_definition.GetRoleDefinitionById(
-			clientFactory,
+			ctx,
+			authorizationClient,
 			*s.Properties.RoleDefinitionID,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		group, err := group.GetGroupById(graphServiceClient, *s.Properties.PrincipalID)
+		group, err := group.GetGroupById(ctx, graphClient, *s.Properties.PrincipalID)
 		if err != nil {
 			return nil, err
 		}
@@ -53,26 +49,31 @@ func GetRoleAssignmentScheduleDeletes(
 			roleAssignmentScheduleDeletes = append(roleAssignmentScheduleDeletes, &core.RoleAssignmentScheduleDelete{
 				Cancel:        false,
 				EndDateTime:   s.Properties.EndDateTime,
+				Justification: defaults.Justification,
+				PrincipalId:   *s.Properties.PrincipalID,
 				PrincipalName: *group.GetDisplayName(),
 				PrincipalType: armauthorization.PrincipalTypeGroup,
 				RoleAssignmentScheduleRequest: &armauthorization.RoleAssignmentScheduleRequest{
 					Properties: &armauthorization.RoleAssignmentScheduleRequestProperties{
-						Justification:                  to.Ptr("Managed by Sheriff"),
+						Justification:                  to.Ptr(defaults.Justification),
 						PrincipalID:                    s.Properties.PrincipalID,
 						RequestType:                    to.Ptr(armauthorization.RequestTypeAdminRemove),
 						RoleDefinitionID:               s.Properties.RoleDefinitionID,
 						TargetRoleAssignmentScheduleID: s.ID,
+						TicketInfo:                     schedule_info.GetRoleAssignmentTicketInfo(defaults.Ticket),
 					},
 				},
 				RoleAssignmentScheduleRequestName: uuid.New().String(),
 				RoleName:                          *roleDefinition.Properties.RoleName,
 				Scope:                             *s.Properties.Scope,
 				StartDateTime:                     s.Properties.StartDateTime,
+				Ticket:                            defaults.Ticket,
 			})
 		} else {
 			roleAssignmentScheduleDeletes = append(roleAssignmentScheduleDeletes, &core.RoleAssignmentScheduleDelete{
 				Cancel:                            true,
 				EndDateTime:                       s.Properties.EndDateTime,
+				PrincipalId:                       *s.Properties.PrincipalID,
 				PrincipalName:                     *group.GetDisplayName(),
 				PrincipalType:                     armauthorization.PrincipalTypeGroup,
 				RoleAssignmentScheduleRequestName: *s.Name,
@@ -83,28 +84,19 @@ func GetRoleAssignmentScheduleDeletes(
 		}
 	}
 
-	userAssignmentSchedulesToDelete, err := role_assignment_schedule.FilterForRoleAssignmentSchedulesToDelete(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		existingUserRoleAssignmentSchedules,
-		userAssignmentSchedules,
-		user.GetUserUpnById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	userAssignmentSchedulesToDelete := role_assignment_schedule.FilterForRoleAssignmentSchedulesToDelete(userAssignmentSchedules)
 
 	for _, s := range userAssignmentSchedulesToDelete {
 		roleDefinition, err := role_definition.GetRoleDefinitionById(
-			clientFactory,
+			ctx,
+			authorizationClient,
 			*s.Properties.RoleDefinitionID,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		user, err := user.GetUserById(graphServiceClient, *s.Properties.PrincipalID)
+		user, err := user.GetUserById(ctx, graphClient, *s.Properties.PrincipalID)
 		if err != nil {
 			return nil, err
 		}
@@ -113,26 +105,31 @@ func GetRoleAssignmentScheduleDeletes(
 			roleAssignmentScheduleDeletes = append(roleAssignmentScheduleDeletes, &core.RoleAssignmentScheduleDelete{
 				Cancel:        false,
 				EndDateTime:   s.Properties.EndDateTime,
+				Justification: defaults.Justification,
+				PrincipalId:   *s.Properties.PrincipalID,
 				PrincipalName: *user.GetUserPrincipalName(),
 				PrincipalType: armauthorization.PrincipalTypeUser,
 				RoleAssignmentScheduleRequest: &armauthorization.RoleAssignmentScheduleRequest{
 					Properties: &armauthorization.RoleAssignmentScheduleRequestProperties{
-						Justification:                  to.Ptr("Managed by Sheriff"),
+						Justification:                  to.Ptr(defaults.Justification),
 						PrincipalID:                    s.Properties.PrincipalID,
 						RequestType:                    to.Ptr(armauthorization.RequestTypeAdminRemove),
 						RoleDefinitionID:               s.Properties.RoleDefinitionID,
 						TargetRoleAssignmentScheduleID: s.ID,
+						TicketInfo:                     schedule_info.GetRoleAssignmentTicketInfo(defaults.Ticket),
 					},
 				},
 				RoleAssignmentScheduleRequestName: uuid.New().String(),
 				RoleName:                          *roleDefinition.Properties.RoleName,
 				Scope:                             *s.Properties.Scope,
 				StartDateTime:                     s.Properties.StartDateTime,
+				Ticket:                            defaults.Ticket,
 			})
 		} else {
 			roleAssignmentScheduleDeletes = append(roleAssignmentScheduleDeletes, &core.RoleAssignmentScheduleDelete{
 				Cancel:                            true,
 				EndDateTime:                       s.Properties.EndDateTime,
+				PrincipalId:                       *s.Properties.PrincipalID,
 				PrincipalName:                     *user.GetUserPrincipalName(),
 				PrincipalType:                     armauthorization.PrincipalTypeUser,
 				RoleAssignmentScheduleRequestName: *s.Name,
@@ -143,5 +140,61 @@ func GetRoleAssignmentScheduleDeletes(
 		}
 	}
 
+	servicePrincipalAssignmentSchedulesToDelete := role_assignment_schedule.FilterForRoleAssignmentSchedulesToDelete(servicePrincipalAssignmentSchedules)
+
+	for _, s := range servicePrincipalAssignmentSchedulesToDelete {
+		roleDefinition, err := role_definition.GetRoleDefinitionById(
+			ctx,
+			authorizationClient,
+			*s.Properties.RoleDefinitionID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		servicePrincipal, err := service_principal.GetServicePrincipalById(ctx, graphClient, *s.Properties.PrincipalID)
+		if err != nil {
+			return nil, err
+		}
+
+		if *s.Properties.Status == armauthorization.StatusProvisioned {
+			roleAssignmentScheduleDeletes = append(roleAssignmentScheduleDeletes, &core.RoleAssignmentScheduleDelete{
+				Cancel:        false,
+				EndDateTime:   s.Properties.EndDateTime,
+				Justification: defaults.Justification,
+				PrincipalId:   *s.Properties.PrincipalID,
+				PrincipalName: *servicePrincipal.GetDisplayName(),
+				PrincipalType: armauthorization.PrincipalTypeServicePrincipal,
+				RoleAssignmentScheduleRequest: &armauthorization.RoleAssignmentScheduleRequest{
+					Properties: &armauthorization.RoleAssignmentScheduleRequestProperties{
+						Justification:                  to.Ptr(defaults.Justification),
+						PrincipalID:                    s.Properties.PrincipalID,
+						RequestType:                    to.Ptr(armauthorization.RequestTypeAdminRemove),
+						RoleDefinitionID:               s.Properties.RoleDefinitionID,
+						TargetRoleAssignmentScheduleID: s.ID,
+						TicketInfo:                     schedule_info.GetRoleAssignmentTicketInfo(defaults.Ticket),
+					},
+				},
+				RoleAssignmentScheduleRequestName: uuid.New().String(),
+				RoleName:                          *roleDefinition.Properties.RoleName,
+				Scope:                             *s.Properties.Scope,
+				StartDateTime:                     s.Properties.StartDateTime,
+				Ticket:                            defaults.Ticket,
+			})
+		} else {
+			roleAssignmentScheduleDeletes = append(roleAssignmentScheduleDeletes, &core.RoleAssignmentScheduleDelete{
+				Cancel:                            true,
+				EndDateTime:                       s.Properties.EndDateTime,
+				PrincipalId:                       *s.Properties.PrincipalID,
+				PrincipalName:                     *servicePrincipal.GetDisplayName(),
+				PrincipalType:                     armauthorization.PrincipalTypeServicePrincipal,
+				RoleAssignmentScheduleRequestName: *s.Name,
+				RoleName:                          *roleDefinition.Properties.RoleName,
+				Scope:                             *s.Properties.Scope,
+				StartDateTime:                     s.Properties.StartDateTime,
+			})
+		}
+	}
+
 	return roleAssignmentScheduleDeletes, nil
 }
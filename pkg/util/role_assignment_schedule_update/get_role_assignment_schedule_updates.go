@@ -1,49 +1,40 @@
 package role_assignment_schedule_update
 
 import (
-	"fmt"
-	"slices"
+	"context"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
-	"github.com/ahmetb/go-linq/v3"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/gofrontier-com/sheriff/pkg/core"
 	"github.com/gofrontier-com/sheriff/pkg/util/group"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
 	"github.com/gofrontier-com/sheriff/pkg/util/schedule"
 	"github.com/gofrontier-com/sheriff/pkg/util/schedule_info"
+	"github.com/gofrontier-com/sheriff/pkg/util/service_principal"
 	"github.com/gofrontier-com/sheriff/pkg/util/user"
 	"github.com/google/uuid"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
 func GetRoleAssignmentScheduleUpdates(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
 	scope string,
-	groupAssignmentSchedules []*core.Schedule,
-	existingGroupRoleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule,
-	userAssignmentSchedules []*core.Schedule,
-	existingUserRoleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule,
+	groupAssignmentSchedules *core.KeyedRoleAssignmentSchedules,
+	userAssignmentSchedules *core.KeyedRoleAssignmentSchedules,
+	servicePrincipalAssignmentSchedules *core.KeyedRoleAssignmentSchedules,
+	defaults *core.ScheduleRequestDefaults,
 ) ([]*core.RoleAssignmentScheduleUpdate, error) {
 	var roleAssignmentScheduleUpdates []*core.RoleAssignmentScheduleUpdate
 
-	groupAssignmentSchedulesToUpdate, err := schedule.FilterForAssignmentSchedulesToUpdate(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		groupAssignmentSchedules,
-		existingGroupRoleAssignmentSchedules,
-		group.GetGroupDisplayNameById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	groupAssignmentSchedulesToUpdate := schedule.FilterForAssignmentSchedulesToUpdate(groupAssignmentSchedules)
 
 	for _, a := range groupAssignmentSchedulesToUpdate {
 		roleDefinition, err := role_definition.GetRoleDefinitionByName(
-			clientFactory,
+			ctx,
+			authorizationClient,
 			scope,
 			a.RoleName,
 		)
@@ -51,73 +42,56 @@ func GetRoleAssignmentScheduleUpdates(
 			return nil, err
 		}
 
-		group, err := group.GetGroupByName(graphServiceClient, a.PrincipalName)
+		group, err := group.GetGroupByName(ctx, graphClient, a.PrincipalName)
 		if err != nil {
 			return nil, err
 		}
 
-		existingGroupRoleAssignmentScheduleIdx := slices.IndexFunc(existingGroupRoleAssignmentSchedules, func(s *armauthorization.RoleAssignmentSchedule) bool {
-			return *s.Properties.Scope == a.Scope &&
-				*s.Properties.RoleDefinitionID == *roleDefinition.ID &&
-				*s.Properties.PrincipalID == *group.GetId()
-		})
-		existingGroupRoleAssignmentScheduleIdx2 := linq.From(existingGroupRoleAssignmentSchedules).IndexOfT(func(s *armauthorization.RoleAssignmentSchedule) bool {
-			return *s.Properties.Scope == a.Scope &&
-				*s.Properties.RoleDefinitionID == *roleDefinition.ID &&
-				*s.Properties.PrincipalID == *group.GetId()
-		})
-		if existingGroupRoleAssignmentScheduleIdx != existingGroupRoleAssignmentScheduleIdx2 {
-			panic("index mismatch")
-		}
-		if existingGroupRoleAssignmentScheduleIdx == -1 {
-			return nil, fmt.Errorf("existing role assignment schedule not found")
-		}
-
-		existingGroupRoleAssignmentSchedule := existingGroupRoleAssignmentSchedules[existingGroupRoleAssignmentScheduleIdx]
+		existingGroupRoleAssignmentSchedule := groupAssignmentSchedules.ExistingIndex[groupAssignmentSchedules.Keys[a]]
 
 		var startTime *time.Time
 		if a.StartDateTime != nil {
 			startTime = a.StartDateTime
-		} else {
+		} else if !a.IsDueForRenewal(existingGroupRoleAssignmentSchedule.Properties.EndDateTime) {
+			// Keep the existing start time unless the schedule is being renewed, in which case
+			// it starts now.
 			startTime = existingGroupRoleAssignmentSchedule.Properties.StartDateTime
 		}
 
-		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(startTime, a.EndDateTime)
+		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(startTime, a.EndDateTime, a.GetDuration())
 		roleAssignmentScheduleUpdates = append(roleAssignmentScheduleUpdates, &core.RoleAssignmentScheduleUpdate{
+			Condition:     a.Condition,
 			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *group.GetId(),
 			PrincipalName: *group.GetDisplayName(),
 			PrincipalType: armauthorization.PrincipalTypeGroup,
 			RoleAssignmentScheduleRequest: &armauthorization.RoleAssignmentScheduleRequest{
 				Properties: &armauthorization.RoleAssignmentScheduleRequestProperties{
-					Justification:    to.Ptr("Managed by Sheriff"),
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
 					PrincipalID:      group.GetId(),
 					RequestType:      to.Ptr(armauthorization.RequestTypeAdminUpdate),
 					RoleDefinitionID: roleDefinition.ID,
 					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleAssignmentTicketInfo(a.GetTicket(defaults)),
 				},
 			},
 			RoleAssignmentScheduleRequestName: uuid.New().String(),
 			RoleName:                          *roleDefinition.Properties.RoleName,
 			Scope:                             *existingGroupRoleAssignmentSchedule.Properties.Scope,
 			StartDateTime:                     scheduleInfo.StartDateTime,
+			Ticket:                            a.GetTicket(defaults),
 		})
 	}
 
-	userAssignmentSchedulesToUpdate, err := schedule.FilterForAssignmentSchedulesToUpdate(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		userAssignmentSchedules,
-		existingUserRoleAssignmentSchedules,
-		user.GetUserUpnById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	userAssignmentSchedulesToUpdate := schedule.FilterForAssignmentSchedulesToUpdate(userAssignmentSchedules)
 
 	for _, a := range userAssignmentSchedulesToUpdate {
 		roleDefinition, err := role_definition.GetRoleDefinitionByName(
-			clientFactory,
+			ctx,
+			authorizationClient,
 			scope,
 			a.RoleName,
 		)
@@ -125,55 +99,104 @@ func GetRoleAssignmentScheduleUpdates(
 			return nil, err
 		}
 
-		user, err := user.GetUserByUpn(graphServiceClient, a.PrincipalName)
+		user, err := user.GetUserByUpn(ctx, graphClient, a.PrincipalName)
 		if err != nil {
 			return nil, err
 		}
 
-		existingUserRoleAssignmentScheduleIdx := slices.IndexFunc(existingUserRoleAssignmentSchedules, func(s *armauthorization.RoleAssignmentSchedule) bool {
-			return *s.Properties.Scope == a.Scope &&
-				*s.Properties.RoleDefinitionID == *roleDefinition.ID &&
-				*s.Properties.PrincipalID == *user.GetId()
-		})
-		existingUserRoleAssignmentScheduleIdx2 := linq.From(existingUserRoleAssignmentSchedules).IndexOfT(func(s *armauthorization.RoleAssignmentSchedule) bool {
-			return *s.Properties.Scope == a.Scope &&
-				*s.Properties.RoleDefinitionID == *roleDefinition.ID &&
-				*s.Properties.PrincipalID == *user.GetId()
-		})
-		if existingUserRoleAssignmentScheduleIdx != existingUserRoleAssignmentScheduleIdx2 {
-			panic("index mismatch")
-		}
-		if existingUserRoleAssignmentScheduleIdx == -1 {
-			return nil, fmt.Errorf("existing role assignment schedule not found")
-		}
-
-		existingUserRoleAssignmentSchedule := existingUserRoleAssignmentSchedules[existingUserRoleAssignmentScheduleIdx]
+		existingUserRoleAssignmentSchedule := userAssignmentSchedules.ExistingIndex[userAssignmentSchedules.Keys[a]]
 
 		var startTime *time.Time
 		if a.StartDateTime != nil {
 			startTime = a.StartDateTime
-		} else {
+		} else if !a.IsDueForRenewal(existingUserRoleAssignmentSchedule.Properties.EndDateTime) {
+			// Keep the existing start time unless the schedule is being renewed, in which case
+			// it starts now.
 			startTime = existingUserRoleAssignmentSchedule.Properties.StartDateTime
 		}
 
-		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(startTime, a.EndDateTime)
+		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(startTime, a.EndDateTime, a.GetDuration())
 		roleAssignmentScheduleUpdates = append(roleAssignmentScheduleUpdates, &core.RoleAssignmentScheduleUpdate{
+			Condition:     a.Condition,
 			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *user.GetId(),
 			PrincipalName: *user.GetUserPrincipalName(),
-			PrincipalType: armauthorization.PrincipalTypeGroup,
+			PrincipalType: armauthorization.PrincipalTypeUser,
 			RoleAssignmentScheduleRequest: &armauthorization.RoleAssignmentScheduleRequest{
 				Properties: &armauthorization.RoleAssignmentScheduleRequestProperties{
-					Justification:    to.Ptr("Managed by Sheriff"),
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
 					PrincipalID:      user.GetId(),
 					RequestType:      to.Ptr(armauthorization.RequestTypeAdminUpdate),
 					RoleDefinitionID: roleDefinition.ID,
 					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleAssignmentTicketInfo(a.GetTicket(defaults)),
 				},
 			},
 			RoleAssignmentScheduleRequestName: uuid.New().String(),
 			RoleName:                          *roleDefinition.Properties.RoleName,
 			Scope:                             *existingUserRoleAssignmentSchedule.Properties.Scope,
 			StartDateTime:                     scheduleInfo.StartDateTime,
+			Ticket:                            a.GetTicket(defaults),
+		})
+	}
+
+	servicePrincipalAssignmentSchedulesToUpdate := schedule.FilterForAssignmentSchedulesToUpdate(servicePrincipalAssignmentSchedules)
+
+	for _, a := range servicePrincipalAssignmentSchedulesToUpdate {
+		roleDefinition, err := role_definition.GetRoleDefinitionByName(
+			ctx,
+			authorizationClient,
+			scope,
+			a.RoleName,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		servicePrincipal, err := service_principal.GetServicePrincipalById(ctx, graphClient, a.PrincipalId)
+		if err != nil {
+			return nil, err
+		}
+
+		existingServicePrincipalRoleAssignmentSchedule := servicePrincipalAssignmentSchedules.ExistingIndex[servicePrincipalAssignmentSchedules.Keys[a]]
+
+		var startTime *time.Time
+		if a.StartDateTime != nil {
+			startTime = a.StartDateTime
+		} else if !a.IsDueForRenewal(existingServicePrincipalRoleAssignmentSchedule.Properties.EndDateTime) {
+			// Keep the existing start time unless the schedule is being renewed, in which case
+			// it starts now.
+			startTime = existingServicePrincipalRoleAssignmentSchedule.Properties.StartDateTime
+		}
+
+		scheduleInfo := schedule_info.GetRoleAssignmentScheduleInfo(startTime, a.EndDateTime, a.GetDuration())
+		roleAssignmentScheduleUpdates = append(roleAssignmentScheduleUpdates, &core.RoleAssignmentScheduleUpdate{
+			Condition:     a.Condition,
+			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *servicePrincipal.GetId(),
+			PrincipalName: *servicePrincipal.GetDisplayName(),
+			PrincipalType: armauthorization.PrincipalTypeServicePrincipal,
+			RoleAssignmentScheduleRequest: &armauthorization.RoleAssignmentScheduleRequest{
+				Properties: &armauthorization.RoleAssignmentScheduleRequestProperties{
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
+					PrincipalID:      servicePrincipal.GetId(),
+					RequestType:      to.Ptr(armauthorization.RequestTypeAdminUpdate),
+					RoleDefinitionID: roleDefinition.ID,
+					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleAssignmentTicketInfo(a.GetTicket(defaults)),
+				},
+			},
+			RoleAssignmentScheduleRequestName: uuid.New().String(),
+			RoleName:                          *roleDefinition.Properties.RoleName,
+			Scope:                             *existingServicePrincipalRoleAssignmentSchedule.Properties.Scope,
+			StartDateTime:                     scheduleInfo.StartDateTime,
+			Ticket:                            a.GetTicket(defaults),
 		})
 	}
 
@@ -0,0 +1,31 @@
+package role_definition
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// GetCustomRoleDefinitions gets the custom role definitions that are visible at the given scope.
+func GetCustomRoleDefinitions(ctx context.Context, authorizationClient backend.AuthorizationClient, scope string) ([]*armauthorization.RoleDefinition, error) {
+	var roleDefinitions []*armauthorization.RoleDefinition
+	cacheKey := fmt.Sprintf("custom::%s", scope)
+
+	if d, found := cache.Get(cacheKey); found {
+		roleDefinitions = d.([]*armauthorization.RoleDefinition)
+	} else {
+		result, err := authorizationClient.ListCustomRoleDefinitions(ctx, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		roleDefinitions = result
+
+		cache.Set(cacheKey, roleDefinitions, gocache.NoExpiration)
+	}
+
+	return roleDefinitions, nil
+}
@@ -2,37 +2,32 @@ package role_definition
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	gocache "github.com/patrickmn/go-cache"
 )
 
-func GetRoleDefinitionById(clientFactory *armauthorization.ClientFactory, roleDefinitionId string) (*armauthorization.RoleDefinition, error) {
+func GetRoleDefinitionById(ctx context.Context, authorizationClient backend.AuthorizationClient, roleDefinitionId string) (*armauthorization.RoleDefinition, error) {
 	var roleDefinition *armauthorization.RoleDefinition
 	cacheKey := fmt.Sprintf("id::%s", roleDefinitionId)
 
 	if d, found := cache.Get(cacheKey); found {
 		roleDefinition = d.(*armauthorization.RoleDefinition)
 	} else {
-		roleDefinitionsClient := clientFactory.NewRoleDefinitionsClient()
-
-		options := &armauthorization.RoleDefinitionsClientGetByIDOptions{}
-		response, err := roleDefinitionsClient.GetByID(context.Background(), roleDefinitionId, options)
+		result, err := authorizationClient.GetRoleDefinitionById(ctx, roleDefinitionId)
 		if err != nil {
-			if _, ok := err.(*azcore.ResponseError); ok {
-				responseError := err.(*azcore.ResponseError)
-				if responseError.ErrorCode == "RoleDefinitionDoesNotExist" {
-					return nil, fmt.Errorf("role definition with Id \"%s\" not found", roleDefinitionId)
-				}
+			if errors.Is(err, backend.ErrNotFound) {
+				return nil, fmt.Errorf("role definition with Id \"%s\" not found", roleDefinitionId)
 			} else {
 				return nil, err
 			}
 		}
 
-		roleDefinition = &response.RoleDefinition
+		roleDefinition = result
 
 		scope := strings.Split(*roleDefinition.ID, "/providers/Microsoft.Authorization/roleDefinitions/")[0]
 
@@ -4,32 +4,21 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	gocache "github.com/patrickmn/go-cache"
 )
 
-func GetRoleDefinitionByName(clientFactory *armauthorization.ClientFactory, scope string, roleDefinitionName string) (*armauthorization.RoleDefinition, error) {
+func GetRoleDefinitionByName(ctx context.Context, authorizationClient backend.AuthorizationClient, scope string, roleDefinitionName string) (*armauthorization.RoleDefinition, error) {
 	var roleDefinition *armauthorization.RoleDefinition
 	cacheKey := fmt.Sprintf("scoped-name::%s:%s", scope, roleDefinitionName)
 
 	if d, found := cache.Get(cacheKey); found {
 		roleDefinition = d.(*armauthorization.RoleDefinition)
 	} else {
-		roleDefinitionsClient := clientFactory.NewRoleDefinitionsClient()
-
-		var roleDefinitions []*armauthorization.RoleDefinition
-		options := &armauthorization.RoleDefinitionsClientListOptions{
-			Filter: to.Ptr(fmt.Sprintf("roleName eq '%s'", roleDefinitionName)),
-		}
-		pager := roleDefinitionsClient.NewListPager(scope, options)
-		for pager.More() {
-			page, err := pager.NextPage(context.Background())
-			if err != nil {
-				return nil, err
-			}
-
-			roleDefinitions = append(roleDefinitions, page.Value...)
+		roleDefinitions, err := authorizationClient.ListRoleDefinitionsByName(ctx, scope, roleDefinitionName)
+		if err != nil {
+			return nil, err
 		}
 
 		if len(roleDefinitions) == 0 {
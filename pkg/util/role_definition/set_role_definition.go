@@ -0,0 +1,20 @@
+package role_definition
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// SetRoleDefinition caches a role definition so that it can be got by name at the given scope
+// before it exists, i.e. when it is created in the same run as schedules that reference it.
+func SetRoleDefinition(scope string, roleDefinition *armauthorization.RoleDefinition) {
+	cacheKeys := []string{
+		fmt.Sprintf("scoped-name::%s:%s", scope, *roleDefinition.Properties.RoleName),
+		fmt.Sprintf("id::%s", *roleDefinition.ID),
+	}
+	for _, cacheKey := range cacheKeys {
+		cache.Set(cacheKey, roleDefinition, gocache.NoExpiration)
+	}
+}
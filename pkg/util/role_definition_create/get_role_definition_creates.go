@@ -0,0 +1,49 @@
+package role_definition_create
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
+	"github.com/google/uuid"
+)
+
+func GetRoleDefinitionCreates(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	config *core.AzureRmConfig,
+) ([]*core.RoleDefinitionCreate, error) {
+	var roleDefinitionCreates []*core.RoleDefinitionCreate
+
+	for _, d := range config.RoleDefinitions {
+		// Custom roles are created at, and looked up from, the first assignable scope. Assignable scopes are
+		// required, so that the role does not depend on the scopes that Sheriff is run against.
+		scope := d.AssignableScopes[0]
+
+		existingRoleDefinitions, err := role_definition.GetCustomRoleDefinitions(ctx, authorizationClient, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		exists := slices.ContainsFunc(existingRoleDefinitions, func(r *armauthorization.RoleDefinition) bool {
+			return *r.Properties.RoleName == d.Name
+		})
+		if exists {
+			continue
+		}
+
+		roleDefinitionCreates = append(roleDefinitionCreates, &core.RoleDefinitionCreate{
+			RoleDefinition: &armauthorization.RoleDefinition{
+				Properties: d.GetRoleDefinitionProperties(),
+			},
+			RoleDefinitionName: uuid.New().String(),
+			RoleName:           d.Name,
+			Scope:              scope,
+		})
+	}
+
+	return roleDefinitionCreates, nil
+}
@@ -0,0 +1,89 @@
+package role_definition_create
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend/fake"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+func TestGetRoleDefinitionCreates(t *testing.T) {
+	tests := []struct {
+		name             string
+		assignableScopes []string
+		existingScope    string
+		expectedScope    string
+	}{
+		{
+			name:             "role is created at first assignable scope",
+			assignableScopes: []string{"/subscriptions/00000000-0000-0000-0000-000000000001", "/subscriptions/00000000-0000-0000-0000-000000000002"},
+			expectedScope:    "/subscriptions/00000000-0000-0000-0000-000000000001",
+		},
+		{
+			name:             "existing role is not created",
+			assignableScopes: []string{"/subscriptions/00000000-0000-0000-0000-000000000003"},
+			existingScope:    "/subscriptions/00000000-0000-0000-0000-000000000003",
+		},
+		{
+			name:             "role existing above first assignable scope is not created",
+			assignableScopes: []string{"/subscriptions/00000000-0000-0000-0000-000000000004"},
+			existingScope:    "/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := fake.NewBackend()
+			if tt.existingScope != "" {
+				b.AddRoleDefinition(tt.existingScope, &armauthorization.RoleDefinition{
+					Properties: &armauthorization.RoleDefinitionProperties{
+						RoleName: to.Ptr("App Operator"),
+						RoleType: to.Ptr("CustomRole"),
+					},
+				})
+			}
+
+			config := &core.AzureRmConfig{
+				RoleDefinitions: []*core.RoleDefinition{
+					{
+						AssignableScopes: tt.assignableScopes,
+						Name:             "App Operator",
+						Permissions:      []*core.RoleDefinitionPermission{{Actions: []string{"Microsoft.Web/sites/restart/action"}}},
+					},
+				},
+			}
+
+			creates, err := GetRoleDefinitionCreates(context.Background(), b, config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expectedScope == "" {
+				if len(creates) != 0 {
+					t.Errorf("expected no creates, got %d", len(creates))
+				}
+				return
+			}
+
+			if len(creates) != 1 {
+				t.Fatalf("expected 1 create, got %d", len(creates))
+			}
+
+			c := creates[0]
+			if c.Scope != tt.expectedScope || c.RoleName != "App Operator" {
+				t.Errorf("expected App Operator at %s, got %s at %s", tt.expectedScope, c.RoleName, c.Scope)
+			}
+			var assignableScopes []string
+			for _, s := range c.RoleDefinition.Properties.AssignableScopes {
+				assignableScopes = append(assignableScopes, *s)
+			}
+			if !slices.Equal(assignableScopes, tt.assignableScopes) {
+				t.Errorf("expected assignable scopes %v, got %v", tt.assignableScopes, assignableScopes)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package role_definition_update
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/go-test/deep"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
+)
+
+func GetRoleDefinitionUpdates(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	config *core.AzureRmConfig,
+) ([]*core.RoleDefinitionUpdate, error) {
+	var roleDefinitionUpdates []*core.RoleDefinitionUpdate
+
+	for _, d := range config.RoleDefinitions {
+		// Custom roles are created at, and looked up from, the first assignable scope. Assignable scopes are
+		// required, so that the role does not depend on the scopes that Sheriff is run against.
+		scope := d.AssignableScopes[0]
+
+		existingRoleDefinitions, err := role_definition.GetCustomRoleDefinitions(ctx, authorizationClient, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		index := slices.IndexFunc(existingRoleDefinitions, func(r *armauthorization.RoleDefinition) bool {
+			return *r.Properties.RoleName == d.Name
+		})
+		if index == -1 {
+			continue
+		}
+
+		existingRoleDefinition := existingRoleDefinitions[index]
+
+		desiredRoleDefinitionProperties := d.GetRoleDefinitionProperties()
+
+		existingDescription := existingRoleDefinition.Properties.Description
+		if existingDescription == nil {
+			existingDescription = to.Ptr("")
+		}
+		existingRoleDefinitionProperties := &armauthorization.RoleDefinitionProperties{
+			AssignableScopes: existingRoleDefinition.Properties.AssignableScopes,
+			Description:      existingDescription,
+			Permissions:      existingRoleDefinition.Properties.Permissions,
+			RoleName:         existingRoleDefinition.Properties.RoleName,
+			RoleType:         existingRoleDefinition.Properties.RoleType,
+		}
+
+		if diff := deep.Equal(existingRoleDefinitionProperties, desiredRoleDefinitionProperties); len(diff) > 0 {
+			roleDefinitionUpdates = append(roleDefinitionUpdates, &core.RoleDefinitionUpdate{
+				RoleDefinition: &armauthorization.RoleDefinition{
+					Properties: desiredRoleDefinitionProperties,
+				},
+				RoleDefinitionName: *existingRoleDefinition.Name,
+				RoleName:           d.Name,
+				Scope:              scope,
+			})
+		}
+	}
+
+	return roleDefinitionUpdates, nil
+}
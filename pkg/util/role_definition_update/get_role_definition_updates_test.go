@@ -0,0 +1,123 @@
+package role_definition_update
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend/fake"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+// newRoleDefinition creates the config of a custom role that can restart web apps at the given scopes.
+func newRoleDefinition(assignableScopes ...string) *core.RoleDefinition {
+	return &core.RoleDefinition{
+		AssignableScopes: assignableScopes,
+		Description:      "Restarts web apps",
+		Name:             "App Operator",
+		Permissions:      []*core.RoleDefinitionPermission{{Actions: []string{"Microsoft.Web/sites/restart/action"}}},
+	}
+}
+
+func TestGetRoleDefinitionUpdates(t *testing.T) {
+	tests := []struct {
+		name                     string
+		scope                    string
+		existing                 func(scope string) *core.RoleDefinition
+		desired                  func(scope string) *core.RoleDefinition
+		expectedAssignableScopes func(scope string) []string
+	}{
+		{
+			name:  "unchanged role is not updated",
+			scope: "/subscriptions/00000000-0000-0000-0000-000000000001",
+			existing: func(scope string) *core.RoleDefinition {
+				return newRoleDefinition(scope, "/subscriptions/00000000-0000-0000-0000-0000000000ff")
+			},
+			desired: func(scope string) *core.RoleDefinition {
+				return newRoleDefinition(scope, "/subscriptions/00000000-0000-0000-0000-0000000000ff")
+			},
+		},
+		{
+			name:  "missing role is not updated",
+			scope: "/subscriptions/00000000-0000-0000-0000-000000000002",
+			desired: func(scope string) *core.RoleDefinition {
+				return newRoleDefinition(scope)
+			},
+		},
+		{
+			name:  "changed permissions are updated",
+			scope: "/subscriptions/00000000-0000-0000-0000-000000000003",
+			existing: func(scope string) *core.RoleDefinition {
+				return newRoleDefinition(scope)
+			},
+			desired: func(scope string) *core.RoleDefinition {
+				d := newRoleDefinition(scope)
+				d.Permissions[0].Actions = append(d.Permissions[0].Actions, "Microsoft.Web/sites/read")
+				return d
+			},
+			expectedAssignableScopes: func(scope string) []string {
+				return []string{scope}
+			},
+		},
+		{
+			name:  "assignable scopes are set to those in config",
+			scope: "/subscriptions/00000000-0000-0000-0000-000000000004",
+			existing: func(scope string) *core.RoleDefinition {
+				return newRoleDefinition(scope)
+			},
+			desired: func(scope string) *core.RoleDefinition {
+				return newRoleDefinition(scope, "/subscriptions/00000000-0000-0000-0000-0000000000ff")
+			},
+			expectedAssignableScopes: func(scope string) []string {
+				return []string{scope, "/subscriptions/00000000-0000-0000-0000-0000000000ff"}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := fake.NewBackend()
+
+			var existingRoleDefinition *armauthorization.RoleDefinition
+			if tt.existing != nil {
+				existingRoleDefinition = b.AddRoleDefinition(tt.scope, &armauthorization.RoleDefinition{
+					Properties: tt.existing(tt.scope).GetRoleDefinitionProperties(),
+				})
+			}
+
+			config := &core.AzureRmConfig{
+				RoleDefinitions: []*core.RoleDefinition{tt.desired(tt.scope)},
+			}
+
+			updates, err := GetRoleDefinitionUpdates(context.Background(), b, config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expectedAssignableScopes == nil {
+				if len(updates) != 0 {
+					t.Errorf("expected no updates, got %d", len(updates))
+				}
+				return
+			}
+
+			if len(updates) != 1 {
+				t.Fatalf("expected 1 update, got %d", len(updates))
+			}
+
+			u := updates[0]
+			if u.Scope != tt.scope || u.RoleDefinitionName != *existingRoleDefinition.Name {
+				t.Errorf("expected update of %s at %s, got %s at %s", *existingRoleDefinition.Name, tt.scope, u.RoleDefinitionName, u.Scope)
+			}
+
+			var assignableScopes []string
+			for _, s := range u.RoleDefinition.Properties.AssignableScopes {
+				assignableScopes = append(assignableScopes, *s)
+			}
+			if expected := tt.expectedAssignableScopes(tt.scope); !slices.Equal(assignableScopes, expected) {
+				t.Errorf("expected assignable scopes %v, got %v", expected, assignableScopes)
+			}
+		})
+	}
+}
@@ -2,51 +2,21 @@ package role_eligibility_schedule
 
 import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
-	"github.com/ahmetb/go-linq/v3"
 	"github.com/gofrontier-com/sheriff/pkg/core"
-	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
-func FilterForRoleEligibilitySchedulesToDelete(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
-	scope string,
-	existingRoleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule,
-	eligibilitySchedules []*core.Schedule,
-	getPrincipalName func(*msgraphsdkgo.GraphServiceClient, string) (*string, error),
-) (filtered []*armauthorization.RoleEligibilitySchedule, err error) {
-	defer func() {
-		if e, ok := recover().(error); ok {
-			err = e
-		}
-	}()
-
-	linq.From(existingRoleEligibilitySchedules).WhereT(func(r *armauthorization.RoleEligibilitySchedule) bool {
-		any := linq.From(eligibilitySchedules).WhereT(func(a *core.Schedule) bool {
-			roleDefinition, err := role_definition.GetRoleDefinitionById(
-				clientFactory,
-				*r.Properties.RoleDefinitionID,
-			)
-			if err != nil {
-				panic(err)
-			}
-
-			principalName, err := getPrincipalName(
-				graphServiceClient,
-				*r.Properties.PrincipalID,
-			)
-			if err != nil {
-				panic(err)
-			}
+func FilterForRoleEligibilitySchedulesToDelete(eligibilitySchedules *core.KeyedRoleEligibilitySchedules) []*armauthorization.RoleEligibilitySchedule {
+	keySet := make(map[core.ScheduleKey]bool, len(eligibilitySchedules.Keys))
+	for _, k := range eligibilitySchedules.Keys {
+		keySet[k] = true
+	}
 
-			return *r.Properties.Scope == a.Scope &&
-				*roleDefinition.Properties.RoleName == a.RoleName &&
-				*principalName == a.PrincipalName
-		}).Any()
-
-		return !any
-	}).ToSlice(&filtered)
+	var filtered []*armauthorization.RoleEligibilitySchedule
+	for _, s := range eligibilitySchedules.Existing {
+		if !keySet[core.NewScheduleKey(*s.Properties.Scope, *s.Properties.RoleDefinitionID, *s.Properties.PrincipalID)] {
+			filtered = append(filtered, s)
+		}
+	}
 
-	return
+	return filtered
 }
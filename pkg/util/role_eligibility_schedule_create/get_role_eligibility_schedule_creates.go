@@ -1,114 +1,153 @@
 package role_eligibility_schedule_create
 
 import (
+	"context"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/gofrontier-com/sheriff/pkg/core"
 	"github.com/gofrontier-com/sheriff/pkg/util/group"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
 	"github.com/gofrontier-com/sheriff/pkg/util/schedule"
 	"github.com/gofrontier-com/sheriff/pkg/util/schedule_info"
+	"github.com/gofrontier-com/sheriff/pkg/util/service_principal"
 	"github.com/gofrontier-com/sheriff/pkg/util/user"
 	"github.com/google/uuid"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
 func GetRoleEligibilityScheduleCreates(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
 	scope string,
-	groupEligibilitySchedules []*core.Schedule,
-	existingGroupRoleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule,
-	userEligibilitySchedules []*core.Schedule,
-	existingUserRoleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule,
+	groupEligibilitySchedules *core.KeyedRoleEligibilitySchedules,
+	userEligibilitySchedules *core.KeyedRoleEligibilitySchedules,
+	servicePrincipalEligibilitySchedules *core.KeyedRoleEligibilitySchedules,
+	defaults *core.ScheduleRequestDefaults,
 ) ([]*core.RoleEligibilityScheduleCreate, error) {
 	var roleEligibilityScheduleCreates []*core.RoleEligibilityScheduleCreate
 
-	groupEligibilitySchedulesToCreate, err := schedule.FilterForEligibilitySchedulesToCreate(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		groupEligibilitySchedules,
-		existingGroupRoleEligibilitySchedules,
-		group.GetGroupDisplayNameById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	groupEligibilitySchedulesToCreate := schedule.FilterForEligibilitySchedulesToCreate(groupEligibilitySchedules)
 
 	for _, a := range groupEligibilitySchedulesToCreate {
-		roleDefinition, err := role_definition.GetRoleDefinitionByName(clientFactory, scope, a.RoleName)
+		roleDefinition, err := role_definition.GetRoleDefinitionByName(ctx, authorizationClient, scope, a.RoleName)
 		if err != nil {
 			return nil, err
 		}
 
-		group, err := group.GetGroupByName(graphServiceClient, a.PrincipalName)
+		group, err := group.GetGroupByName(ctx, graphClient, a.PrincipalName)
 		if err != nil {
 			return nil, err
 		}
 
-		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(a.StartDateTime, a.EndDateTime)
+		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(a.StartDateTime, a.EndDateTime, a.GetDuration())
 		roleEligibilityScheduleCreates = append(roleEligibilityScheduleCreates, &core.RoleEligibilityScheduleCreate{
+			Condition:     a.Condition,
 			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *group.GetId(),
 			PrincipalName: *group.GetDisplayName(),
 			PrincipalType: armauthorization.PrincipalTypeGroup,
 			RoleEligibilityScheduleRequest: &armauthorization.RoleEligibilityScheduleRequest{
 				Properties: &armauthorization.RoleEligibilityScheduleRequestProperties{
-					Justification:    to.Ptr("Managed by Sheriff"),
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
 					PrincipalID:      group.GetId(),
 					RequestType:      to.Ptr(armauthorization.RequestTypeAdminAssign),
 					RoleDefinitionID: roleDefinition.ID,
 					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleEligibilityTicketInfo(a.GetTicket(defaults)),
 				},
 			},
 			RoleEligibilityScheduleRequestName: uuid.New().String(),
 			RoleName:                           *roleDefinition.Properties.RoleName,
 			Scope:                              a.Scope,
 			StartDateTime:                      scheduleInfo.StartDateTime,
+			Ticket:                             a.GetTicket(defaults),
 		})
 	}
 
-	userEligibilitySchedulesToCreate, err := schedule.FilterForEligibilitySchedulesToCreate(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		userEligibilitySchedules,
-		existingUserRoleEligibilitySchedules,
-		user.GetUserUpnById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	userEligibilitySchedulesToCreate := schedule.FilterForEligibilitySchedulesToCreate(userEligibilitySchedules)
 
 	for _, a := range userEligibilitySchedulesToCreate {
-		roleDefinition, err := role_definition.GetRoleDefinitionByName(clientFactory, scope, a.RoleName)
+		roleDefinition, err := role_definition.GetRoleDefinitionByName(ctx, authorizationClient, scope, a.RoleName)
 		if err != nil {
 			return nil, err
 		}
 
-		user, err := user.GetUserByUpn(graphServiceClient, a.PrincipalName)
+		user, err := user.GetUserByUpn(ctx, graphClient, a.PrincipalName)
 		if err != nil {
 			return nil, err
 		}
 
-		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(a.StartDateTime, a.EndDateTime)
+		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(a.StartDateTime, a.EndDateTime, a.GetDuration())
 		roleEligibilityScheduleCreates = append(roleEligibilityScheduleCreates, &core.RoleEligibilityScheduleCreate{
+			Condition:     a.Condition,
 			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *user.GetId(),
 			PrincipalName: *user.GetUserPrincipalName(),
 			PrincipalType: armauthorization.PrincipalTypeUser,
 			RoleEligibilityScheduleRequest: &armauthorization.RoleEligibilityScheduleRequest{
 				Properties: &armauthorization.RoleEligibilityScheduleRequestProperties{
-					Justification:    to.Ptr("Managed by Sheriff"),
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
 					PrincipalID:      user.GetId(),
 					RequestType:      to.Ptr(armauthorization.RequestTypeAdminAssign),
 					RoleDefinitionID: roleDefinition.ID,
 					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleEligibilityTicketInfo(a.GetTicket(defaults)),
+				},
+			},
+			RoleEligibilityScheduleRequestName: uuid.New().String(),
+			RoleName:                           *roleDefinition.Properties.RoleName,
+			Scope:                              a.Scope,
+			StartDateTime:                      scheduleInfo.StartDateTime,
+			Ticket:                             a.GetTicket(defaults),
+		})
+	}
+
+	servicePrincipalEligibilitySchedulesToCreate := schedule.FilterForEligibilitySchedulesToCreate(servicePrincipalEligibilitySchedules)
+
+	for _, a := range servicePrincipalEligibilitySchedulesToCreate {
+		roleDefinition, err := role_definition.GetRoleDefinitionByName(ctx, authorizationClient, scope, a.RoleName)
+		if err != nil {
+			return nil, err
+		}
+
+		servicePrincipal, err := service_principal.GetServicePrincipalById(ctx, graphClient, a.PrincipalId)
+		if err != nil {
+			return nil, err
+		}
+
+		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(a.StartDateTime, a.EndDateTime, a.GetDuration())
+		roleEligibilityScheduleCreates = append(roleEligibilityScheduleCreates, &core.RoleEligibilityScheduleCreate{
+			Condition:     a.Condition,
+			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *servicePrincipal.GetId(),
+			PrincipalName: *servicePrincipal.GetDisplayName(),
+			PrincipalType: armauthorization.PrincipalTypeServicePrincipal,
+			RoleEligibilityScheduleRequest: &armauthorization.RoleEligibilityScheduleRequest{
+				Properties: &armauthorization.RoleEligibilityScheduleRequestProperties{
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
+					PrincipalID:      servicePrincipal.GetId(),
+					RequestType:      to.Ptr(armauthorization.RequestTypeAdminAssign),
+					RoleDefinitionID: roleDefinition.ID,
+					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleEligibilityTicketInfo(a.GetTicket(defaults)),
 				},
 			},
 			RoleEligibilityScheduleRequestName: uuid.New().String(),
 			RoleName:                           *roleDefinition.Properties.RoleName,
 			Scope:                              a.Scope,
 			StartDateTime:                      scheduleInfo.StartDateTime,
+			Ticket:                             a.GetTicket(defaults),
 		})
 	}
 
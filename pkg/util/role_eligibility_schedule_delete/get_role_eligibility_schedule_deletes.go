@@ -1,50 +1,46 @@
 package role_eligibility_schedule_delete
 
 import (
+	"context"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/gofrontier-com/sheriff/pkg/core"
 	"github.com/gofrontier-com/sheriff/pkg/util/group"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_eligibility_schedule"
+	"github.com/gofrontier-com/sheriff/pkg/util/schedule_info"
+	"github.com/gofrontier-com/sheriff/pkg/util/service_principal"
 	"github.com/gofrontier-com/sheriff/pkg/util/user"
 	"github.com/google/uuid"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
 func GetRoleEligibilityScheduleDeletes(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
 	scope string,
-	groupEligibilitySchedules []*core.Schedule,
-	existingGroupRoleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule,
-	userEligibilitySchedules []*core.Schedule,
-	existingUserRoleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule,
+	groupEligibilitySchedules *core.KeyedRoleEligibilitySchedules,
+	userEligibilitySchedules *core.KeyedRoleEligibilitySchedules,
+	servicePrincipalEligibilitySchedules *core.KeyedRoleEligibilitySchedules,
+	defaults *core.ScheduleRequestDefaults,
 ) ([]*core.RoleEligibilityScheduleDelete, error) {
 	var roleEligibilityScheduleDeletes []*core.RoleEligibilityScheduleDelete
 
-	groupEligibilitySchedulesToDelete, err := role_eligibility_schedule.FilterForRoleEligibilitySchedulesToDelete(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		existingGroupRoleEligibilitySchedules,
-		groupEligibilitySchedules,
-		group.GetGroupDisplayNameById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	groupEligibilitySchedulesToDelete := role_eligibility_schedule.FilterForRoleEligibilitySchedulesToDelete(groupEligibilitySchedules)
 
 	for _, s := range groupEligibilitySchedulesToDelete {
 		roleDefinition, err := role_definition.GetRoleDefinitionById(
-			clientFactory,
+			ctx,
+			authorizationClient,
 			*s.Properties.RoleDefinitionID,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		group, err := group.GetGroupById(graphServiceClient, *s.Properties.PrincipalID)
+		group, err := group.GetGroupById(ctx, graphClient, *s.Properties.PrincipalID)
 		if err != nil {
 			return nil, err
 		}
@@ -53,26 +49,31 @@ func GetRoleEligibilityScheduleDeletes(
 			roleEligibilityScheduleDeletes = append(roleEligibilityScheduleDeletes, &core.RoleEligibilityScheduleDelete{
 				Cancel:        false,
 				EndDateTime:   s.Properties.EndDateTime,
+				Justification: defaults.Justification,
+				PrincipalId:   *s.Properties.PrincipalID,
 				PrincipalName: *group.GetDisplayName(),
 				PrincipalType: armauthorization.PrincipalTypeGroup,
 				RoleEligibilityScheduleRequest: &armauthorization.RoleEligibilityScheduleRequest{
 					Properties: &armauthorization.RoleEligibilityScheduleRequestProperties{
-						Justification:                   to.Ptr("Managed by Sheriff"),
+						Justification:                   to.Ptr(defaults.Justification),
 						PrincipalID:                     s.Properties.PrincipalID,
 						RequestType:                     to.Ptr(armauthorization.RequestTypeAdminRemove),
 						RoleDefinitionID:                s.Properties.RoleDefinitionID,
 						TargetRoleEligibilityScheduleID: s.ID,
+						TicketInfo:                      schedule_info.GetRoleEligibilityTicketInfo(defaults.Ticket),
 					},
 				},
 				RoleEligibilityScheduleRequestName: uuid.New().String(),
 				RoleName:                           *roleDefinition.Properties.RoleName,
 				Scope:                              *s.Properties.Scope,
 				StartDateTime:                      s.Properties.StartDateTime,
+				Ticket:                             defaults.Ticket,
 			})
 		} else {
 			roleEligibilityScheduleDeletes = append(roleEligibilityScheduleDeletes, &core.RoleEligibilityScheduleDelete{
 				Cancel:                             true,
 				EndDateTime:                        s.Properties.EndDateTime,
+				PrincipalId:                        *s.Properties.PrincipalID,
 				PrincipalName:                      *group.GetDisplayName(),
 				PrincipalType:                      armauthorization.PrincipalTypeGroup,
 				RoleEligibilityScheduleRequestName: *s.Name,
@@ -83,28 +84,19 @@ func GetRoleEligibilityScheduleDeletes(
 		}
 	}
 
-	userEligibilitySchedulesToDelete, err := role_eligibility_schedule.FilterForRoleEligibilitySchedulesToDelete(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		existingUserRoleEligibilitySchedules,
-		userEligibilitySchedules,
-		user.GetUserUpnById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	userEligibilitySchedulesToDelete := role_eligibility_schedule.FilterForRoleEligibilitySchedulesToDelete(userEligibilitySchedules)
 
 	for _, s := range userEligibilitySchedulesToDelete {
 		roleDefinition, err := role_definition.GetRoleDefinitionById(
-			clientFactory,
+			ctx,
+			authorizationClient,
 			*s.Properties.RoleDefinitionID,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		user, err := user.GetUserById(graphServiceClient, *s.Properties.PrincipalID)
+		user, err := user.GetUserById(ctx, graphClient, *s.Properties.PrincipalID)
 		if err != nil {
 			return nil, err
 		}
@@ -113,26 +105,31 @@ func GetRoleEligibilityScheduleDeletes(
 			roleEligibilityScheduleDeletes = append(roleEligibilityScheduleDeletes, &core.RoleEligibilityScheduleDelete{
 				Cancel:        false,
 				EndDateTime:   s.Properties.EndDateTime,
+				Justification: defaults.Justification,
+				PrincipalId:   *s.Properties.PrincipalID,
 				PrincipalName: *user.GetUserPrincipalName(),
 				PrincipalType: armauthorization.PrincipalTypeUser,
 				RoleEligibilityScheduleRequest: &armauthorization.RoleEligibilityScheduleRequest{
 					Properties: &armauthorization.RoleEligibilityScheduleRequestProperties{
-						Justification:                   to.Ptr("Managed by Sheriff"),
+						Justification:                   to.Ptr(defaults.Justification),
 						PrincipalID:                     s.Properties.PrincipalID,
 						RequestType:                     to.Ptr(armauthorization.RequestTypeAdminRemove),
 						RoleDefinitionID:                s.Properties.RoleDefinitionID,
 						TargetRoleEligibilityScheduleID: s.ID,
+						TicketInfo:                      schedule_info.GetRoleEligibilityTicketInfo(defaults.Ticket),
 					},
 				},
 				RoleEligibilityScheduleRequestName: uuid.New().String(),
 				RoleName:                           *roleDefinition.Properties.RoleName,
 				Scope:                              *s.Properties.Scope,
 				StartDateTime:                      s.Properties.StartDateTime,
+				Ticket:                             defaults.Ticket,
 			})
 		} else {
 			roleEligibilityScheduleDeletes = append(roleEligibilityScheduleDeletes, &core.RoleEligibilityScheduleDelete{
 				Cancel:                             true,
 				EndDateTime:                        s.Properties.EndDateTime,
+				PrincipalId:                        *s.Properties.PrincipalID,
 				PrincipalName:                      *user.GetUserPrincipalName(),
 				PrincipalType:                      armauthorization.PrincipalTypeUser,
 				RoleEligibilityScheduleRequestName: *s.Name,
@@ -143,5 +140,61 @@ func GetRoleEligibilityScheduleDeletes(
 		}
 	}
 
+	servicePrincipalEligibilitySchedulesToDelete := role_eligibility_schedule.FilterForRoleEligibilitySchedulesToDelete(servicePrincipalEligibilitySchedules)
+
+	for _, s := range servicePrincipalEligibilitySchedulesToDelete {
+		roleDefinition, err := role_definition.GetRoleDefinitionById(
+			ctx,
+			authorizationClient,
+			*s.Properties.RoleDefinitionID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		servicePrincipal, err := service_principal.GetServicePrincipalById(ctx, graphClient, *s.Properties.PrincipalID)
+		if err != nil {
+			return nil, err
+		}
+
+		if *s.Properties.Status == armauthorization.StatusProvisioned {
+			roleEligibilityScheduleDeletes = append(roleEligibilityScheduleDeletes, &core.RoleEligibilityScheduleDelete{
+				Cancel:        false,
+				EndDateTime:   s.Properties.EndDateTime,
+				Justification: defaults.Justification,
+				PrincipalId:   *s.Properties.PrincipalID,
+				PrincipalName: *servicePrincipal.GetDisplayName(),
+				PrincipalType: armauthorization.PrincipalTypeServicePrincipal,
+				RoleEligibilityScheduleRequest: &armauthorization.RoleEligibilityScheduleRequest{
+					Properties: &armauthorization.RoleEligibilityScheduleRequestProperties{
+						Justification:                   to.Ptr(defaults.Justification),
+						PrincipalID:                     s.Properties.PrincipalID,
+						RequestType:                     to.Ptr(armauthorization.RequestTypeAdminRemove),
+						RoleDefinitionID:                s.Properties.RoleDefinitionID,
+						TargetRoleEligibilityScheduleID: s.ID,
+						TicketInfo:                      schedule_info.GetRoleEligibilityTicketInfo(defaults.Ticket),
+					},
+				},
+				RoleEligibilityScheduleRequestName: uuid.New().String(),
+				RoleName:                           *roleDefinition.Properties.RoleName,
+				Scope:                              *s.Properties.Scope,
+				StartDateTime:                      s.Properties.StartDateTime,
+				Ticket:                             defaults.Ticket,
+			})
+		} else {
+			roleEligibilityScheduleDeletes = append(roleEligibilityScheduleDeletes, &core.RoleEligibilityScheduleDelete{
+				Cancel:                             true,
+				EndDateTime:                        s.Properties.EndDateTime,
+				PrincipalId:                        *s.Properties.PrincipalID,
+				PrincipalName:                      *servicePrincipal.GetDisplayName(),
+				PrincipalType:                      armauthorization.PrincipalTypeServicePrincipal,
+				RoleEligibilityScheduleRequestName: *s.Name,
+				RoleName:                           *roleDefinition.Properties.RoleName,
+				Scope:                              *s.Properties.Scope,
+				StartDateTime:                      s.Properties.StartDateTime,
+			})
+		}
+	}
+
 	return roleEligibilityScheduleDeletes, nil
 }
@@ -1,49 +1,40 @@
 package role_eligibility_schedule_update
 
 import (
-	"fmt"
-	"slices"
+	"context"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
-	"github.com/ahmetb/go-linq/v3"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/gofrontier-com/sheriff/pkg/core"
 	"github.com/gofrontier-com/sheriff/pkg/util/group"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
 	"github.com/gofrontier-com/sheriff/pkg/util/schedule"
 	"github.com/gofrontier-com/sheriff/pkg/util/schedule_info"
+	"github.com/gofrontier-com/sheriff/pkg/util/service_principal"
 	"github.com/gofrontier-com/sheriff/pkg/util/user"
 	"github.com/google/uuid"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
 func GetRoleEligibilityScheduleUpdates(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
 	scope string,
-	groupEligibilitySchedules []*core.Schedule,
-	existingGroupRoleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule,
-	userEligibilitySchedules []*core.Schedule,
-	existingUserRoleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule,
+	groupEligibilitySchedules *core.KeyedRoleEligibilitySchedules,
+	userEligibilitySchedules *core.KeyedRoleEligibilitySchedules,
+	servicePrincipalEligibilitySchedules *core.KeyedRoleEligibilitySchedules,
+	defaults *core.ScheduleRequestDefaults,
 ) ([]*core.RoleEligibilityScheduleUpdate, error) {
 	var roleEligibilityScheduleUpdates []*core.RoleEligibilityScheduleUpdate
 
-	groupEligibilitySchedulesToUpdate, err := schedule.FilterForEligibilitySchedulesToUpdate(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		groupEligibilitySchedules,
-		existingGroupRoleEligibilitySchedules,
-		group.GetGroupDisplayNameById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	groupEligibilitySchedulesToUpdate := schedule.FilterForEligibilitySchedulesToUpdate(groupEligibilitySchedules)
 
 	for _, a := range groupEligibilitySchedulesToUpdate {
 		roleDefinition, err := role_definition.GetRoleDefinitionByName(
-			clientFactory,
+			ctx,
+			authorizationClient,
 			scope,
 			a.RoleName,
 		)
@@ -51,73 +42,56 @@ func GetRoleEligibilityScheduleUpdates(
 			return nil, err
 		}
 
-		group, err := group.GetGroupByName(graphServiceClient, a.PrincipalName)
+		group, err := group.GetGroupByName(ctx, graphClient, a.PrincipalName)
 		if err != nil {
 			return nil, err
 		}
 
-		existingGroupRoleEligibilityScheduleIdx := slices.IndexFunc(existingGroupRoleEligibilitySchedules, func(s *armauthorization.RoleEligibilitySchedule) bool {
-			return *s.Properties.Scope == a.Scope &&
-				*s.Properties.RoleDefinitionID == *roleDefinition.ID &&
-				*s.Properties.PrincipalID == *group.GetId()
-		})
-		existingGroupRoleEligibilityScheduleIdx2 := linq.From(existingGroupRoleEligibilitySchedules).IndexOfT(func(s *armauthorization.RoleEligibilitySchedule) bool {
-			return *s.Properties.Scope == a.Scope &&
-				*s.Properties.RoleDefinitionID == *roleDefinition.ID &&
-				*s.Properties.PrincipalID == *group.GetId()
-		})
-		if existingGroupRoleEligibilityScheduleIdx != existingGroupRoleEligibilityScheduleIdx2 {
-			panic("index mismatch")
-		}
-		if existingGroupRoleEligibilityScheduleIdx == -1 {
-			return nil, fmt.Errorf("existing role eligibility schedule not found")
-		}
-
-		existingGroupRoleEligibilitySchedule := existingGroupRoleEligibilitySchedules[existingGroupRoleEligibilityScheduleIdx]
+		existingGroupRoleEligibilitySchedule := groupEligibilitySchedules.ExistingIndex[groupEligibilitySchedules.Keys[a]]
 
 		var startTime *time.Time
 		if a.StartDateTime != nil {
 			startTime = a.StartDateTime
-		} else {
+		} else if !a.IsDueForRenewal(existingGroupRoleEligibilitySchedule.Properties.EndDateTime) {
+			// Keep the existing start time unless the schedule is being renewed, in which case
+			// it starts now.
 			startTime = existingGroupRoleEligibilitySchedule.Properties.StartDateTime
 		}
 
-		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(startTime, a.EndDateTime)
+		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(startTime, a.EndDateTime, a.GetDuration())
 		roleEligibilityScheduleUpdates = append(roleEligibilityScheduleUpdates, &core.RoleEligibilityScheduleUpdate{
+			Condition:     a.Condition,
 			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *group.GetId(),
 			PrincipalName: *group.GetDisplayName(),
 			PrincipalType: armauthorization.PrincipalTypeGroup,
 			RoleEligibilityScheduleRequest: &armauthorization.RoleEligibilityScheduleRequest{
 				Properties: &armauthorization.RoleEligibilityScheduleRequestProperties{
-					Justification:    to.Ptr("Managed by Sheriff"),
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
 					PrincipalID:      group.GetId(),
 					RequestType:      to.Ptr(armauthorization.RequestTypeAdminUpdate),
 					RoleDefinitionID: roleDefinition.ID,
 					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleEligibilityTicketInfo(a.GetTicket(defaults)),
 				},
 			},
 			RoleEligibilityScheduleRequestName: uuid.New().String(),
 			RoleName:                           *roleDefinition.Properties.RoleName,
 			Scope:                              *existingGroupRoleEligibilitySchedule.Properties.Scope,
 			StartDateTime:                      scheduleInfo.StartDateTime,
+			Ticket:                             a.GetTicket(defaults),
 		})
 	}
 
-	userEligibilitySchedulesToUpdate, err := schedule.FilterForEligibilitySchedulesToUpdate(
-		clientFactory,
-		graphServiceClient,
-		scope,
-		userEligibilitySchedules,
-		existingUserRoleEligibilitySchedules,
-		user.GetUserUpnById,
-	)
-	if err != nil {
-		return nil, err
-	}
+	userEligibilitySchedulesToUpdate := schedule.FilterForEligibilitySchedulesToUpdate(userEligibilitySchedules)
 
 	for _, a := range userEligibilitySchedulesToUpdate {
 		roleDefinition, err := role_definition.GetRoleDefinitionByName(
-			clientFactory,
+			ctx,
+			authorizationClient,
 			scope,
 			a.RoleName,
 		)
@@ -125,55 +99,104 @@ func GetRoleEligibilityScheduleUpdates(
 			return nil, err
 		}
 
-		user, err := user.GetUserByUpn(graphServiceClient, a.PrincipalName)
+		user, err := user.GetUserByUpn(ctx, graphClient, a.PrincipalName)
 		if err != nil {
 			return nil, err
 		}
 
-		existingUserRoleEligibilityScheduleIdx := slices.IndexFunc(existingUserRoleEligibilitySchedules, func(s *armauthorization.RoleEligibilitySchedule) bool {
-			return *s.Properties.Scope == a.Scope &&
-				*s.Properties.RoleDefinitionID == *roleDefinition.ID &&
-				*s.Properties.PrincipalID == *user.GetId()
-		})
-		existingUserRoleEligibilityScheduleIdx2 := linq.From(existingUserRoleEligibilitySchedules).IndexOfT(func(s *armauthorization.RoleEligibilitySchedule) bool {
-			return *s.Properties.Scope == a.Scope &&
-				*s.Properties.RoleDefinitionID == *roleDefinition.ID &&
-				*s.Properties.PrincipalID == *user.GetId()
-		})
-		if existingUserRoleEligibilityScheduleIdx != existingUserRoleEligibilityScheduleIdx2 {
-			panic("index mismatch")
-		}
-		if existingUserRoleEligibilityScheduleIdx == -1 {
-			return nil, fmt.Errorf("existing role eligibility schedule not found")
-		}
-
-		existingUserRoleEligibilitySchedule := existingUserRoleEligibilitySchedules[existingUserRoleEligibilityScheduleIdx]
+		existingUserRoleEligibilitySchedule := userEligibilitySchedules.ExistingIndex[userEligibilitySchedules.Keys[a]]
 
 		var startTime *time.Time
 		if a.StartDateTime != nil {
 			startTime = a.StartDateTime
-		} else {
+		} else if !a.IsDueForRenewal(existingUserRoleEligibilitySchedule.Properties.EndDateTime) {
+			// Keep the existing start time unless the schedule is being renewed, in which case
+			// it starts now.
 			startTime = existingUserRoleEligibilitySchedule.Properties.StartDateTime
 		}
 
-		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(startTime, a.EndDateTime)
+		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(startTime, a.EndDateTime, a.GetDuration())
 		roleEligibilityScheduleUpdates = append(roleEligibilityScheduleUpdates, &core.RoleEligibilityScheduleUpdate{
+			Condition:     a.Condition,
 			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *user.GetId(),
 			PrincipalName: *user.GetUserPrincipalName(),
-			PrincipalType: armauthorization.PrincipalTypeGroup,
+			PrincipalType: armauthorization.PrincipalTypeUser,
 			RoleEligibilityScheduleRequest: &armauthorization.RoleEligibilityScheduleRequest{
 				Properties: &armauthorization.RoleEligibilityScheduleRequestProperties{
-					Justification:    to.Ptr("Managed by Sheriff"),
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
 					PrincipalID:      user.GetId(),
 					RequestType:      to.Ptr(armauthorization.RequestTypeAdminUpdate),
 					RoleDefinitionID: roleDefinition.ID,
 					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleEligibilityTicketInfo(a.GetTicket(defaults)),
 				},
 			},
 			RoleEligibilityScheduleRequestName: uuid.New().String(),
 			RoleName:                           *roleDefinition.Properties.RoleName,
 			Scope:                              *existingUserRoleEligibilitySchedule.Properties.Scope,
 			StartDateTime:                      scheduleInfo.StartDateTime,
+			Ticket:                             a.GetTicket(defaults),
+		})
+	}
+
+	servicePrincipalEligibilitySchedulesToUpdate := schedule.FilterForEligibilitySchedulesToUpdate(servicePrincipalEligibilitySchedules)
+
+	for _, a := range servicePrincipalEligibilitySchedulesToUpdate {
+		roleDefinition, err := role_definition.GetRoleDefinitionByName(
+			ctx,
+			authorizationClient,
+			scope,
+			a.RoleName,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		servicePrincipal, err := service_principal.GetServicePrincipalById(ctx, graphClient, a.PrincipalId)
+		if err != nil {
+			return nil, err
+		}
+
+		existingServicePrincipalRoleEligibilitySchedule := servicePrincipalEligibilitySchedules.ExistingIndex[servicePrincipalEligibilitySchedules.Keys[a]]
+
+		var startTime *time.Time
+		if a.StartDateTime != nil {
+			startTime = a.StartDateTime
+		} else if !a.IsDueForRenewal(existingServicePrincipalRoleEligibilitySchedule.Properties.EndDateTime) {
+			// Keep the existing start time unless the schedule is being renewed, in which case
+			// it starts now.
+			startTime = existingServicePrincipalRoleEligibilitySchedule.Properties.StartDateTime
+		}
+
+		scheduleInfo := schedule_info.GetRoleEligibilityScheduleInfo(startTime, a.EndDateTime, a.GetDuration())
+		roleEligibilityScheduleUpdates = append(roleEligibilityScheduleUpdates, &core.RoleEligibilityScheduleUpdate{
+			Condition:     a.Condition,
+			EndDateTime:   scheduleInfo.Expiration.EndDateTime,
+			Justification: a.GetJustification(defaults),
+			PrincipalId:   *servicePrincipal.GetId(),
+			PrincipalName: *servicePrincipal.GetDisplayName(),
+			PrincipalType: armauthorization.PrincipalTypeServicePrincipal,
+			RoleEligibilityScheduleRequest: &armauthorization.RoleEligibilityScheduleRequest{
+				Properties: &armauthorization.RoleEligibilityScheduleRequestProperties{
+					Condition:        a.GetCondition(),
+					ConditionVersion: a.GetConditionVersion(),
+					Justification:    to.Ptr(a.GetJustification(defaults)),
+					PrincipalID:      servicePrincipal.GetId(),
+					RequestType:      to.Ptr(armauthorization.RequestTypeAdminUpdate),
+					RoleDefinitionID: roleDefinition.ID,
+					ScheduleInfo:     scheduleInfo,
+					TicketInfo:       schedule_info.GetRoleEligibilityTicketInfo(a.GetTicket(defaults)),
+				},
+			},
+			RoleEligibilityScheduleRequestName: uuid.New().String(),
+			RoleName:                           *roleDefinition.Properties.RoleName,
+			Scope:                              *existingServicePrincipalRoleEligibilitySchedule.Properties.Scope,
+			StartDateTime:                      scheduleInfo.StartDateTime,
+			Ticket:                             a.GetTicket(defaults),
 		})
 	}
 
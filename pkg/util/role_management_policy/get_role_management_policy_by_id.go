@@ -2,23 +2,22 @@ package role_management_policy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 )
 
-func GetRoleManagementPolicyById(clientFactory *armauthorization.ClientFactory, scope string, roleManagementPolicyId string) (*armauthorization.RoleManagementPolicy, error) {
-	roleManagementPoliciesClient := clientFactory.NewRoleManagementPoliciesClient()
-
-	response, err := roleManagementPoliciesClient.Get(context.Background(), scope, roleManagementPolicyId, nil)
+func GetRoleManagementPolicyById(ctx context.Context, authorizationClient backend.AuthorizationClient, scope string, roleManagementPolicyId string) (*armauthorization.RoleManagementPolicy, error) {
+	roleManagementPolicy, err := authorizationClient.GetRoleManagementPolicy(ctx, scope, roleManagementPolicyId)
 	if err != nil {
-		return nil, err
-	}
+		if errors.Is(err, backend.ErrNotFound) {
+			return nil, fmt.Errorf("role management policy with Id '%s' not found", roleManagementPolicyId)
+		}
 
-	// There's a bug in the SDK where the response is not nil even if you pass a bad Id.
-	if response.RoleManagementPolicy.ID == nil {
-		return nil, fmt.Errorf("role management policy with Id '%s' not found", roleManagementPolicyId)
+		return nil, err
 	}
 
-	return &response.RoleManagementPolicy, err
+	return roleManagementPolicy, nil
 }
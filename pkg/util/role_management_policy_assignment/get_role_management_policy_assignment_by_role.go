@@ -1,14 +1,17 @@
 package role_management_policy_assignment
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 )
 
-func GetRoleManagementPolicyAssignmentByRole(clientFactory *armauthorization.ClientFactory, scope string, roleName string) (*armauthorization.RoleManagementPolicyAssignment, error) {
+func GetRoleManagementPolicyAssignmentByRole(ctx context.Context, authorizationClient backend.AuthorizationClient, scope string, roleName string) (*armauthorization.RoleManagementPolicyAssignment, error) {
 	roleManagementPolicyAssignments, err := GetRoleManagementPolicyAssignments(
-		clientFactory,
+		ctx,
+		authorizationClient,
 		scope,
 		func(r *armauthorization.RoleManagementPolicyAssignment) bool {
 			return *r.Properties.PolicyAssignmentProperties.RoleDefinition.DisplayName == roleName
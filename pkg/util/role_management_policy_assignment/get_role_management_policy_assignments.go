@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	gocache "github.com/patrickmn/go-cache"
 )
 
@@ -14,25 +15,20 @@ func init() {
 	cache = *gocache.New(gocache.NoExpiration, gocache.NoExpiration)
 }
 
-func GetRoleManagementPolicyAssignments(clientFactory *armauthorization.ClientFactory, scope string, filter func(*armauthorization.RoleManagementPolicyAssignment) bool) ([]*armauthorization.RoleManagementPolicyAssignment, error) {
+func GetRoleManagementPolicyAssignments(ctx context.Context, authorizationClient backend.AuthorizationClient, scope string, filter func(*armauthorization.RoleManagementPolicyAssignment) bool) ([]*armauthorization.RoleManagementPolicyAssignment, error) {
 	var roleManagementPolicyAssignments []*armauthorization.RoleManagementPolicyAssignment
 	cacheKey := fmt.Sprintf("roleManagementPolicyAssignments_%s", scope)
 
 	if a, found := cache.Get(cacheKey); found {
 		roleManagementPolicyAssignments = a.([]*armauthorization.RoleManagementPolicyAssignment)
 	} else {
-		roleManagementPolicyAssignmentsClient := clientFactory.NewRoleManagementPolicyAssignmentsClient()
-
-		pager := roleManagementPolicyAssignmentsClient.NewListForScopePager(scope, nil)
-		for pager.More() {
-			page, err := pager.NextPage(context.Background())
-			if err != nil {
-				return nil, err
-			}
-
-			roleManagementPolicyAssignments = append(roleManagementPolicyAssignments, page.Value...)
+		result, err := authorizationClient.ListRoleManagementPolicyAssignments(ctx, scope)
+		if err != nil {
+			return nil, err
 		}
 
+		roleManagementPolicyAssignments = result
+
 		cache.Set(cacheKey, roleManagementPolicyAssignments, gocache.NoExpiration)
 	}
 
@@ -0,0 +1,38 @@
+package role_management_policy_classification_rule
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/go-test/deep"
+)
+
+// GetChangedRuleIds gets the Ids of the desired rules that differ from the current rule with the same Id, or
+// that have no current rule with the same Id. Differences in claim value are ignored, as they are when deciding
+// whether a role management policy needs to be updated.
+func GetChangedRuleIds(currentRules, desiredRules []armauthorization.RoleManagementPolicyRuleClassification) []string {
+	var changedRuleIds []string
+	for _, desiredRule := range desiredRules {
+		id := *desiredRule.GetRoleManagementPolicyRule().ID
+
+		idx := slices.IndexFunc(currentRules, func(r armauthorization.RoleManagementPolicyRuleClassification) bool {
+			return *r.GetRoleManagementPolicyRule().ID == id
+		})
+		if idx == -1 {
+			changedRuleIds = append(changedRuleIds, id)
+			continue
+		}
+
+		diff := slices.DeleteFunc(deep.Equal(currentRules[idx], desiredRule), func(d string) bool {
+			return strings.HasPrefix(d, "ClaimValue:")
+		})
+		if len(diff) > 0 {
+			changedRuleIds = append(changedRuleIds, id)
+		}
+	}
+
+	slices.Sort(changedRuleIds)
+
+	return changedRuleIds
+}
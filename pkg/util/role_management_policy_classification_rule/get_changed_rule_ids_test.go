@@ -0,0 +1,45 @@
+package role_management_policy_classification_rule
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+)
+
+func newExpirationRule(id string, isExpirationRequired bool) *armauthorization.RoleManagementPolicyExpirationRule {
+	return &armauthorization.RoleManagementPolicyExpirationRule{
+		ID:                   to.Ptr(id),
+		IsExpirationRequired: to.Ptr(isExpirationRequired),
+		RuleType:             to.Ptr(armauthorization.RoleManagementPolicyRuleTypeRoleManagementPolicyExpirationRule),
+	}
+}
+
+func TestGetChangedRuleIds(t *testing.T) {
+	currentRules := []armauthorization.RoleManagementPolicyRuleClassification{
+		newExpirationRule("Expiration_Admin_Assignment", true),
+		newExpirationRule("Expiration_Admin_Eligibility", true),
+		&armauthorization.RoleManagementPolicyAuthenticationContextRule{
+			ID:       to.Ptr("AuthenticationContext_EndUser_Assignment"),
+			RuleType: to.Ptr(armauthorization.RoleManagementPolicyRuleTypeRoleManagementPolicyAuthenticationContextRule),
+		},
+	}
+	desiredRules := []armauthorization.RoleManagementPolicyRuleClassification{
+		&armauthorization.RoleManagementPolicyAuthenticationContextRule{
+			ClaimValue: to.Ptr(""),
+			ID:         to.Ptr("AuthenticationContext_EndUser_Assignment"),
+			RuleType:   to.Ptr(armauthorization.RoleManagementPolicyRuleTypeRoleManagementPolicyAuthenticationContextRule),
+		},
+		newExpirationRule("Expiration_EndUser_Assignment", true),
+		newExpirationRule("Expiration_Admin_Eligibility", false),
+		newExpirationRule("Expiration_Admin_Assignment", true),
+	}
+
+	changedRuleIds := GetChangedRuleIds(currentRules, desiredRules)
+
+	expected := []string{"Expiration_Admin_Eligibility", "Expiration_EndUser_Assignment"}
+	if !slices.Equal(changedRuleIds, expected) {
+		t.Errorf("expected %v, got %v", expected, changedRuleIds)
+	}
+}
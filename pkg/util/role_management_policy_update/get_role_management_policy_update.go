@@ -1,6 +1,7 @@
 package role_management_policy_update
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"slices"
@@ -10,6 +11,7 @@ import (
 	"github.com/ahmetb/go-linq/v3"
 	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/go-test/deep"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/gofrontier-com/sheriff/pkg/core"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_management_policy"
 	"github.com/gofrontier-com/sheriff/pkg/util/role_management_policy_assignment"
@@ -17,16 +19,23 @@ import (
 )
 
 func GetRoleManagementPolicyUpdates(
-	clientFactory *armauthorization.ClientFactory,
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
 	defaultRoleManagementPolicyPropertiesData string,
 	config *core.AzureRmConfig,
-	subscriptionId string,
+	scope string,
+	pendingRoleNames []string,
 ) ([]*core.RoleManagementPolicyUpdate, error) {
 	var roleManagementPolicyUpdates []*core.RoleManagementPolicyUpdate
 
-	scopeRoleNameCombinations := config.GetScopeRoleNameCombinations(subscriptionId)
+	scopeRoleNameCombinations := config.GetScopeRoleNameCombinations(scope)
 
 	for _, c := range scopeRoleNameCombinations {
+		// Roles that are yet to be created have no role management policy to update.
+		if slices.Contains(pendingRoleNames, c.RoleName) {
+			continue
+		}
+
 		var desiredRoleManagementPolicyProperties armauthorization.RoleManagementPolicyProperties
 		err := desiredRoleManagementPolicyProperties.UnmarshalJSON([]byte(defaultRoleManagementPolicyPropertiesData))
 		if err != nil {
@@ -40,24 +49,25 @@ func GetRoleManagementPolicyUpdates(
 
 		policy := config.GetPolicyByRoleName(c.RoleName)
 
+		// Rulesets are applied in the order that they are referenced by the policy, so that where
+		// rulesets patch the same rule, later rulesets take precedence.
 		var roleManagementPolicyRulesets []*core.RoleManagementPolicyRuleset
 		if policy != nil {
-			roleManagementPolicyRulesetReferences := policy.GetRulesetReferencesForScope(c.Scope, subscriptionId)
-
-			var rulesetNames []string
-			linq.From(roleManagementPolicyRulesetReferences).SelectT(func(r *core.RulesetReference) string {
-				return r.RulesetName
-			}).ToSlice(&rulesetNames)
+			rulesetReferences, err := policy.GetRulesetReferencesForScope(c.Scope)
+			if err != nil {
+				return nil, err
+			}
 
-			linq.From(config.Rulesets).WhereT(func(s *core.RoleManagementPolicyRuleset) bool {
-				return linq.From(rulesetNames).Contains(s.Name)
-			}).ToSlice(&roleManagementPolicyRulesets)
+			for _, r := range rulesetReferences {
+				idx := slices.IndexFunc(config.Rulesets, func(s *core.RoleManagementPolicyRuleset) bool {
+					return s.Name == r.RulesetName
+				})
+				if idx == -1 {
+					return nil, fmt.Errorf("ruleset '%s' not found", r.RulesetName)
+				}
 
-			if len(roleManagementPolicyRulesets) != len(rulesetNames) {
-				panic("ruleset count does not match ruleset reference count")
+				roleManagementPolicyRulesets = append(roleManagementPolicyRulesets, config.Rulesets[idx])
 			}
-		} else {
-			roleManagementPolicyRulesets = []*core.RoleManagementPolicyRuleset{}
 		}
 
 		for _, roleManagementPolicyRuleset := range roleManagementPolicyRulesets {
@@ -189,7 +199,8 @@ func GetRoleManagementPolicyUpdates(
 		}
 
 		roleManagementPolicyAssignment, err := role_management_policy_assignment.GetRoleManagementPolicyAssignmentByRole(
-			clientFactory,
+			ctx,
+			authorizationClient,
 			c.Scope,
 			c.RoleName,
 		)
@@ -212,7 +223,8 @@ func GetRoleManagementPolicyUpdates(
 		if len(diff) > 0 {
 			roleManagementPolicyIdParts := strings.Split(*roleManagementPolicyAssignment.Properties.PolicyID, "/")
 			roleManagementPolicy, err := role_management_policy.GetRoleManagementPolicyById(
-				clientFactory,
+				ctx,
+				authorizationClient,
 				*roleManagementPolicyAssignment.Properties.Scope,
 				roleManagementPolicyIdParts[len(roleManagementPolicyIdParts)-1],
 			)
@@ -222,6 +234,10 @@ func GetRoleManagementPolicyUpdates(
 
 			roleManagementPolicy.Properties.Rules = desiredRoleManagementPolicyProperties.Rules
 			roleManagementPolicyUpdates = append(roleManagementPolicyUpdates, &core.RoleManagementPolicyUpdate{
+				ChangedRuleIds: role_management_policy_classification_rule.GetChangedRuleIds(
+					roleManagementPolicyAssignment.Properties.EffectiveRules,
+					desiredRoleManagementPolicyProperties.Rules,
+				),
 				RoleManagementPolicy: roleManagementPolicy,
 				RoleName:             *roleManagementPolicyAssignment.Properties.PolicyAssignmentProperties.RoleDefinition.DisplayName,
 				Scope:                *roleManagementPolicyAssignment.Properties.PolicyAssignmentProperties.Scope.ID,
@@ -0,0 +1,175 @@
+package saved_plan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+type scheduleState struct {
+	Condition        *string                  `json:"condition,omitempty"`
+	EndDateTime      *time.Time               `json:"endDateTime,omitempty"`
+	ID               *string                  `json:"id"`
+	PrincipalID      *string                  `json:"principalId"`
+	RoleDefinitionID *string                  `json:"roleDefinitionId"`
+	Scope            *string                  `json:"scope"`
+	StartDateTime    *time.Time               `json:"startDateTime,omitempty"`
+	Status           *armauthorization.Status `json:"status"`
+}
+
+type policyState struct {
+	EffectiveRules []armauthorization.RoleManagementPolicyRuleClassification `json:"effectiveRules"`
+	ID             *string                                                   `json:"id"`
+	PolicyID       *string                                                   `json:"policyId"`
+}
+
+type roleDefinitionState struct {
+	AssignableScopes []*string                      `json:"assignableScopes"`
+	Description      *string                        `json:"description,omitempty"`
+	ID               *string                        `json:"id"`
+	Permissions      []*armauthorization.Permission `json:"permissions"`
+	RoleName         *string                        `json:"roleName"`
+}
+
+type state struct {
+	RoleAssignmentSchedules  []*scheduleState       `json:"roleAssignmentSchedules"`
+	RoleDefinitions          []*roleDefinitionState `json:"roleDefinitions"`
+	RoleEligibilitySchedules []*scheduleState       `json:"roleEligibilitySchedules"`
+	RoleManagementPolicies   []*policyState         `json:"roleManagementPolicies"`
+}
+
+// GetStateHash gets a hash of the live state that a plan of the given config at the given scopes is based on:
+// the custom role definitions and the role assignment and eligibility schedules at each scope, and the role
+// management policies of every role that the config references at each scope, whether or not the plan updates
+// them. It is read directly from the backend, rather than from cache, so that the hash of a saved plan can be
+// compared with the live state when the plan is applied.
+func GetStateHash(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	config *core.AzureRmConfig,
+	scopes []string,
+) (string, error) {
+	s := &state{}
+
+	for _, scope := range scopes {
+		roleDefinitions, err := authorizationClient.ListCustomRoleDefinitions(ctx, scope)
+		if err != nil {
+			return "", err
+		}
+
+		for _, r := range roleDefinitions {
+			// Custom role definitions are visible at every scope that they are assignable to, so are only
+			// included once.
+			alreadyIncluded := slices.ContainsFunc(s.RoleDefinitions, func(d *roleDefinitionState) bool {
+				return strings.EqualFold(*d.ID, *r.ID)
+			})
+			if alreadyIncluded {
+				continue
+			}
+
+			s.RoleDefinitions = append(s.RoleDefinitions, &roleDefinitionState{
+				AssignableScopes: r.Properties.AssignableScopes,
+				Description:      r.Properties.Description,
+				ID:               r.ID,
+				Permissions:      r.Properties.Permissions,
+				RoleName:         r.Properties.RoleName,
+			})
+		}
+
+		roleAssignmentSchedules, err := authorizationClient.ListRoleAssignmentSchedules(ctx, scope)
+		if err != nil {
+			return "", err
+		}
+
+		for _, r := range roleAssignmentSchedules {
+			if isAtOrBelow(*r.Properties.Scope, scope) {
+				s.RoleAssignmentSchedules = append(s.RoleAssignmentSchedules, &scheduleState{
+					Condition:        r.Properties.Condition,
+					EndDateTime:      r.Properties.EndDateTime,
+					ID:               r.ID,
+					PrincipalID:      r.Properties.PrincipalID,
+					RoleDefinitionID: r.Properties.RoleDefinitionID,
+					Scope:            r.Properties.Scope,
+					StartDateTime:    r.Properties.StartDateTime,
+					Status:           r.Properties.Status,
+				})
+			}
+		}
+
+		roleEligibilitySchedules, err := authorizationClient.ListRoleEligibilitySchedules(ctx, scope)
+		if err != nil {
+			return "", err
+		}
+
+		for _, r := range roleEligibilitySchedules {
+			if isAtOrBelow(*r.Properties.Scope, scope) {
+				s.RoleEligibilitySchedules = append(s.RoleEligibilitySchedules, &scheduleState{
+					Condition:        r.Properties.Condition,
+					EndDateTime:      r.Properties.EndDateTime,
+					ID:               r.ID,
+					PrincipalID:      r.Properties.PrincipalID,
+					RoleDefinitionID: r.Properties.RoleDefinitionID,
+					Scope:            r.Properties.Scope,
+					StartDateTime:    r.Properties.StartDateTime,
+					Status:           r.Properties.Status,
+				})
+			}
+		}
+
+		// The rules of a role management policy are planned from its assignment to a role, so the assignments
+		// are hashed rather than the policies themselves.
+		roleNamesByScope := map[string][]string{}
+		for _, c := range config.GetScopeRoleNameCombinations(scope) {
+			roleNamesByScope[c.Scope] = append(roleNamesByScope[c.Scope], c.RoleName)
+		}
+
+		for roleScope, roleNames := range roleNamesByScope {
+			roleManagementPolicyAssignments, err := authorizationClient.ListRoleManagementPolicyAssignments(ctx, roleScope)
+			if err != nil {
+				return "", err
+			}
+
+			for _, r := range roleManagementPolicyAssignments {
+				if slices.Contains(roleNames, *r.Properties.PolicyAssignmentProperties.RoleDefinition.DisplayName) {
+					s.RoleManagementPolicies = append(s.RoleManagementPolicies, &policyState{
+						EffectiveRules: r.Properties.EffectiveRules,
+						ID:             r.ID,
+						PolicyID:       r.Properties.PolicyID,
+					})
+				}
+			}
+		}
+	}
+
+	compareScheduleStates := func(a, b *scheduleState) int {
+		return strings.Compare(strings.ToLower(*a.ID), strings.ToLower(*b.ID))
+	}
+	slices.SortFunc(s.RoleAssignmentSchedules, compareScheduleStates)
+	slices.SortFunc(s.RoleEligibilitySchedules, compareScheduleStates)
+	slices.SortFunc(s.RoleDefinitions, func(a, b *roleDefinitionState) int {
+		return strings.Compare(strings.ToLower(*a.ID), strings.ToLower(*b.ID))
+	})
+	slices.SortFunc(s.RoleManagementPolicies, func(a, b *policyState) int {
+		return strings.Compare(strings.ToLower(*a.ID), strings.ToLower(*b.ID))
+	})
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data)), nil
+}
+
+// isAtOrBelow determines whether scope is the same as, or a descendant of, otherScope.
+func isAtOrBelow(scope string, otherScope string) bool {
+	return scope == otherScope || strings.HasPrefix(scope, otherScope+"/")
+}
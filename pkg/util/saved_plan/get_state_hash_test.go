@@ -0,0 +1 @@
+package saved_plan
@@ -0,0 +1,33 @@
+package saved_plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+// Load reads a saved plan from a JSON file written by Save.
+func Load(filePath string) (*core.SavedPlan, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var savedPlan core.SavedPlan
+	err = json.Unmarshal(data, &savedPlan)
+	if err != nil {
+		return nil, fmt.Errorf("plan file \"%s\" is not valid: %w", filePath, err)
+	}
+
+	if savedPlan.Version != version {
+		return nil, fmt.Errorf("plan file \"%s\" has version %d, expected version %d", filePath, savedPlan.Version, version)
+	}
+
+	if savedPlan.ConfigHash == "" || savedPlan.Plan == nil || len(savedPlan.Scopes) == 0 || savedPlan.StateHash == "" {
+		return nil, fmt.Errorf("plan file \"%s\" is incomplete", filePath)
+	}
+
+	return &savedPlan, nil
+}
@@ -0,0 +1,20 @@
+package saved_plan
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+// Save writes a saved plan to a JSON file.
+func Save(filePath string, savedPlan *core.SavedPlan) error {
+	savedPlan.Version = version
+
+	data, err := json.MarshalIndent(savedPlan, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,4 @@
+package saved_plan
+
+// version is the version of the saved plan format. A saved plan of any other version is refused.
+const version = 1
@@ -1,102 +1,74 @@
 package schedule
 
 import (
-	"slices"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
-	"github.com/ahmetb/go-linq/v3"
 	"github.com/gofrontier-com/sheriff/pkg/core"
-	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
-func FilterForAssignmentSchedulesToUpdate(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
-	scope string,
-	assignmentSchedules []*core.Schedule,
-	existingRoleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule,
-	getPrincipalName func(*msgraphsdkgo.GraphServiceClient, string) (*string, error),
-) (filtered []*core.Schedule, err error) {
-	defer func() {
-		if e, ok := recover().(error); ok {
-			err = e
+func FilterForAssignmentSchedulesToUpdate(assignmentSchedules *core.KeyedRoleAssignmentSchedules) []*core.Schedule {
+	var filtered []*core.Schedule
+	for _, s := range assignmentSchedules.Schedules {
+		existingRoleAssignmentSchedule, found := assignmentSchedules.ExistingIndex[assignmentSchedules.Keys[s]]
+		if found && isAssignmentScheduleOutdated(s, existingRoleAssignmentSchedule) {
+			filtered = append(filtered, s)
 		}
-	}()
+	}
 
-	linq.From(assignmentSchedules).WhereT(func(a *core.Schedule) bool {
-		idx := slices.IndexFunc(existingRoleAssignmentSchedules, func(s *armauthorization.RoleAssignmentSchedule) bool {
-			roleDefinition, err := role_definition.GetRoleDefinitionById(
-				clientFactory,
-				*s.Properties.RoleDefinitionID,
-			)
-			if err != nil {
-				panic(err)
-			}
-
-			principalName, err := getPrincipalName(
-				graphServiceClient,
-				*s.Properties.PrincipalID,
-			)
-			if err != nil {
-				panic(err)
-			}
-
-			return a.Scope == *s.Properties.Scope &&
-				a.RoleName == *roleDefinition.Properties.RoleName &&
-				a.PrincipalName == *principalName
-		})
-		idx2 := linq.From(existingRoleAssignmentSchedules).IndexOfT(func(s *armauthorization.RoleAssignmentSchedule) bool {
-			roleDefinition, err := role_definition.GetRoleDefinitionById(
-				clientFactory,
-				*s.Properties.RoleDefinitionID,
-			)
-			if err != nil {
-				panic(err)
-			}
+	return filtered
+}
 
-			principalName, err := getPrincipalName(
-				graphServiceClient,
-				*s.Properties.PrincipalID,
-			)
-			if err != nil {
-				panic(err)
-			}
+// isAssignmentScheduleOutdated determines whether a role assignment schedule in Azure differs from the schedule in
+// config that it matches.
+func isAssignmentScheduleOutdated(a *core.Schedule, existingRoleAssignmentSchedule *armauthorization.RoleAssignmentSchedule) bool {
+	// If the condition in config differs from Azure, flag for update.
+	existingCondition := ""
+	if existingRoleAssignmentSchedule.Properties.Condition != nil {
+		existingCondition = *existingRoleAssignmentSchedule.Properties.Condition
+	}
+	if existingCondition != a.Condition {
+		return true
+	}
 
-			return a.Scope == *s.Properties.Scope &&
-				a.RoleName == *roleDefinition.Properties.RoleName &&
-				a.PrincipalName == *principalName
-		})
-		if idx != idx2 {
-			panic("index mismatch")
+	// If start time in config is nil, then we don't want to update the start time in Azure
+	// because it will be set to when the schedule was created, which is fine.
+	if a.StartDateTime != nil {
+		// If there is a start time in config, compare to Azure and flag for update as needed.
+		if *existingRoleAssignmentSchedule.Properties.StartDateTime != *a.StartDateTime {
+			return true
 		}
-		if idx == -1 {
-			return false
+	}
+
+	// If the schedule has a relative duration, renew it once it is within the renewal window,
+	// otherwise compare the end date it would have from the existing start time.
+	if a.GetDuration() != nil {
+		if a.IsDueForRenewal(existingRoleAssignmentSchedule.Properties.EndDateTime) {
+			return true
 		}
 
-		existingRoleAssignmentSchedule := existingRoleAssignmentSchedules[idx]
+		if existingRoleAssignmentSchedule.Properties.EndDateTime == nil {
+			return true
+		}
 
-		// If start time in config is nil, then we don't want to update the start time in Azure
-		// because it will be set to when the schedule was created, which is fine.
+		startDateTime := existingRoleAssignmentSchedule.Properties.StartDateTime
 		if a.StartDateTime != nil {
-			// If there is a start time in config, compare to Azure and flag for update as needed.
-			if *existingRoleAssignmentSchedule.Properties.StartDateTime != *a.StartDateTime {
-				return true
-			}
+			startDateTime = a.StartDateTime
 		}
 
-		// If end date is present in config and Azure, compare and flag for update as needed.
-		if existingRoleAssignmentSchedule.Properties.EndDateTime != nil && a.EndDateTime != nil {
-			if *existingRoleAssignmentSchedule.Properties.EndDateTime != *a.EndDateTime {
-				return true
-			}
-		} else if (existingRoleAssignmentSchedule.Properties.EndDateTime != nil && a.EndDateTime == nil) || (existingRoleAssignmentSchedule.Properties.EndDateTime == nil && a.EndDateTime != nil) {
-			// If end date is present in config but not Azure, or vice versa, flag for update.
+		endDateTime := a.GetEndDateTime(*startDateTime)
+		return endDateTime.Sub(*existingRoleAssignmentSchedule.Properties.EndDateTime).Abs() > time.Minute
+	}
+
+	// If end date is present in config and Azure, compare and flag for update as needed.
+	if existingRoleAssignmentSchedule.Properties.EndDateTime != nil && a.EndDateTime != nil {
+		if *existingRoleAssignmentSchedule.Properties.EndDateTime != *a.EndDateTime {
 			return true
 		}
+	} else if (existingRoleAssignmentSchedule.Properties.EndDateTime != nil && a.EndDateTime == nil) || (existingRoleAssignmentSchedule.Properties.EndDateTime == nil && a.EndDateTime != nil) {
+		// If end date is present in config but not Azure, or vice versa, flag for update.
+		return true
+	}
 
-		return false
-	}).ToSlice(&filtered)
-
-	return
+	return false
 }
@@ -1 +1,149 @@
 package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+// outdatedTest is a schedule in config and the condition, start and end of the schedule in Azure that it
+// matches, shared by the tests of role assignment and role eligibility schedules.
+type outdatedTest struct {
+	name              string
+	schedule          *core.Schedule
+	existingCondition *string
+	existingStart     time.Time
+	existingEnd       *time.Time
+	expected          bool
+}
+
+func getOutdatedTests() []outdatedTest {
+	start := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second)
+	end := start.AddDate(0, 0, 180)
+
+	return []outdatedTest{
+		{
+			name:          "unchanged without end date time",
+			schedule:      &core.Schedule{},
+			existingStart: start,
+		},
+		{
+			name:              "condition changed",
+			schedule:          &core.Schedule{Condition: "@Resource[x] == 'y'"},
+			existingCondition: to.Ptr("@Resource[x] == 'z'"),
+			existingStart:     start,
+			expected:          true,
+		},
+		{
+			name:              "condition removed",
+			schedule:          &core.Schedule{},
+			existingCondition: to.Ptr("@Resource[x] == 'z'"),
+			existingStart:     start,
+			expected:          true,
+		},
+		{
+			name:          "start date time changed",
+			schedule:      &core.Schedule{StartDateTime: to.Ptr(start.Add(time.Hour))},
+			existingStart: start,
+			expected:      true,
+		},
+		{
+			name:          "end date time unchanged",
+			schedule:      &core.Schedule{EndDateTime: to.Ptr(end)},
+			existingStart: start,
+			existingEnd:   to.Ptr(end),
+		},
+		{
+			name:          "end date time changed",
+			schedule:      &core.Schedule{EndDateTime: to.Ptr(end.Add(time.Hour))},
+			existingStart: start,
+			existingEnd:   to.Ptr(end),
+			expected:      true,
+		},
+		{
+			name:          "end date time added",
+			schedule:      &core.Schedule{EndDateTime: to.Ptr(end)},
+			existingStart: start,
+			expected:      true,
+		},
+		{
+			name:          "end date time removed",
+			schedule:      &core.Schedule{},
+			existingStart: start,
+			existingEnd:   to.Ptr(end),
+			expected:      true,
+		},
+		{
+			name:          "duration unchanged",
+			schedule:      &core.Schedule{Duration: "P180D"},
+			existingStart: start,
+			existingEnd:   to.Ptr(end),
+		},
+		{
+			name:          "duration within the 1 minute tolerance",
+			schedule:      &core.Schedule{Duration: "P180D"},
+			existingStart: start,
+			existingEnd:   to.Ptr(end.Add(time.Minute)),
+		},
+		{
+			name:          "duration beyond the 1 minute tolerance",
+			schedule:      &core.Schedule{Duration: "P180D"},
+			existingStart: start,
+			existingEnd:   to.Ptr(end.Add(time.Minute + time.Second)),
+			expected:      true,
+		},
+		{
+			name:          "duration changed",
+			schedule:      &core.Schedule{Duration: "P90D"},
+			existingStart: start,
+			existingEnd:   to.Ptr(end),
+			expected:      true,
+		},
+		{
+			name:          "duration without end date time in Azure",
+			schedule:      &core.Schedule{Duration: "P180D"},
+			existingStart: start,
+			expected:      true,
+		},
+		{
+			name:          "duration takes precedence over end date time",
+			schedule:      &core.Schedule{Duration: "P180D", EndDateTime: to.Ptr(end.AddDate(1, 0, 0))},
+			existingStart: start,
+			existingEnd:   to.Ptr(end),
+		},
+		{
+			name:          "duration outside the renewal window",
+			schedule:      &core.Schedule{Duration: "PT48H", RenewalWindow: "PT12H"},
+			existingStart: start,
+			existingEnd:   to.Ptr(start.Add(48 * time.Hour)),
+		},
+		{
+			name:          "duration within the renewal window",
+			schedule:      &core.Schedule{Duration: "PT36H", RenewalWindow: "PT13H"},
+			existingStart: start,
+			existingEnd:   to.Ptr(start.Add(36 * time.Hour)),
+			expected:      true,
+		},
+	}
+}
+
+func TestIsAssignmentScheduleOutdated(t *testing.T) {
+	for _, tt := range getOutdatedTests() {
+		t.Run(tt.name, func(t *testing.T) {
+			existingRoleAssignmentSchedule := &armauthorization.RoleAssignmentSchedule{
+				Properties: &armauthorization.RoleAssignmentScheduleProperties{
+					Condition:     tt.existingCondition,
+					EndDateTime:   tt.existingEnd,
+					StartDateTime: to.Ptr(tt.existingStart),
+				},
+			}
+
+			if actual := isAssignmentScheduleOutdated(tt.schedule, existingRoleAssignmentSchedule); actual != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, actual)
+			}
+		})
+	}
+}
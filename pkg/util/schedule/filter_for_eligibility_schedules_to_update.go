@@ -1,102 +1,74 @@
 package schedule
 
 import (
-	"slices"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
-	"github.com/ahmetb/go-linq/v3"
 	"github.com/gofrontier-com/sheriff/pkg/core"
-	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
 )
 
-func FilterForEligibilitySchedulesToUpdate(
-	clientFactory *armauthorization.ClientFactory,
-	graphServiceClient *msgraphsdkgo.GraphServiceClient,
-	scope string,
-	eligibilitySchedules []*core.Schedule,
-	existingRoleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule,
-	getPrincipalName func(*msgraphsdkgo.GraphServiceClient, string) (*string, error),
-) (filtered []*core.Schedule, err error) {
-	defer func() {
-		if e, ok := recover().(error); ok {
-			err = e
+func FilterForEligibilitySchedulesToUpdate(eligibilitySchedules *core.KeyedRoleEligibilitySchedules) []*core.Schedule {
+	var filtered []*core.Schedule
+	for _, s := range eligibilitySchedules.Schedules {
+		existingRoleEligibilitySchedule, found := eligibilitySchedules.ExistingIndex[eligibilitySchedules.Keys[s]]
+		if found && isEligibilityScheduleOutdated(s, existingRoleEligibilitySchedule) {
+			filtered = append(filtered, s)
 		}
-	}()
+	}
 
-	linq.From(eligibilitySchedules).WhereT(func(a *core.Schedule) bool {
-		idx := slices.IndexFunc(existingRoleEligibilitySchedules, func(s *armauthorization.RoleEligibilitySchedule) bool {
-			roleDefinition, err := role_definition.GetRoleDefinitionById(
-				clientFactory,
-				*s.Properties.RoleDefinitionID,
-			)
-			if err != nil {
-				panic(err)
-			}
-
-			principalName, err := getPrincipalName(
-				graphServiceClient,
-				*s.Properties.PrincipalID,
-			)
-			if err != nil {
-				panic(err)
-			}
-
-			return a.Scope == *s.Properties.Scope &&
-				a.RoleName == *roleDefinition.Properties.RoleName &&
-				a.PrincipalName == *principalName
-		})
-		idx2 := linq.From(existingRoleEligibilitySchedules).IndexOfT(func(s *armauthorization.RoleEligibilitySchedule) bool {
-			roleDefinition, err := role_definition.GetRoleDefinitionById(
-				clientFactory,
-				*s.Properties.RoleDefinitionID,
-			)
-			if err != nil {
-				panic(err)
-			}
+	return filtered
+}
 
-			principalName, err := getPrincipalName(
-				graphServiceClient,
-				*s.Properties.PrincipalID,
-			)
-			if err != nil {
-				panic(err)
-			}
+// isEligibilityScheduleOutdated determines whether a role eligibility schedule in Azure differs from the
+// schedule in config that it matches.
+func isEligibilityScheduleOutdated(a *core.Schedule, existingRoleEligibilitySchedule *armauthorization.RoleEligibilitySchedule) bool {
+	// If the condition in config differs from Azure, flag for update.
+	existingCondition := ""
+	if existingRoleEligibilitySchedule.Properties.Condition != nil {
+		existingCondition = *existingRoleEligibilitySchedule.Properties.Condition
+	}
+	if existingCondition != a.Condition {
+		return true
+	}
 
-			return a.Scope == *s.Properties.Scope &&
-				a.RoleName == *roleDefinition.Properties.RoleName &&
-				a.PrincipalName == *principalName
-		})
-		if idx != idx2 {
-			panic("index mismatch")
+	// If start time in config is nil, then we don't want to update the start time in Azure
+	// because it will be set to when the schedule was created, which is fine.
+	if a.StartDateTime != nil {
+		// If there is a start time in config, compare to Azure and flag for update as needed.
+		if *existingRoleEligibilitySchedule.Properties.StartDateTime != *a.StartDateTime {
+			return true
 		}
-		if idx == -1 {
-			return false
+	}
+
+	// If the schedule has a relative duration, renew it once it is within the renewal window,
+	// otherwise compare the end date it would have from the existing start time.
+	if a.GetDuration() != nil {
+		if a.IsDueForRenewal(existingRoleEligibilitySchedule.Properties.EndDateTime) {
+			return true
 		}
 
-		existingRoleEligibilitySchedule := existingRoleEligibilitySchedules[idx]
+		if existingRoleEligibilitySchedule.Properties.EndDateTime == nil {
+			return true
+		}
 
-		// If start time in config is nil, then we don't want to update the start time in Azure
-		// because it will be set to when the schedule was created, which is fine.
+		startDateTime := existingRoleEligibilitySchedule.Properties.StartDateTime
 		if a.StartDateTime != nil {
-			// If there is a start time in config, compare to Azure and flag for update as needed.
-			if *existingRoleEligibilitySchedule.Properties.StartDateTime != *a.StartDateTime {
-				return true
-			}
+			startDateTime = a.StartDateTime
 		}
 
-		// If end date is present in config and Azure, compare and flag for update as needed.
-		if existingRoleEligibilitySchedule.Properties.EndDateTime != nil && a.EndDateTime != nil {
-			if *existingRoleEligibilitySchedule.Properties.EndDateTime != *a.EndDateTime {
-				return true
-			}
-		} else if (existingRoleEligibilitySchedule.Properties.EndDateTime != nil && a.EndDateTime == nil) || (existingRoleEligibilitySchedule.Properties.EndDateTime == nil && a.EndDateTime != nil) {
-			// If end date is present in config but not Azure, or vice versa, flag for update.
+		endDateTime := a.GetEndDateTime(*startDateTime)
+		return endDateTime.Sub(*existingRoleEligibilitySchedule.Properties.EndDateTime).Abs() > time.Minute
+	}
+
+	// If end date is present in config and Azure, compare and flag for update as needed.
+	if existingRoleEligibilitySchedule.Properties.EndDateTime != nil && a.EndDateTime != nil {
+		if *existingRoleEligibilitySchedule.Properties.EndDateTime != *a.EndDateTime {
 			return true
 		}
+	} else if (existingRoleEligibilitySchedule.Properties.EndDateTime != nil && a.EndDateTime == nil) || (existingRoleEligibilitySchedule.Properties.EndDateTime == nil && a.EndDateTime != nil) {
+		// If end date is present in config but not Azure, or vice versa, flag for update.
+		return true
+	}
 
-		return false
-	}).ToSlice(&filtered)
-
-	return
+	return false
 }
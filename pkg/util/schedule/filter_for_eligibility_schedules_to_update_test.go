@@ -1 +1,26 @@
 package schedule
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+)
+
+func TestIsEligibilityScheduleOutdated(t *testing.T) {
+	for _, tt := range getOutdatedTests() {
+		t.Run(tt.name, func(t *testing.T) {
+			existingRoleEligibilitySchedule := &armauthorization.RoleEligibilitySchedule{
+				Properties: &armauthorization.RoleEligibilityScheduleProperties{
+					Condition:     tt.existingCondition,
+					EndDateTime:   tt.existingEnd,
+					StartDateTime: to.Ptr(tt.existingStart),
+				},
+			}
+
+			if actual := isEligibilityScheduleOutdated(tt.schedule, existingRoleEligibilitySchedule); actual != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+)
+
+func newRoleAssignmentSchedule(id string, scope string, roleDefinitionId string, principalId string) *armauthorization.RoleAssignmentSchedule {
+	return &armauthorization.RoleAssignmentSchedule{
+		ID: to.Ptr(id),
+		Properties: &armauthorization.RoleAssignmentScheduleProperties{
+			PrincipalID:      to.Ptr(principalId),
+			RoleDefinitionID: to.Ptr(roleDefinitionId),
+			Scope:            to.Ptr(scope),
+		},
+	}
+}
+
+func TestIndexRoleAssignmentSchedules(t *testing.T) {
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000001"
+	roleDefinitionId := "/providers/Microsoft.Authorization/roleDefinitions/acdd72a7-3385-48ef-bd42-f606fba81ae7"
+
+	tests := []struct {
+		name          string
+		schedules     []*armauthorization.RoleAssignmentSchedule
+		expectedCount int
+		expectedError string
+	}{
+		{
+			name: "distinct keys",
+			schedules: []*armauthorization.RoleAssignmentSchedule{
+				newRoleAssignmentSchedule("a", scope, roleDefinitionId, "00000000-0000-0000-0000-00000000000a"),
+				newRoleAssignmentSchedule("b", scope, roleDefinitionId, "00000000-0000-0000-0000-00000000000b"),
+			},
+			expectedCount: 2,
+		},
+		{
+			name: "same key differing in case and role definition scope",
+			schedules: []*armauthorization.RoleAssignmentSchedule{
+				newRoleAssignmentSchedule("a", scope, roleDefinitionId, "00000000-0000-0000-0000-00000000000a"),
+				newRoleAssignmentSchedule("b", strings.ToUpper(scope), scope+roleDefinitionId, "00000000-0000-0000-0000-00000000000A"),
+			},
+			expectedError: "a and b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := IndexRoleAssignmentSchedules(tt.schedules)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(index) != tt.expectedCount {
+				t.Errorf("expected %d indexed schedules, got %d", tt.expectedCount, len(index))
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+// IndexRoleEligibilitySchedules keys role eligibility schedules in Azure by scope, role definition and principal. Schedules
+// with the same key cannot be told apart when matching them with config, so they are returned as an error.
+func IndexRoleEligibilitySchedules(roleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule) (map[core.ScheduleKey]*armauthorization.RoleEligibilitySchedule, error) {
+	index := make(map[core.ScheduleKey]*armauthorization.RoleEligibilitySchedule, len(roleEligibilitySchedules))
+	var duplicates []string
+	for _, s := range roleEligibilitySchedules {
+		key := core.NewScheduleKey(*s.Properties.Scope, *s.Properties.RoleDefinitionID, *s.Properties.PrincipalID)
+		if existing, found := index[key]; found {
+			duplicates = append(duplicates, fmt.Sprintf("%s and %s", *existing.ID, *s.ID))
+			continue
+		}
+
+		index[key] = s
+	}
+
+	if len(duplicates) > 0 {
+		return nil, fmt.Errorf("the following role eligibility schedules have the same scope, role definition and principal:\n- %s", strings.Join(duplicates, "\n- "))
+	}
+
+	return index, nil
+}
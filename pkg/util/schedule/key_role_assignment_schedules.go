@@ -0,0 +1,43 @@
+package schedule
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+// KeyRoleAssignmentSchedules keys the given schedules in config and indexes the role assignment schedules in Azure
+// that they are matched with, so that creates, updates and deletes can be planned without keying them again.
+func KeyRoleAssignmentSchedules(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
+	scope string,
+	assignmentSchedules []*core.Schedule,
+	existingRoleAssignmentSchedules []*armauthorization.RoleAssignmentSchedule,
+	getPrincipalId func(context.Context, backend.GraphClient, string) (*string, error),
+) (*core.KeyedRoleAssignmentSchedules, error) {
+	keys, err := KeySchedules(ctx, authorizationClient, graphClient, scope, assignmentSchedules, getPrincipalId)
+	if err != nil {
+		return nil, err
+	}
+
+	existingIndex, err := IndexRoleAssignmentSchedules(existingRoleAssignmentSchedules)
+	if err != nil {
+		return nil, err
+	}
+
+	keyed := &core.KeyedRoleAssignmentSchedules{
+		Existing:      existingRoleAssignmentSchedules,
+		ExistingIndex: existingIndex,
+		Keys:          make(map[*core.Schedule]core.ScheduleKey, len(assignmentSchedules)),
+		Schedules:     assignmentSchedules,
+	}
+	for i, s := range assignmentSchedules {
+		keyed.Keys[s] = keys[i]
+	}
+
+	return keyed, nil
+}
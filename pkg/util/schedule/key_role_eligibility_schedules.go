@@ -0,0 +1,43 @@
+package schedule
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+// KeyRoleEligibilitySchedules keys the given schedules in config and indexes the role eligibility schedules in Azure
+// that they are matched with, so that creates, updates and deletes can be planned without keying them again.
+func KeyRoleEligibilitySchedules(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
+	scope string,
+	eligibilitySchedules []*core.Schedule,
+	existingRoleEligibilitySchedules []*armauthorization.RoleEligibilitySchedule,
+	getPrincipalId func(context.Context, backend.GraphClient, string) (*string, error),
+) (*core.KeyedRoleEligibilitySchedules, error) {
+	keys, err := KeySchedules(ctx, authorizationClient, graphClient, scope, eligibilitySchedules, getPrincipalId)
+	if err != nil {
+		return nil, err
+	}
+
+	existingIndex, err := IndexRoleEligibilitySchedules(existingRoleEligibilitySchedules)
+	if err != nil {
+		return nil, err
+	}
+
+	keyed := &core.KeyedRoleEligibilitySchedules{
+		Existing:      existingRoleEligibilitySchedules,
+		ExistingIndex: existingIndex,
+		Keys:          make(map[*core.Schedule]core.ScheduleKey, len(eligibilitySchedules)),
+		Schedules:     eligibilitySchedules,
+	}
+	for i, s := range eligibilitySchedules {
+		keyed.Keys[s] = keys[i]
+	}
+
+	return keyed, nil
+}
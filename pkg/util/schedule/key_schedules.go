@@ -0,0 +1,49 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/gofrontier-com/sheriff/pkg/util/role_definition"
+)
+
+// KeySchedules resolves the role definition and principal of each schedule in config to get the key that
+// matches it with a schedule in Azure. The keys are returned in the same order as the schedules. Schedules whose
+// principal has been resolved to an Id before planning are keyed by that Id, and getPrincipalId may be nil when
+// every principal has been resolved.
+func KeySchedules(
+	ctx context.Context,
+	authorizationClient backend.AuthorizationClient,
+	graphClient backend.GraphClient,
+	scope string,
+	schedules []*core.Schedule,
+	getPrincipalId func(context.Context, backend.GraphClient, string) (*string, error),
+) ([]core.ScheduleKey, error) {
+	keys := make([]core.ScheduleKey, len(schedules))
+	for i, s := range schedules {
+		roleDefinition, err := role_definition.GetRoleDefinitionByName(ctx, authorizationClient, scope, s.RoleName)
+		if err != nil {
+			return nil, err
+		}
+
+		principalId := s.PrincipalId
+		if principalId == "" {
+			if getPrincipalId == nil {
+				return nil, fmt.Errorf("principal \"%s\" has not been resolved to an Id", s.PrincipalName)
+			}
+
+			id, err := getPrincipalId(ctx, graphClient, s.PrincipalName)
+			if err != nil {
+				return nil, err
+			}
+
+			principalId = *id
+		}
+
+		keys[i] = core.NewScheduleKey(s.Scope, *roleDefinition.ID, principalId)
+	}
+
+	return keys, nil
+}
@@ -5,12 +5,23 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/core"
 )
 
 func GetRoleAssignmentScheduleInfo(
 	startDateTime *time.Time,
 	endDateTime *time.Time,
+	duration *core.Duration,
 ) *armauthorization.RoleAssignmentScheduleRequestPropertiesScheduleInfo {
+	if startDateTime == nil {
+		startDateTime = to.Ptr(time.Now())
+	}
+
+	// A relative duration is anchored to the start of the schedule.
+	if duration != nil {
+		endDateTime = to.Ptr(duration.AddTo(*startDateTime))
+	}
+
 	var expiration armauthorization.RoleAssignmentScheduleRequestPropertiesScheduleInfoExpiration
 
 	if endDateTime == nil {
@@ -24,15 +35,8 @@ func GetRoleAssignmentScheduleInfo(
 		}
 	}
 
-	if startDateTime == nil {
-		return &armauthorization.RoleAssignmentScheduleRequestPropertiesScheduleInfo{
-			Expiration:    &expiration,
-			StartDateTime: to.Ptr(time.Now()),
-		}
-	} else {
-		return &armauthorization.RoleAssignmentScheduleRequestPropertiesScheduleInfo{
-			Expiration:    &expiration,
-			StartDateTime: startDateTime,
-		}
+	return &armauthorization.RoleAssignmentScheduleRequestPropertiesScheduleInfo{
+		Expiration:    &expiration,
+		StartDateTime: startDateTime,
 	}
 }
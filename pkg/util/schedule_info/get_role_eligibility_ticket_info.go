@@ -0,0 +1,25 @@
+package schedule_info
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+)
+
+func GetRoleEligibilityTicketInfo(
+	ticket *core.Ticket,
+) *armauthorization.RoleEligibilityScheduleRequestPropertiesTicketInfo {
+	if ticket == nil {
+		return nil
+	}
+
+	ticketInfo := &armauthorization.RoleEligibilityScheduleRequestPropertiesTicketInfo{}
+	if ticket.Number != "" {
+		ticketInfo.TicketNumber = to.Ptr(ticket.Number)
+	}
+	if ticket.System != "" {
+		ticketInfo.TicketSystem = to.Ptr(ticket.System)
+	}
+
+	return ticketInfo
+}
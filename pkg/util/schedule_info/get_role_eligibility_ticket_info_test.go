@@ -0,0 +1 @@
+package schedule_info
@@ -0,0 +1,44 @@
+package service_principal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// GetServicePrincipalById gets a service principal by object Id. The service principal is also cached by its app
+// Id and object Id, but not by display name, as display names are not unique.
+func GetServicePrincipalById(ctx context.Context, graphClient backend.GraphClient, servicePrincipalId string) (models.ServicePrincipalable, error) {
+	var servicePrincipal models.ServicePrincipalable
+	cacheKey := fmt.Sprintf("id::%s", servicePrincipalId)
+
+	if s, found := cache.Get(cacheKey); found {
+		servicePrincipal = s.(models.ServicePrincipalable)
+	} else {
+		result, err := graphClient.GetServicePrincipalById(ctx, servicePrincipalId)
+		if err != nil {
+			if errors.Is(err, backend.ErrNotFound) {
+				return nil, fmt.Errorf("service principal with Id \"%s\" not found", servicePrincipalId)
+			} else {
+				return nil, err
+			}
+		}
+
+		servicePrincipal = result
+
+		cacheKeys := []string{
+			cacheKey,
+			fmt.Sprintf("name::%s", *servicePrincipal.GetAppId()),
+			fmt.Sprintf("name::%s", *servicePrincipal.GetId()),
+		}
+		for _, cacheKey := range cacheKeys {
+			cache.Set(cacheKey, servicePrincipal, gocache.NoExpiration)
+		}
+	}
+
+	return servicePrincipal, nil
+}
@@ -0,0 +1,45 @@
+package service_principal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// GetServicePrincipalByName gets a service principal by display name, application (client) Id or object Id.
+func GetServicePrincipalByName(ctx context.Context, graphClient backend.GraphClient, servicePrincipalName string) (models.ServicePrincipalable, error) {
+	var servicePrincipal models.ServicePrincipalable
+	cacheKey := fmt.Sprintf("name::%s", servicePrincipalName)
+
+	if s, found := cache.Get(cacheKey); found {
+		servicePrincipal = s.(models.ServicePrincipalable)
+	} else {
+		servicePrincipals, err := graphClient.ListServicePrincipalsByName(ctx, servicePrincipalName)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(servicePrincipals) == 0 {
+			return nil, fmt.Errorf("service principal with display name, app Id or Id \"%s\" not found", servicePrincipalName)
+		}
+
+		if len(servicePrincipals) > 1 {
+			return nil, fmt.Errorf("multiple service principals with display name, app Id or Id \"%s\" found", servicePrincipalName)
+		}
+
+		servicePrincipal = servicePrincipals[0]
+
+		cacheKeys := []string{
+			cacheKey,
+			fmt.Sprintf("id::%s", *servicePrincipal.GetId()),
+		}
+		for _, cacheKey := range cacheKeys {
+			cache.Set(cacheKey, servicePrincipal, gocache.NoExpiration)
+		}
+	}
+
+	return servicePrincipal, nil
+}
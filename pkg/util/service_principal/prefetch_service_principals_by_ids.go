@@ -0,0 +1,57 @@
+package service_principal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// PrefetchServicePrincipalsByIds looks up, in bulk, the service principals with the given object Ids that are
+// not already cached, and caches them by app Id and object Id. Service principals that are not found are returned as principal
+// errors.
+func PrefetchServicePrincipalsByIds(ctx context.Context, graphClient backend.GraphClient, servicePrincipalIds []string) (core.PrincipalErrors, error) {
+	var uncachedServicePrincipalIds []string
+	for _, id := range servicePrincipalIds {
+		if _, found := cache.Get(fmt.Sprintf("id::%s", id)); !found && !slices.Contains(uncachedServicePrincipalIds, id) {
+			uncachedServicePrincipalIds = append(uncachedServicePrincipalIds, id)
+		}
+	}
+
+	if len(uncachedServicePrincipalIds) == 0 {
+		return nil, nil
+	}
+
+	servicePrincipals, err := graphClient.ListServicePrincipalsByIds(ctx, uncachedServicePrincipalIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var principalErrors core.PrincipalErrors
+	for _, id := range uncachedServicePrincipalIds {
+		index := slices.IndexFunc(servicePrincipals, func(s models.ServicePrincipalable) bool {
+			return strings.EqualFold(*s.GetId(), id)
+		})
+		if index == -1 {
+			principalErrors = append(principalErrors, fmt.Errorf("service principal with Id \"%s\" not found", id))
+			continue
+		}
+
+		servicePrincipal := servicePrincipals[index]
+		cacheKeys := []string{
+			fmt.Sprintf("id::%s", id),
+			fmt.Sprintf("name::%s", *servicePrincipal.GetAppId()),
+			fmt.Sprintf("name::%s", *servicePrincipal.GetId()),
+		}
+		for _, cacheKey := range cacheKeys {
+			cache.Set(cacheKey, servicePrincipal, gocache.NoExpiration)
+		}
+	}
+
+	return principalErrors, nil
+}
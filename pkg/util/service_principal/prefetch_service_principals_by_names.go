@@ -0,0 +1,65 @@
+package service_principal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// PrefetchServicePrincipalsByNames looks up, in bulk, the service principals with the given display names,
+// application (client) Ids or object Ids that are not already cached, and caches them. Names that are not
+// found, or that match more than one service principal, are returned as principal errors.
+func PrefetchServicePrincipalsByNames(ctx context.Context, graphClient backend.GraphClient, servicePrincipalNames []string) (core.PrincipalErrors, error) {
+	var uncachedServicePrincipalNames []string
+	for _, n := range servicePrincipalNames {
+		if _, found := cache.Get(fmt.Sprintf("name::%s", n)); !found && !slices.Contains(uncachedServicePrincipalNames, n) {
+			uncachedServicePrincipalNames = append(uncachedServicePrincipalNames, n)
+		}
+	}
+
+	if len(uncachedServicePrincipalNames) == 0 {
+		return nil, nil
+	}
+
+	servicePrincipals, err := graphClient.ListServicePrincipalsByNames(ctx, uncachedServicePrincipalNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var principalErrors core.PrincipalErrors
+	for _, n := range uncachedServicePrincipalNames {
+		var matches []models.ServicePrincipalable
+		for _, s := range servicePrincipals {
+			if strings.EqualFold(*s.GetDisplayName(), n) || strings.EqualFold(*s.GetAppId(), n) || strings.EqualFold(*s.GetId(), n) {
+				matches = append(matches, s)
+			}
+		}
+
+		if len(matches) == 0 {
+			principalErrors = append(principalErrors, fmt.Errorf("service principal with display name, app Id or Id \"%s\" not found", n))
+			continue
+		}
+
+		if len(matches) > 1 {
+			principalErrors = append(principalErrors, fmt.Errorf("multiple service principals with display name, app Id or Id \"%s\" found", n))
+			continue
+		}
+
+		servicePrincipal := matches[0]
+		cacheKeys := []string{
+			fmt.Sprintf("name::%s", n),
+			fmt.Sprintf("id::%s", *servicePrincipal.GetId()),
+		}
+		for _, cacheKey := range cacheKeys {
+			cache.Set(cacheKey, servicePrincipal, gocache.NoExpiration)
+		}
+	}
+
+	return principalErrors, nil
+}
@@ -0,0 +1,60 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armsubscriptions"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// GetSubscriptionByName gets a subscription by display name or Id.
+func GetSubscriptionByName(ctx context.Context, client *armsubscriptions.Client, subscriptionName string) (*armsubscriptions.Subscription, error) {
+	var subscription *armsubscriptions.Subscription
+	cacheKey := fmt.Sprintf("name::%s", subscriptionName)
+
+	if s, found := cache.Get(cacheKey); found {
+		subscription = s.(*armsubscriptions.Subscription)
+	} else {
+		var subscriptions []*armsubscriptions.Subscription
+		pager := client.NewListPager(nil)
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, s := range page.Value {
+				if strings.EqualFold(*s.SubscriptionID, subscriptionName) || *s.DisplayName == subscriptionName {
+					subscriptions = append(subscriptions, s)
+				}
+			}
+		}
+
+		if len(subscriptions) == 0 {
+			return nil, fmt.Errorf("subscription with display name or Id \"%s\" not found", subscriptionName)
+		}
+
+		if len(subscriptions) > 1 {
+			var subscriptionIds []string
+			for _, s := range subscriptions {
+				subscriptionIds = append(subscriptionIds, *s.SubscriptionID)
+			}
+
+			return nil, fmt.Errorf("multiple subscriptions with display name or Id \"%s\" found: %s", subscriptionName, strings.Join(subscriptionIds, ", "))
+		}
+
+		subscription = subscriptions[0]
+
+		cacheKeys := []string{
+			cacheKey,
+			fmt.Sprintf("id::%s", *subscription.SubscriptionID),
+		}
+		for _, cacheKey := range cacheKeys {
+			cache.Set(cacheKey, subscription, gocache.NoExpiration)
+		}
+	}
+
+	return subscription, nil
+}
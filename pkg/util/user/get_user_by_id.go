@@ -2,24 +2,24 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
 
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
 	gocache "github.com/patrickmn/go-cache"
 )
 
-func GetUserById(graphServiceClient *msgraphsdkgo.GraphServiceClient, userId string) (models.Userable, error) {
+func GetUserById(ctx context.Context, graphClient backend.GraphClient, userId string) (models.Userable, error) {
 	var user models.Userable
 	cacheKey := fmt.Sprintf("id::%s", userId)
 
 	if u, found := cache.Get(cacheKey); found {
 		user = u.(models.Userable)
 	} else {
-		result, err := graphServiceClient.Users().ByUserId(userId).Get(context.Background(), nil)
+		result, err := graphClient.GetUserById(ctx, userId)
 		if err != nil {
-			if strings.HasPrefix(err.Error(), fmt.Sprintf("Resource '%s' does not exist", userId)) {
+			if errors.Is(err, backend.ErrNotFound) {
 				return nil, fmt.Errorf("user with Id \"%s\" not found", userId)
 			} else {
 				return nil, err
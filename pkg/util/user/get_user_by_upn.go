@@ -4,33 +4,23 @@ import (
 	"context"
 	"fmt"
 
-	msgraphsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/gofrontier-com/sheriff/pkg/backend"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
-	"github.com/microsoftgraph/msgraph-sdk-go/users"
 	gocache "github.com/patrickmn/go-cache"
 )
 
-func GetUserByUpn(graphServiceClient *msgraphsdkgo.GraphServiceClient, upn string) (models.Userable, error) {
+func GetUserByUpn(ctx context.Context, graphClient backend.GraphClient, upn string) (models.Userable, error) {
 	var user models.Userable
 	cacheKey := fmt.Sprintf("upn::%s", upn)
 
 	if u, found := cache.Get(cacheKey); found {
 		user = u.(models.Userable)
 	} else {
-		filterValue := fmt.Sprintf("userPrincipalName eq '%s'", upn)
-		query := users.UsersRequestBuilderGetQueryParameters{
-			Filter: &filterValue,
-		}
-		options := users.UsersRequestBuilderGetRequestConfiguration{
-			QueryParameters: &query,
-		}
-		result, err := graphServiceClient.Users().Get(context.Background(), &options)
+		users, err := graphClient.ListUsersByUpn(ctx, upn)
 		if err != nil {
 			return nil, err
 		}
 
-		users := result.GetValue()
-
 		if len(users) == 0 {
 			return nil, fmt.Errorf("user with upn \"%s\" not found", upn)
 		}
@@ -0,0 +1,15 @@
+package user
+
+import (
+	"context"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+)
+
+func GetUserIdByUpn(ctx context.Context, graphClient backend.GraphClient, upn string) (*string, error) {
+	user, err := GetUserByUpn(ctx, graphClient, upn)
+	if err != nil {
+		return nil, err
+	}
+	return user.GetId(), nil
+}
@@ -0,0 +1,55 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// PrefetchUsersByIds looks up, in bulk, the users with the given object Ids that are not already cached, and
+// caches them. Users that are not found are returned as principal errors.
+func PrefetchUsersByIds(ctx context.Context, graphClient backend.GraphClient, userIds []string) (core.PrincipalErrors, error) {
+	var uncachedUserIds []string
+	for _, id := range userIds {
+		if _, found := cache.Get(fmt.Sprintf("id::%s", id)); !found && !slices.Contains(uncachedUserIds, id) {
+			uncachedUserIds = append(uncachedUserIds, id)
+		}
+	}
+
+	if len(uncachedUserIds) == 0 {
+		return nil, nil
+	}
+
+	users, err := graphClient.ListUsersByIds(ctx, uncachedUserIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var principalErrors core.PrincipalErrors
+	for _, id := range uncachedUserIds {
+		index := slices.IndexFunc(users, func(u models.Userable) bool {
+			return strings.EqualFold(*u.GetId(), id)
+		})
+		if index == -1 {
+			principalErrors = append(principalErrors, fmt.Errorf("user with Id \"%s\" not found", id))
+			continue
+		}
+
+		user := users[index]
+		cacheKeys := []string{
+			fmt.Sprintf("id::%s", id),
+			fmt.Sprintf("upn::%s", *user.GetUserPrincipalName()),
+		}
+		for _, cacheKey := range cacheKeys {
+			cache.Set(cacheKey, user, gocache.NoExpiration)
+		}
+	}
+
+	return principalErrors, nil
+}
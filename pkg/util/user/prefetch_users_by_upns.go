@@ -0,0 +1,65 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofrontier-com/sheriff/pkg/backend"
+	"github.com/gofrontier-com/sheriff/pkg/core"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// PrefetchUsersByUpns looks up, in bulk, the users with the given user principal names that are not already
+// cached, and caches them. UPNs that are not found, or that match more than one user, are returned as
+// principal errors.
+func PrefetchUsersByUpns(ctx context.Context, graphClient backend.GraphClient, upns []string) (core.PrincipalErrors, error) {
+	var uncachedUpns []string
+	for _, n := range upns {
+		if _, found := cache.Get(fmt.Sprintf("upn::%s", n)); !found && !slices.Contains(uncachedUpns, n) {
+			uncachedUpns = append(uncachedUpns, n)
+		}
+	}
+
+	if len(uncachedUpns) == 0 {
+		return nil, nil
+	}
+
+	users, err := graphClient.ListUsersByUpns(ctx, uncachedUpns)
+	if err != nil {
+		return nil, err
+	}
+
+	var principalErrors core.PrincipalErrors
+	for _, n := range uncachedUpns {
+		var matches []models.Userable
+		for _, u := range users {
+			if strings.EqualFold(*u.GetUserPrincipalName(), n) {
+				matches = append(matches, u)
+			}
+		}
+
+		if len(matches) == 0 {
+			principalErrors = append(principalErrors, fmt.Errorf("user with upn \"%s\" not found", n))
+			continue
+		}
+
+		if len(matches) > 1 {
+			principalErrors = append(principalErrors, fmt.Errorf("multiple users with upn \"%s\" found", n))
+			continue
+		}
+
+		user := matches[0]
+		cacheKeys := []string{
+			fmt.Sprintf("upn::%s", n),
+			fmt.Sprintf("id::%s", *user.GetId()),
+		}
+		for _, cacheKey := range cacheKeys {
+			cache.Set(cacheKey, user, gocache.NoExpiration)
+		}
+	}
+
+	return principalErrors, nil
+}